@@ -46,9 +46,11 @@ func main() {
 
 	appLog.Info("init services")
 	service := samplerService.InitService(ctx, appLog, repo)
+	defer service.Stop()
 
 	appLog.Info("init http service")
-	appHTTPServer := routes.InitAppRouter(appLog, service, fmt.Sprintf(":%d", appConf.AppPort), true)
+	appHTTPServer := routes.InitAppRouter(appLog, service, fmt.Sprintf(":%d", appConf.AppPort), true).
+		WithAdminToken(appConf.AdminToken)
 	defer func() {
 		if err = appHTTPServer.Stop(); err != nil {
 			appLog.Fatal("unable to stop http service", err)
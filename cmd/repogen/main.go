@@ -0,0 +1,50 @@
+// Command repogen generates typed repository.Repo methods for db tagged structs.
+// It is run by go generate from the package of the struct:
+//
+//	//go:generate go run go_project_template/cmd/repogen -type User -table users
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go_project_template/internal/repogen"
+	"os"
+	"strings"
+)
+
+var (
+	typeName = flag.String("type", "", "Struct type name")
+	table    = flag.String("table", "", "Table name")
+	output   = flag.String("output", "", "Output file, default is <type>_repo_gen.go")
+)
+
+func main() {
+	flag.Parse()
+	if *typeName == "" || *table == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "repogen:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	entity, err := repogen.Parse(".", *typeName, *table)
+	if err != nil {
+		return err
+	}
+	src, err := repogen.Generate(entity)
+	if err != nil {
+		return err
+	}
+	out := *output
+	if out == "" {
+		out = strings.ToLower(*typeName) + "_repo_gen.go"
+	}
+	if err := os.WriteFile(out, src, 0o600); err != nil {
+		return fmt.Errorf("write %s: %w", out, err)
+	}
+	return nil
+}
@@ -13,6 +13,7 @@ type AppConfig struct {
 	AppPort         int       `yaml:"app_port"`
 	EnableTelemetry bool      `yaml:"enable_telemetry"`
 	MigratesFolder  string    `yaml:"migrates_folder"`
+	AdminToken      string    `yaml:"admin_token"` // /admin endpoints are served only when it is set
 	ConfigDB        DBConf    `yaml:"conf_db"`
 	ConfigGraph     GraphConf `yaml:"conf_graph"`
 }
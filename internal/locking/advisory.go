@@ -0,0 +1,109 @@
+package locking
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"go_project_template/internal/storage/database"
+	"hash/fnv"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrNotHeld = errors.New("advisory lock is not held")
+
+// Key maps lock name to key of Postgres advisory lock.
+func Key(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64()) //nolint:gosec // overflow is fine, key is just a hash
+}
+
+// SessionLock is a session scoped advisory lock. It holds a dedicated connection
+// of the pool until Unlock, the lock is released by Postgres if the connection is lost.
+type SessionLock struct {
+	conn *sqlx.Conn
+	name string
+	key  int64
+}
+
+// Lock waits until lock of name is acquired or ctx is done.
+func Lock(ctx context.Context, db database.DBConnector, name string) (*SessionLock, error) {
+	conn, err := db.Client().Connx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get connection for lock %s: %w", name, err)
+	}
+	lock := &SessionLock{conn: conn, name: name, key: Key(name)}
+	if _, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lock.key); err != nil {
+		discard(conn)
+		return nil, fmt.Errorf("lock %s: %w", name, err)
+	}
+	return lock, nil
+}
+
+// TryLock acquires lock of name without waiting, ok is false if it is held by another session.
+func TryLock(ctx context.Context, db database.DBConnector, name string) (lock *SessionLock, ok bool, err error) {
+	conn, err := db.Client().Connx(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("get connection for lock %s: %w", name, err)
+	}
+	key := Key(name)
+	if err = conn.GetContext(ctx, &ok, `SELECT pg_try_advisory_lock($1)`, key); err != nil {
+		discard(conn)
+		return nil, false, fmt.Errorf("try lock %s: %w", name, err)
+	}
+	if !ok {
+		_ = conn.Close()
+		return nil, false, nil
+	}
+	return &SessionLock{conn: conn, name: name, key: key}, true, nil
+}
+
+// Ping checks connection holding the lock, error means lock may be lost.
+func (l *SessionLock) Ping(ctx context.Context) error {
+	if _, err := l.conn.ExecContext(ctx, `SELECT 1`); err != nil {
+		return fmt.Errorf("ping lock %s connection: %w", l.name, err)
+	}
+	return nil
+}
+
+// Unlock releases the lock and returns connection to the pool. If unlock fails the connection
+// is closed instead, so the lock is released with the session.
+func (l *SessionLock) Unlock(ctx context.Context) error {
+	var released bool
+	if err := l.conn.GetContext(ctx, &released, `SELECT pg_advisory_unlock($1)`, l.key); err != nil {
+		discard(l.conn)
+		return fmt.Errorf("unlock %s: %w", l.name, err)
+	}
+	_ = l.conn.Close()
+	if !released {
+		return fmt.Errorf("unlock %s: %w", l.name, ErrNotHeld)
+	}
+	return nil
+}
+
+// discard closes the underlying connection instead of returning it to the pool, ending the session
+// and all advisory locks it may hold. It waits for a query in flight on the connection.
+func discard(conn *sqlx.Conn) {
+	// ErrBadConn makes database/sql close the driver connection on release
+	_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+	_ = conn.Close()
+}
+
+// LockTx waits until transaction scoped lock of name is acquired, it is released on commit or rollback.
+func LockTx(ctx context.Context, tx *sqlx.Tx, name string) error {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, Key(name)); err != nil {
+		return fmt.Errorf("lock %s in tx: %w", name, err)
+	}
+	return nil
+}
+
+// TryLockTx acquires transaction scoped lock of name without waiting, false if it is held by another session.
+func TryLockTx(ctx context.Context, tx *sqlx.Tx, name string) (bool, error) {
+	var ok bool
+	if err := tx.GetContext(ctx, &ok, `SELECT pg_try_advisory_xact_lock($1)`, Key(name)); err != nil {
+		return false, fmt.Errorf("try lock %s in tx: %w", name, err)
+	}
+	return ok, nil
+}
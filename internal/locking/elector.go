@@ -0,0 +1,225 @@
+package locking
+
+import (
+	"context"
+	"fmt"
+	"go_project_template/internal/storage/database"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultElectionInterval = 5 * time.Second
+	// releaseTimeout limits unlock on Stop, the lock is released with the connection anyway
+	releaseTimeout = 5 * time.Second
+)
+
+// Elector elects one leader among instances competing for the same name with a session advisory lock.
+// Leader pings connection holding the lock every interval and steps down if ping fails or takes longer
+// than interval, followers try to take the lock every interval.
+type Elector struct {
+	db       database.DBConnector
+	name     string
+	interval time.Duration
+	onChange func(leader bool)
+	onError  func(err error)
+	changes  chan bool
+
+	mu           sync.RWMutex
+	lock         *SessionLock
+	leaderCtx    context.Context
+	leaderCancel context.CancelFunc
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewElector(db database.DBConnector, name string) *Elector {
+	return &Elector{
+		db:       db,
+		name:     name,
+		interval: DefaultElectionInterval,
+		onChange: func(bool) {},
+		onError:  func(error) {},
+		changes:  make(chan bool, 1),
+		cancel:   func() {},
+	}
+}
+
+// WithInterval sets period of lock attempts and leader checks, default is DefaultElectionInterval.
+func (e *Elector) WithInterval(interval time.Duration) *Elector {
+	e.interval = interval
+	return e
+}
+
+// WithOnChange sets callback called from election goroutine when leadership is gained or lost.
+func (e *Elector) WithOnChange(onChange func(leader bool)) *Elector {
+	e.onChange = onChange
+	return e
+}
+
+// WithOnError sets handler of database errors, e.g. to log them.
+func (e *Elector) WithOnError(onError func(err error)) *Elector {
+	e.onError = onError
+	return e
+}
+
+// Changes returns channel of leadership changes, a slow reader gets only the latest state.
+func (e *Elector) Changes() <-chan bool {
+	return e.changes
+}
+
+// IsLeader reports whether this instance holds the lock.
+func (e *Elector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.lock != nil
+}
+
+// LeaderContext returns context canceled when leadership is lost, ok is false if instance is not leader.
+func (e *Elector) LeaderContext() (ctx context.Context, ok bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leaderCtx, e.lock != nil
+}
+
+// Start runs election until Stop or ctx is done, leadership is released then.
+func (e *Elector) Start(ctx context.Context) *Elector {
+	// canceling ctx interrupts lock queries in flight
+	ctx, e.cancel = context.WithCancel(ctx)
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		defer e.release()
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+		for {
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				return
+			}
+			e.elect(ctx)
+			timer.Reset(e.interval)
+		}
+	}()
+	return e
+}
+
+func (e *Elector) elect(ctx context.Context) {
+	e.mu.RLock()
+	lock := e.lock
+	e.mu.RUnlock()
+	if lock != nil {
+		if err := e.ping(ctx, lock); err != nil {
+			e.onError(err)
+			// server may have dropped the session, so another instance can be leader already.
+			// Discarding connection releases the lock if server still holds it, it waits for hung ping
+			prev := e.step(nil)
+			go discard(prev.conn)
+			e.notify(false)
+		}
+		return
+	}
+	lock, ok, err := TryLock(ctx, e.db, e.name)
+	if err != nil {
+		e.onError(err)
+		return
+	}
+	if ok {
+		e.step(lock)
+		e.notify(true)
+	}
+}
+
+// ping checks connection holding the lock within interval. Driver may not interrupt query on
+// a black-holed connection until TCP timeout, so ping is not awaited after the timeout.
+func (e *Elector) ping(ctx context.Context, lock *SessionLock) error {
+	ctx, cancel := context.WithTimeout(ctx, e.interval)
+	defer cancel()
+	res := make(chan error, 1)
+	go func() { res <- lock.Ping(ctx) }()
+	select {
+	case err := <-res:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("ping lock %s connection: %w", e.name, ctx.Err())
+	}
+}
+
+// step replaces held lock, nil lock means stepping down. Returns previous lock.
+func (e *Elector) step(lock *SessionLock) *SessionLock {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	prev := e.lock
+	if prev != nil {
+		e.leaderCancel()
+	}
+	e.lock = lock
+	if lock != nil {
+		e.leaderCtx, e.leaderCancel = context.WithCancel(context.Background())
+	}
+	return prev
+}
+
+func (e *Elector) notify(leader bool) {
+	select {
+	case <-e.changes:
+		// drop state the reader hasn't seen, it is outdated
+	default:
+	}
+	e.changes <- leader
+	e.onChange(leader)
+}
+
+func (e *Elector) release() {
+	lock := e.step(nil)
+	if lock == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancel()
+	if err := lock.Unlock(ctx); err != nil {
+		e.onError(err)
+	}
+	e.notify(false)
+}
+
+// Stop releases leadership and stops election.
+func (e *Elector) Stop() {
+	e.cancel()
+	e.wg.Wait()
+}
+
+// OnlyLeader wraps task to run only on leader, other instances skip it. Context of the task
+// is canceled when leadership is lost.
+func (e *Elector) OnlyLeader(task func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		leaderCtx, ok := e.LeaderContext()
+		if !ok {
+			return nil
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		stop := context.AfterFunc(leaderCtx, cancel)
+		defer stop()
+		return task(ctx)
+	}
+}
+
+// RunPeriodic runs task every interval on leader until ctx is done, task errors are passed to WithOnError handler.
+func (e *Elector) RunPeriodic(ctx context.Context, interval time.Duration, task func(ctx context.Context) error) {
+	leaderTask := e.OnlyLeader(task)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := leaderTask(ctx); err != nil {
+				e.onError(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
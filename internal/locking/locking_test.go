@@ -0,0 +1,251 @@
+package locking_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"go_project_template/internal/locking"
+	testhelpers "go_project_template/internal/test_helpers"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func boolRows(value bool) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"ok"}).AddRow(value)
+}
+
+func TestSessionLock(t *testing.T) {
+	db, mock := testhelpers.NewMockDB(t)
+	key := locking.Key("reports")
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock\(\$1\)`).WithArgs(key).WillReturnRows(boolRows(false))
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock\(\$1\)`).WithArgs(key).WillReturnRows(boolRows(true))
+	mock.ExpectQuery(`SELECT pg_advisory_unlock\(\$1\)`).WithArgs(key).WillReturnRows(boolRows(false))
+
+	_, ok, err := locking.TryLock(context.Background(), db, "reports")
+	require.NoError(t, err)
+	require.False(t, ok)
+	lock, ok, err := locking.TryLock(context.Background(), db, "reports")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.ErrorIs(t, lock.Unlock(context.Background()), locking.ErrNotHeld)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.NotEqual(t, key, locking.Key("report"))
+}
+
+func TestElector(t *testing.T) {
+	db, mock := testhelpers.NewMockDB(t)
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock`).WillReturnRows(boolRows(true))
+	mock.ExpectExec(`SELECT 1`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`SELECT 1`).WillReturnError(errors.New("connection reset"))
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock`).WillReturnRows(boolRows(false)).WillDelayFor(time.Hour)
+
+	var changes []bool
+	elector := locking.NewElector(db, "reports").
+		WithInterval(20 * time.Millisecond).
+		WithOnChange(func(leader bool) { changes = append(changes, leader) })
+	taskStarted, taskCanceled := make(chan struct{}), make(chan struct{})
+	task := elector.OnlyLeader(func(ctx context.Context) error {
+		close(taskStarted)
+		<-ctx.Done()
+		close(taskCanceled)
+		return nil
+	})
+	require.NoError(t, task(context.Background()), "task is skipped on follower")
+
+	elector.Start(context.Background())
+	require.True(t, <-elector.Changes())
+	require.True(t, elector.IsLeader())
+	go func() { _ = task(context.Background()) }()
+	<-taskStarted
+
+	require.False(t, <-elector.Changes())
+	<-taskCanceled
+	require.False(t, elector.IsLeader())
+	elector.Stop()
+	require.Equal(t, []bool{true, false}, changes)
+}
+
+func TestElector_HungPing(t *testing.T) {
+	db, mock := testhelpers.NewMockDB(t)
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock`).WillReturnRows(boolRows(true))
+	mock.ExpectExec(`SELECT 1`).WillReturnResult(sqlmock.NewResult(0, 0)).WillDelayFor(time.Hour)
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock`).WillReturnRows(boolRows(false)).WillDelayFor(time.Hour)
+
+	var pingErr error
+	elector := locking.NewElector(db, "reports").
+		WithInterval(20 * time.Millisecond).
+		WithOnError(func(err error) { pingErr = err })
+	elector.Start(context.Background())
+	defer elector.Stop()
+	require.True(t, <-elector.Changes())
+
+	select {
+	case leader := <-elector.Changes():
+		require.False(t, leader)
+	case <-time.After(time.Second):
+		t.Fatal("leader should step down when ping times out")
+	}
+	require.ErrorIs(t, pingErr, context.DeadlineExceeded)
+}
+
+func TestElectorWithPostgres(t *testing.T) {
+	container := testhelpers.GetClean(t)
+	first := locking.NewElector(container.DB, "reports").WithInterval(10 * time.Millisecond).Start(container.Ctx)
+	require.True(t, <-first.Changes())
+	second := locking.NewElector(container.DB, "reports").WithInterval(10 * time.Millisecond).Start(container.Ctx)
+	defer second.Stop()
+
+	var runs int
+	task := func(context.Context) error {
+		runs++
+		return nil
+	}
+	require.NoError(t, first.OnlyLeader(task)(container.Ctx))
+	require.NoError(t, second.OnlyLeader(task)(container.Ctx))
+	require.Equal(t, 1, runs)
+
+	// the second instance takes over when the leader stops
+	first.Stop()
+	require.False(t, first.IsLeader())
+	require.True(t, <-second.Changes())
+
+	tx, err := container.DB.Client().BeginTxx(container.Ctx, nil)
+	require.NoError(t, err)
+	defer func() { _ = tx.Rollback() }()
+	ok, err := locking.TryLockTx(container.Ctx, tx, "reports")
+	require.NoError(t, err)
+	require.False(t, ok, "lock is held by the second elector")
+	require.NoError(t, locking.LockTx(container.Ctx, tx, "cleanup"))
+}
+
+// lockServer emulates Postgres session advisory locks, which are released only when the
+// physical connection holding them is closed.
+type lockServer struct {
+	mu        sync.Mutex
+	holders   map[int64]*lockConn
+	pingDelay atomic.Int64
+}
+
+func (s *lockServer) Connect(context.Context) (driver.Conn, error) {
+	return &lockConn{server: s}, nil
+}
+
+func (s *lockServer) Driver() driver.Driver {
+	return nil
+}
+
+// open returns connector with its own pool, as used by a separate instance
+func (s *lockServer) open(t *testing.T) lockDB {
+	db := sqlx.NewDb(sql.OpenDB(s), "postgres")
+	t.Cleanup(func() { _ = db.Close() })
+	return lockDB{db: db}
+}
+
+type lockDB struct {
+	db *sqlx.DB
+}
+
+func (d lockDB) Client() *sqlx.DB {
+	return d.db
+}
+
+type lockConn struct {
+	server *lockServer
+}
+
+func (c *lockConn) Prepare(string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c *lockConn) Begin() (driver.Tx, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c *lockConn) Close() error {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	for key, holder := range c.server.holders {
+		if holder == c {
+			delete(c.server.holders, key)
+		}
+	}
+	return nil
+}
+
+func (c *lockConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	// hung connection doesn't interrupt the query on cancel
+	time.Sleep(time.Duration(c.server.pingDelay.Load()))
+	return driver.RowsAffected(0), nil
+}
+
+func (c *lockConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	key := args[0].Value.(int64)
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	holder, held := c.server.holders[key]
+	switch {
+	case strings.Contains(query, "pg_try_advisory_lock"):
+		if !held {
+			c.server.holders[key] = c
+		}
+		return &boolRow{value: !held || holder == c}, nil
+	case strings.Contains(query, "pg_advisory_unlock"):
+		if holder == c {
+			delete(c.server.holders, key)
+		}
+		return &boolRow{value: holder == c}, nil
+	}
+	return nil, fmt.Errorf("unexpected query %s", query)
+}
+
+type boolRow struct {
+	value bool
+	read  bool
+}
+
+func (r *boolRow) Columns() []string {
+	return []string{"ok"}
+}
+
+func (r *boolRow) Close() error {
+	return nil
+}
+
+func (r *boolRow) Next(dest []driver.Value) error {
+	if r.read {
+		return io.EOF
+	}
+	r.read = true
+	dest[0] = r.value
+	return nil
+}
+
+func TestElector_SlowPingReleasesLock(t *testing.T) {
+	server := &lockServer{holders: map[int64]*lockConn{}}
+	first := locking.NewElector(server.open(t), "reports").WithInterval(20 * time.Millisecond).Start(context.Background())
+	require.True(t, <-first.Changes())
+	second := locking.NewElector(server.open(t), "reports").WithInterval(20 * time.Millisecond).Start(context.Background())
+	defer second.Stop()
+
+	server.pingDelay.Store(int64(200 * time.Millisecond))
+	require.False(t, <-first.Changes())
+	first.Stop()
+
+	// the session of the stepped down leader must end, otherwise it keeps the lock in the pool
+	select {
+	case leader := <-second.Changes():
+		require.True(t, leader)
+	case <-time.After(2 * time.Second):
+		t.Fatal("second elector should take over when slow leader steps down")
+	}
+}
@@ -0,0 +1,43 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_project_template/internal/repository"
+	"go_project_template/internal/utils"
+	"strings"
+)
+
+var ErrListenersMissedEvent = errors.New("broadcast listeners missed event")
+
+// Publisher delivers outbox events to consumers, returned error makes relay retry the event later.
+// Events are delivered at least once, consumers should deduplicate them by ID.
+type Publisher interface {
+	Publish(ctx context.Context, event repository.OutboxEvent) error
+}
+
+// PublisherFunc adapts function to Publisher.
+type PublisherFunc func(ctx context.Context, event repository.OutboxEvent) error
+
+func (f PublisherFunc) Publish(ctx context.Context, event repository.OutboxEvent) error {
+	return f(ctx, event)
+}
+
+// BroadcastPublisher publishes events to in-process listeners of broadcaster without waiting for them,
+// listeners should be registered with RegisterBufferedListener. If buffer of a listener is full, Publish
+// fails and relay retries the event later, so other listeners may receive it again.
+type BroadcastPublisher struct {
+	broadcaster *utils.Broadcaster[repository.OutboxEvent]
+}
+
+func NewBroadcastPublisher(broadcaster *utils.Broadcaster[repository.OutboxEvent]) *BroadcastPublisher {
+	return &BroadcastPublisher{broadcaster: broadcaster}
+}
+
+func (p *BroadcastPublisher) Publish(_ context.Context, event repository.OutboxEvent) error {
+	if missed := p.broadcaster.TryBroadcast(event); len(missed) > 0 {
+		return fmt.Errorf("%w %d: %s", ErrListenersMissedEvent, event.ID, strings.Join(missed, ", "))
+	}
+	return nil
+}
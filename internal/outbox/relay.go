@@ -0,0 +1,128 @@
+package outbox
+
+import (
+	"context"
+	"go_project_template/internal/logger"
+	"go_project_template/internal/repository"
+	"go_project_template/internal/utils"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultPollInterval = time.Second
+	DefaultBatchSize    = 100
+	DefaultRetryBase    = time.Second
+	DefaultRetryMax     = 10 * time.Minute
+
+	// retentionPurgeInterval limits how often delivered events are deleted
+	retentionPurgeInterval = time.Minute
+)
+
+// Relay polls outbox table and publishes due events with Publisher. Several relays may run
+// concurrently, each event is locked by one of them. Failed events are retried with exponential backoff.
+type Relay struct {
+	repo      *repository.Repo
+	publisher Publisher
+	log       logger.AppLogger
+
+	pollInterval time.Duration
+	batchSize    int
+	retryBase    time.Duration
+	retryMax     time.Duration
+	retention    time.Duration
+	purgedAt     atomic.Int64
+
+	stopCh    chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func NewRelay(log logger.AppLogger, repo *repository.Repo, publisher Publisher) *Relay {
+	return &Relay{
+		repo:         repo,
+		publisher:    publisher,
+		log:          log.With(logger.WithService("outbox_relay")),
+		pollInterval: DefaultPollInterval,
+		batchSize:    DefaultBatchSize,
+		retryBase:    DefaultRetryBase,
+		retryMax:     DefaultRetryMax,
+		stopCh:       make(chan struct{}),
+	}
+}
+
+// WithPollInterval sets pause between polls when there are no due events.
+func (r *Relay) WithPollInterval(interval time.Duration) *Relay {
+	r.pollInterval = interval
+	return r
+}
+
+// WithBatchSize sets number of events taken in one transaction.
+func (r *Relay) WithBatchSize(size int) *Relay {
+	r.batchSize = max(1, size)
+	return r
+}
+
+// WithRetryBackoff sets delay before the first retry, doubled on every next attempt up to maxDelay.
+func (r *Relay) WithRetryBackoff(base, maxDelay time.Duration) *Relay {
+	r.retryBase, r.retryMax = base, maxDelay
+	return r
+}
+
+// WithRetention deletes events delivered longer than retention ago, zero keeps them.
+func (r *Relay) WithRetention(retention time.Duration) *Relay {
+	r.retention = retention
+	return r
+}
+
+// Start runs polling until Stop, ctx is passed to Publisher.
+func (r *Relay) Start(ctx context.Context) *Relay {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		utils.PollLoop(ctx, r.stopCh, r.pollInterval, func() bool {
+			taken, err := r.RunOnce(ctx)
+			if err != nil {
+				r.log.Error("unable to relay outbox events", err)
+				return false
+			}
+			return taken == r.batchSize
+		})
+	}()
+	return r
+}
+
+// RunOnce publishes one batch of due events, returns number of taken events.
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	taken, err := r.repo.DeliverOutboxEvents(ctx, r.batchSize, func(event repository.OutboxEvent) error {
+		err := r.publisher.Publish(ctx, event)
+		if err != nil {
+			r.log.Error("unable to publish outbox event", err,
+				logger.WithInt64("id", event.ID), logger.WithString("topic", event.Topic), logger.WithInt("attempts", event.Attempts))
+		}
+		return err
+	}, func(attempts int) time.Duration {
+		return utils.ExponentialBackoff(r.retryBase, r.retryMax, attempts)
+	})
+	if err != nil || r.retention <= 0 {
+		return taken, err
+	}
+	now := time.Now()
+	purgedAt := r.purgedAt.Load()
+	if now.UnixNano()-purgedAt < int64(retentionPurgeInterval) || !r.purgedAt.CompareAndSwap(purgedAt, now.UnixNano()) {
+		return taken, nil
+	}
+	if _, err = r.repo.DeleteDeliveredOutboxEvents(ctx, now.Add(-r.retention)); err != nil {
+		return taken, err
+	}
+	return taken, nil
+}
+
+// Stop waits until current batch is published and stops polling.
+func (r *Relay) Stop() {
+	r.closeOnce.Do(func() {
+		close(r.stopCh)
+	})
+	r.wg.Wait()
+}
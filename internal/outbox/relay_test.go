@@ -0,0 +1,106 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"go_project_template/internal/logger"
+	"go_project_template/internal/outbox"
+	"go_project_template/internal/repository"
+	testhelpers "go_project_template/internal/test_helpers"
+	"go_project_template/internal/utils"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func newMockRepo(t *testing.T) (*repository.Repo, sqlmock.Sqlmock) {
+	db, mock := testhelpers.NewMockDB(t)
+	return repository.InitRepo(db), mock
+}
+
+func expectEvents(mock sqlmock.Sqlmock, limit int, events ...repository.OutboxEvent) {
+	rows := sqlmock.NewRows([]string{"id", "topic", "event_key", "payload", "created_at", "attempts"})
+	for _, e := range events {
+		rows.AddRow(e.ID, e.Topic, e.Key, []byte(e.Payload), e.CreatedAt, e.Attempts)
+	}
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT .+ FROM outbox .+ FOR UPDATE SKIP LOCKED`).WithArgs(limit).WillReturnRows(rows)
+}
+
+func TestRelay(t *testing.T) {
+	t.Run("should publish events and mark them delivered", func(t *testing.T) {
+		repo, mock := newMockRepo(t)
+		broadcaster := utils.NewBroadcaster[repository.OutboxEvent]()
+		listener := broadcaster.RegisterBufferedListener("test", 2)
+		var received []repository.OutboxEvent
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for event := range listener {
+				received = append(received, event)
+			}
+		}()
+
+		event := repository.OutboxEvent{ID: 1, Topic: "user.created", Key: "a", Payload: []byte(`{"id":"a"}`), CreatedAt: time.Now()}
+		expectEvents(mock, 10, event, repository.OutboxEvent{ID: 2, Topic: "user.deleted", Payload: []byte(`{}`)})
+		mock.ExpectExec(`UPDATE outbox SET attempts = attempts \+ 1, delivered_at = now\(\)`).WithArgs(int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE outbox SET attempts = attempts \+ 1, delivered_at = now\(\)`).WithArgs(int64(2)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		relay := outbox.NewRelay(logger.NewAppSLogger(), repo, outbox.NewBroadcastPublisher(broadcaster)).WithBatchSize(10)
+		taken, err := relay.RunOnce(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 2, taken)
+		require.NoError(t, mock.ExpectationsWereMet())
+
+		broadcaster.UnregisterListener("test")
+		<-done
+		require.Len(t, received, 2)
+		require.Equal(t, "user.created", received[0].Topic)
+		require.JSONEq(t, `{"id":"a"}`, string(received[0].Payload))
+	})
+	t.Run("should retry failed events with backoff", func(t *testing.T) {
+		repo, mock := newMockRepo(t)
+		expectEvents(mock, outbox.DefaultBatchSize, repository.OutboxEvent{ID: 7, Topic: "user.created", Payload: []byte(`{}`), Attempts: 2})
+		mock.ExpectExec(`UPDATE outbox SET attempts = attempts \+ 1, last_error = \$2`).
+			WithArgs(int64(7), "broker is down", 4.0).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		publisher := outbox.PublisherFunc(func(context.Context, repository.OutboxEvent) error {
+			return errors.New("broker is down")
+		})
+		relay := outbox.NewRelay(logger.NewAppSLogger(), repo, publisher).WithRetryBackoff(time.Second, 5*time.Second)
+		taken, err := relay.RunOnce(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 1, taken)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("should not wait for slow listeners", func(t *testing.T) {
+		broadcaster := utils.NewBroadcaster[repository.OutboxEvent]()
+		broadcaster.RegisterBufferedListener("slow", 1)
+		publisher := outbox.NewBroadcastPublisher(broadcaster)
+		require.NoError(t, publisher.Publish(context.Background(), repository.OutboxEvent{ID: 1}))
+		err := publisher.Publish(context.Background(), repository.OutboxEvent{ID: 2})
+		require.ErrorIs(t, err, outbox.ErrListenersMissedEvent)
+		require.ErrorContains(t, err, "2: slow")
+	})
+	t.Run("should poll until stopped", func(t *testing.T) {
+		repo, mock := newMockRepo(t)
+		expectEvents(mock, outbox.DefaultBatchSize)
+		mock.ExpectCommit()
+		mock.ExpectBegin().WillReturnError(errors.New("db is down"))
+
+		relay := outbox.NewRelay(logger.NewAppSLogger(), repo, outbox.PublisherFunc(func(context.Context, repository.OutboxEvent) error {
+			return nil
+		})).WithPollInterval(10 * time.Millisecond).Start(context.Background())
+		require.Eventually(t, func() bool {
+			return mock.ExpectationsWereMet() == nil
+		}, time.Second, 10*time.Millisecond)
+		relay.Stop()
+	})
+}
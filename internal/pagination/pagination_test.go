@@ -0,0 +1,116 @@
+package pagination_test
+
+import (
+	"go_project_template/internal/pagination"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type item struct {
+	ID      int64
+	Name    string
+	Created time.Time
+}
+
+func newSchema() *pagination.Schema[item] {
+	return pagination.NewSchema(pagination.Field[item]{
+		Name: "id", Column: "id", Value: func(i item) any { return i.ID }, Parse: pagination.ParseInt,
+	}).
+		WithField(pagination.Field[item]{
+			Name: "name", Column: "item_name", Value: func(i item) any { return i.Name }, Sortable: true, Filterable: true,
+		}).
+		WithField(pagination.Field[item]{
+			Name: "created", Column: "created_at", Value: func(i item) any { return i.Created }, Parse: pagination.ParseTime, Sortable: true,
+		}).
+		WithDefaultSort(pagination.SortField{Field: "created", Desc: true}).
+		WithLimits(2, 10)
+}
+
+func TestParseQuery(t *testing.T) {
+	q, err := pagination.ParseQuery("5", "abc", "-created, name", []string{"name:in:a,b", "id:gt:10"})
+	require.NoError(t, err)
+	require.Equal(t, pagination.Query{
+		Limit:  5,
+		Cursor: "abc",
+		Sort:   []pagination.SortField{{Field: "created", Desc: true}, {Field: "name"}},
+		Filters: []pagination.Filter{
+			{Field: "name", Op: pagination.OpIn, Values: []string{"a", "b"}},
+			{Field: "id", Op: pagination.OpGt, Values: []string{"10"}},
+		},
+	}, q)
+
+	q, err = pagination.ParseQuery("", "", "", nil)
+	require.NoError(t, err)
+	require.Zero(t, q)
+
+	for _, params := range [][]string{{"0", ""}, {"x", ""}, {"", "-"}, {"", "", "name:eq"}, {"", "", "name:regex:a"}} {
+		var filters []string
+		if len(params) == 3 {
+			filters = params[2:]
+		}
+		_, err = pagination.ParseQuery(params[0], "", params[1], filters)
+		require.ErrorIs(t, err, pagination.ErrInvalidQuery, params)
+	}
+}
+
+func TestSchema_Query(t *testing.T) {
+	schema := newSchema()
+	query, args, err := schema.Query("SELECT * FROM items", pagination.Query{})
+	require.NoError(t, err)
+	require.Equal(t, `SELECT * FROM items ORDER BY "created_at" DESC, "id" ASC LIMIT 3`, query)
+	require.Empty(t, args)
+
+	query, args, err = schema.Query("SELECT * FROM items", pagination.Query{
+		Limit: 50,
+		Sort:  []pagination.SortField{{Field: "name"}},
+		Filters: []pagination.Filter{
+			{Field: "name", Op: pagination.OpIn, Values: []string{"a", "b"}},
+			{Field: "id", Op: pagination.OpGte, Values: []string{"7"}},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, `SELECT * FROM items WHERE "item_name" IN ($1, $2) AND "id" >= $3 ORDER BY "item_name" ASC, "id" ASC LIMIT 11`, query)
+	require.Equal(t, []any{"a", "b", int64(7)}, args)
+
+	_, _, err = schema.Query("", pagination.Query{Sort: []pagination.SortField{{Field: "secret"}}})
+	require.ErrorIs(t, err, pagination.ErrInvalidQuery)
+	_, _, err = schema.Query("", pagination.Query{Filters: []pagination.Filter{{Field: "created", Op: pagination.OpEq, Values: []string{"2024-01-01"}}}})
+	require.ErrorIs(t, err, pagination.ErrInvalidQuery)
+	_, _, err = schema.Query("", pagination.Query{Filters: []pagination.Filter{{Field: "id", Op: pagination.OpEq, Values: []string{"x"}}}})
+	require.ErrorIs(t, err, pagination.ErrInvalidQuery)
+	_, _, err = schema.Query("", pagination.Query{Cursor: "!!!"})
+	require.ErrorIs(t, err, pagination.ErrInvalidQuery)
+}
+
+func TestSchema_Page(t *testing.T) {
+	schema := newSchema()
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	items := []item{{ID: 3, Created: created}, {ID: 2, Created: created}, {ID: 1, Created: created}}
+
+	page, err := schema.Page(items[:2], pagination.Query{})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 2)
+	require.Empty(t, page.NextCursor)
+
+	q := pagination.Query{Filters: []pagination.Filter{{Field: "name", Op: pagination.OpEq, Values: []string{"a"}}}}
+	page, err = schema.Page(items, q)
+	require.NoError(t, err)
+	require.Equal(t, items[:2], page.Items)
+	require.NotEmpty(t, page.NextCursor)
+
+	q.Cursor = page.NextCursor
+	query, args, err := schema.Query("SELECT * FROM items", q)
+	require.NoError(t, err)
+	require.Equal(t, `SELECT * FROM items WHERE "item_name" = $1 AND ("created_at" < $2 OR ("created_at" = $2 AND "id" > $3)) `+
+		`ORDER BY "created_at" DESC, "id" ASC LIMIT 3`, query)
+	require.Len(t, args, 3)
+	require.Equal(t, "2024-01-01T00:00:00Z", args[1])
+	require.EqualValues(t, "2", args[2])
+
+	// cursor can't be used with another order
+	q.Sort = []pagination.SortField{{Field: "name"}}
+	_, _, err = schema.Query("SELECT * FROM items", q)
+	require.ErrorIs(t, err, pagination.ErrInvalidQuery)
+}
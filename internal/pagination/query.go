@@ -0,0 +1,114 @@
+package pagination
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidQuery is wrapped by all errors caused by bad query params, e.g. to respond with 400.
+var ErrInvalidQuery = errors.New("invalid list query")
+
+type Operator string
+
+const (
+	OpEq   Operator = "eq"
+	OpNe   Operator = "ne"
+	OpLt   Operator = "lt"
+	OpLte  Operator = "lte"
+	OpGt   Operator = "gt"
+	OpGte  Operator = "gte"
+	OpIn   Operator = "in"
+	OpLike Operator = "like"
+)
+
+var operatorsSQL = map[Operator]string{
+	OpEq:   "=",
+	OpNe:   "<>",
+	OpLt:   "<",
+	OpLte:  "<=",
+	OpGt:   ">",
+	OpGte:  ">=",
+	OpLike: "LIKE",
+}
+
+// SortField is a field of sort order, descending if Desc.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// Filter is a condition on field value, Values has several items only for OpIn.
+type Filter struct {
+	Field  string
+	Op     Operator
+	Values []string
+}
+
+// Query is a request of a list page: up to Limit items sorted by Sort matching all Filters,
+// starting after Cursor returned with the previous page. Zero Query requests the first page
+// with default limit and sort of the Schema.
+type Query struct {
+	Limit   int
+	Cursor  string
+	Sort    []SortField
+	Filters []Filter
+}
+
+// ParseQuery parses list query params:
+//   - limit: number of items
+//   - cursor: next_cursor of the previous page
+//   - sort: comma separated fields, "-" prefix means descending order, e.g. -created_at,email
+//   - filter: field:operator:value, for "in" operator values are comma separated, e.g. locale:in:en,de
+//
+// Fields are checked against Schema when the query is built.
+func ParseQuery(limit, cursor, sort string, filters []string) (Query, error) {
+	q := Query{Cursor: cursor}
+	if limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return Query{}, fmt.Errorf("%w: limit must be a positive number", ErrInvalidQuery)
+		}
+		q.Limit = n
+	}
+	if sort != "" {
+		for _, field := range strings.Split(sort, ",") {
+			name, desc := strings.CutPrefix(strings.TrimSpace(field), "-")
+			if name == "" {
+				return Query{}, fmt.Errorf("%w: empty sort field", ErrInvalidQuery)
+			}
+			q.Sort = append(q.Sort, SortField{Field: name, Desc: desc})
+		}
+	}
+	for _, filter := range filters {
+		parts := strings.SplitN(filter, ":", 3)
+		if len(parts) != 3 || parts[0] == "" {
+			return Query{}, fmt.Errorf("%w: filter %q is not field:operator:value", ErrInvalidQuery, filter)
+		}
+		op := Operator(parts[1])
+		values := []string{parts[2]}
+		switch {
+		case op == OpIn:
+			values = strings.Split(parts[2], ",")
+		case operatorsSQL[op] == "":
+			return Query{}, fmt.Errorf("%w: unknown filter operator %q", ErrInvalidQuery, parts[1])
+		}
+		q.Filters = append(q.Filters, Filter{Field: parts[0], Op: op, Values: values})
+	}
+	return q, nil
+}
+
+// ParseInt parses filter values of integer fields.
+func ParseInt(value string) (any, error) {
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// ParseTime parses filter values of time fields in RFC 3339 format or as a date.
+func ParseTime(value string) (any, error) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
@@ -0,0 +1,284 @@
+package pagination
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"go_project_template/internal/utils"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultLimit    = 20
+	DefaultMaxLimit = 100
+)
+
+// Field is a list field exposed to clients by Name and stored in Column.
+// Value returns field value of an item for cursors. Parse converts filter values,
+// by default they are passed to the query as strings.
+type Field[T any] struct {
+	Name       string
+	Column     string
+	Value      func(item T) any
+	Parse      func(value string) (any, error)
+	Sortable   bool
+	Filterable bool
+}
+
+// Page is a part of list result, NextCursor is empty on the last page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// cursor holds sort fields values of the last item of a page,
+// Sort is checked to reject cursor of a query with another order.
+type cursor struct {
+	Sort   string `json:"s"`
+	Values []any  `json:"v"`
+}
+
+// Schema builds keyset pagination queries over whitelisted fields.
+// Sort always ends with the key field, so order of items is stable and cursor points to exactly one row,
+// key and sorted columns should be NOT NULL.
+type Schema[T any] struct {
+	fields      map[string]Field[T]
+	key         string
+	defaultSort []SortField
+	limit       int
+	maxLimit    int
+}
+
+// NewSchema creates schema with unique key field, e.g. primary key, which is sortable and filterable.
+func NewSchema[T any](key Field[T]) *Schema[T] {
+	key.Sortable, key.Filterable = true, true
+	return &Schema[T]{
+		fields:   map[string]Field[T]{key.Name: key},
+		key:      key.Name,
+		limit:    DefaultLimit,
+		maxLimit: DefaultMaxLimit,
+	}
+}
+
+// WithField exposes field for sorting or filtering.
+func (s *Schema[T]) WithField(field Field[T]) *Schema[T] {
+	s.fields[field.Name] = field
+	return s
+}
+
+// WithDefaultSort sets order of queries without sort, default is key ascending.
+func (s *Schema[T]) WithDefaultSort(sort ...SortField) *Schema[T] {
+	s.defaultSort = sort
+	return s
+}
+
+// WithLimits sets limit of queries without limit and max allowed one, bigger limits are lowered to max.
+func (s *Schema[T]) WithLimits(limit, maxLimit int) *Schema[T] {
+	s.maxLimit = max(1, maxLimit)
+	s.limit = min(max(1, limit), s.maxLimit)
+	return s
+}
+
+// Query appends conditions, order and limit of q to base select without WHERE clause, e.g. SELECT * FROM users.
+// Limit is one more than page size to find out if there is a next page, pass selected items to Page.
+func (s *Schema[T]) Query(base string, q Query) (query string, args []any, err error) {
+	sort, err := s.sort(q)
+	if err != nil {
+		return "", nil, err
+	}
+	var conditions []string
+	for _, filter := range q.Filters {
+		condition, filterArgs, err := s.filter(filter, len(args))
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, filterArgs...)
+	}
+	if q.Cursor != "" {
+		values, err := decodeCursor(q.Cursor, sort)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, s.keyset(sort, len(args)))
+		args = append(args, values...)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(base)
+	if len(conditions) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(strings.Join(conditions, " AND "))
+	}
+	order := make([]string, len(sort))
+	for i, field := range sort {
+		order[i] = s.column(field.Field) + direction(field.Desc)
+	}
+	fmt.Fprintf(&sb, " ORDER BY %s LIMIT %d", strings.Join(order, ", "), s.pageLimit(q)+1)
+	return sb.String(), args, nil
+}
+
+// Page trims items selected by Query to the page size and sets cursor of the next page.
+func (s *Schema[T]) Page(items []T, q Query) (Page[T], error) {
+	limit := s.pageLimit(q)
+	if items == nil {
+		// empty page is encoded as empty list
+		items = []T{}
+	}
+	if len(items) <= limit {
+		return Page[T]{Items: items}, nil
+	}
+	sort, err := s.sort(q)
+	if err != nil {
+		return Page[T]{}, err
+	}
+	items = items[:limit]
+	last := items[limit-1]
+	values := make([]any, len(sort))
+	for i, field := range sort {
+		values[i] = s.fields[field.Field].Value(last)
+	}
+	next, err := encodeCursor(sort, values)
+	if err != nil {
+		return Page[T]{}, err
+	}
+	return Page[T]{Items: items, NextCursor: next}, nil
+}
+
+func (s *Schema[T]) pageLimit(q Query) int {
+	if q.Limit <= 0 {
+		return s.limit
+	}
+	return min(q.Limit, s.maxLimit)
+}
+
+// sort returns validated order of q with the key field at the end.
+func (s *Schema[T]) sort(q Query) ([]SortField, error) {
+	sort := q.Sort
+	if len(sort) == 0 {
+		sort = s.defaultSort
+	}
+	resolved := make([]SortField, 0, len(sort)+1)
+	hasKey := false
+	for _, field := range sort {
+		if !s.fields[field.Field].Sortable {
+			return nil, fmt.Errorf("%w: field %q is not sortable", ErrInvalidQuery, field.Field)
+		}
+		resolved = append(resolved, field)
+		if field.Field == s.key {
+			// key is unique, fields after it don't change the order
+			hasKey = true
+			break
+		}
+	}
+	if !hasKey {
+		resolved = append(resolved, SortField{Field: s.key})
+	}
+	return resolved, nil
+}
+
+func (s *Schema[T]) filter(filter Filter, argsBefore int) (condition string, args []any, err error) {
+	field, ok := s.fields[filter.Field]
+	if !ok || !field.Filterable {
+		return "", nil, fmt.Errorf("%w: field %q is not filterable", ErrInvalidQuery, filter.Field)
+	}
+	if len(filter.Values) == 0 || (filter.Op != OpIn && len(filter.Values) > 1) {
+		return "", nil, fmt.Errorf("%w: wrong number of %q filter values", ErrInvalidQuery, filter.Field)
+	}
+	placeholders := make([]string, len(filter.Values))
+	for i, raw := range filter.Values {
+		var value any = raw
+		if field.Parse != nil {
+			if value, err = field.Parse(raw); err != nil {
+				return "", nil, fmt.Errorf("%w: field %q value %q: %w", ErrInvalidQuery, filter.Field, raw, err)
+			}
+		}
+		args = append(args, value)
+		placeholders[i] = placeholder(argsBefore + len(args))
+	}
+	column := s.column(filter.Field)
+	if filter.Op == OpIn {
+		return fmt.Sprintf("%s IN (%s)", column, strings.Join(placeholders, ", ")), args, nil
+	}
+	op, ok := operatorsSQL[filter.Op]
+	if !ok {
+		return "", nil, fmt.Errorf("%w: unknown filter operator %q", ErrInvalidQuery, filter.Op)
+	}
+	return fmt.Sprintf("%s %s %s", column, op, placeholders[0]), args, nil
+}
+
+// keyset returns condition selecting rows after the cursor row in sort order, e.g. for a, -b:
+// (a > $1 OR (a = $1 AND b < $2)). Cursor values are the following args.
+func (s *Schema[T]) keyset(sort []SortField, argsBefore int) string {
+	alternatives := make([]string, len(sort))
+	for i, field := range sort {
+		parts := make([]string, 0, i+1)
+		for j := range i {
+			parts = append(parts, fmt.Sprintf("%s = %s", s.column(sort[j].Field), placeholder(argsBefore+j+1)))
+		}
+		op := ">"
+		if field.Desc {
+			op = "<"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %s", s.column(field.Field), op, placeholder(argsBefore+i+1)))
+		alternatives[i] = strings.Join(parts, " AND ")
+		if i > 0 {
+			alternatives[i] = "(" + alternatives[i] + ")"
+		}
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")"
+}
+
+func (s *Schema[T]) column(field string) string {
+	return utils.DialectPostgres.QuoteIdentifier(s.fields[field].Column)
+}
+
+func placeholder(n int) string {
+	return utils.PQParamPlaceholder + strconv.Itoa(n)
+}
+
+func direction(desc bool) string {
+	if desc {
+		return " DESC"
+	}
+	return " ASC"
+}
+
+func sortSignature(sort []SortField) string {
+	fields := make([]string, len(sort))
+	for i, field := range sort {
+		fields[i] = field.Field
+		if field.Desc {
+			fields[i] = "-" + field.Field
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+func encodeCursor(sort []SortField, values []any) (string, error) {
+	data, err := json.Marshal(cursor{Sort: sortSignature(sort), Values: values})
+	if err != nil {
+		return "", fmt.Errorf("encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(encoded string, sort []SortField) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	// numbers are kept as json.Number to not lose precision of big integers
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var c cursor
+	if err = decoder.Decode(&c); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	if c.Sort != sortSignature(sort) || len(c.Values) != len(sort) {
+		return nil, fmt.Errorf("%w: cursor doesn't match sort order", ErrInvalidQuery)
+	}
+	return c.Values, nil
+}
@@ -0,0 +1,179 @@
+package repogen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"go_project_template/internal/utils"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+var repoTemplate = template.Must(template.New("repo").Parse(`// Code generated by repogen; DO NOT EDIT.
+
+package {{ .Package }}
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go_project_template/internal/utils"
+{{- if .Imports }}
+{{ range .Imports }}
+	{{ . }}
+{{- end }}
+{{- end }}
+)
+
+// {{ .Var }}Values maps {{ .Name }} fields to {{ .Table }} columns.
+func {{ .Var }}Values(e {{ .Name }}) map[string]any {
+	return map[string]any{
+{{- range .Fields }}
+		"{{ .Column }}": e.{{ .Name }},
+{{- end }}
+	}
+}
+
+var (
+	{{ .Var }}Insert = utils.NewInsertBuilder(utils.DialectPostgres, "{{ .Table }}", {{ .Var }}Values)
+	{{ .Var }}Upsert = utils.NewInsertBuilder(utils.DialectPostgres, "{{ .Table }}", {{ .Var }}Values).
+		WithUpsert([]string{"{{ .PK.Column }}"})
+)
+
+// Create{{ .Name }} inserts {{ .Name }} into {{ .Table }}.
+func (r *Repo) Create{{ .Name }}(ctx context.Context, e *{{ .Name }}) error {
+	query, params, err := {{ .Var }}Insert.Build([]{{ .Name }}{*e})
+	if err != nil {
+		return fmt.Errorf("build {{ .Table }} insert: %w", err)
+	}
+	if _, err = r.db.Client().ExecContext(ctx, query, params...); err != nil {
+		return fmt.Errorf("create {{ .Var }}: %w", err)
+	}
+	return nil
+}
+
+// Get{{ .Name }} returns {{ .Name }} by primary key, ErrNotFound if it doesn't exist.
+func (r *Repo) Get{{ .Name }}(ctx context.Context, {{ .PKArg }} {{ .PK.Type }}) (*{{ .Name }}, error) {
+	var e {{ .Name }}
+	err := r.db.Client().GetContext(ctx, &e, ` + "`{{ .SelectSQL }}`" + `, {{ .PKArg }})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get {{ .Var }}: %w", err)
+	}
+	return &e, nil
+}
+
+// Update{{ .Name }} updates all columns of {{ .Name }} by primary key, ErrNotFound if it doesn't exist.
+func (r *Repo) Update{{ .Name }}(ctx context.Context, e *{{ .Name }}) error {
+	res, err := r.db.Client().ExecContext(ctx, ` + "`{{ .UpdateSQL }}`" + `,
+{{- range .UpdateFields }}
+		e.{{ .Name }},
+{{- end }}
+		e.{{ .PK.Name }},
+	)
+	if err != nil {
+		return fmt.Errorf("update {{ .Var }}: %w", err)
+	}
+	return requireAffected(res)
+}
+
+// Delete{{ .Name }} deletes {{ .Name }} by primary key, ErrNotFound if it doesn't exist.
+func (r *Repo) Delete{{ .Name }}(ctx context.Context, {{ .PKArg }} {{ .PK.Type }}) error {
+	res, err := r.db.Client().ExecContext(ctx, ` + "`{{ .DeleteSQL }}`" + `, {{ .PKArg }})
+	if err != nil {
+		return fmt.Errorf("delete {{ .Var }}: %w", err)
+	}
+	return requireAffected(res)
+}
+
+// BulkInsert{{ .Name }}s inserts entities in one transaction.
+func (r *Repo) BulkInsert{{ .Name }}s(ctx context.Context, entities []{{ .Name }}) error {
+	return execBatches(ctx, r.db, {{ .Var }}Insert, entities, {{ len .Fields }})
+}
+
+// Upsert{{ .Name }}s inserts entities or updates existing ones by primary key in one transaction.
+func (r *Repo) Upsert{{ .Name }}s(ctx context.Context, entities []{{ .Name }}) error {
+	return execBatches(ctx, r.db, {{ .Var }}Upsert, entities, {{ len .Fields }})
+}
+
+// List{{ .Name }}s returns up to limit entities ordered by primary key which is greater than after,
+// zero after returns the first page.
+func (r *Repo) List{{ .Name }}s(ctx context.Context, after {{ .PK.Type }}, limit int) ([]{{ .Name }}, error) {
+	entities := make([]{{ .Name }}, 0, limit)
+	if err := r.db.Client().SelectContext(ctx, &entities, ` + "`{{ .ListSQL }}`" + `, after, limit); err != nil {
+		return nil, fmt.Errorf("list {{ .Var }}s: %w", err)
+	}
+	return entities, nil
+}
+`))
+
+type templateData struct {
+	*Entity
+	Var          string
+	PKArg        string
+	UpdateFields []Field
+	SelectSQL    string
+	UpdateSQL    string
+	DeleteSQL    string
+	ListSQL      string
+}
+
+// Generate returns formatted source of repository methods of entity.
+func Generate(entity *Entity) ([]byte, error) {
+	quote := utils.DialectPostgres.QuoteIdentifier
+	data := templateData{
+		Entity: entity,
+		Var:    lowerFirst(entity.Name),
+		PKArg:  lowerFirst(entity.PK().Name),
+	}
+	if token.IsKeyword(data.PKArg) {
+		data.PKArg = "pk"
+	}
+	table, pk := quote(entity.Table), quote(entity.PK().Column)
+	columns := make([]string, 0, len(entity.Fields))
+	sets := make([]string, 0, len(entity.Fields))
+	for _, field := range entity.Fields {
+		columns = append(columns, quote(field.Column))
+		if !field.PK {
+			data.UpdateFields = append(data.UpdateFields, field)
+			sets = append(sets, fmt.Sprintf("%s = $%d", quote(field.Column), len(sets)+1))
+		}
+	}
+	selected := strings.Join(columns, ", ")
+	data.SelectSQL = fmt.Sprintf("SELECT %s FROM %s WHERE %s = $1", selected, table, pk)
+	data.UpdateSQL = fmt.Sprintf("UPDATE %s SET %s WHERE %s = $%d", table, strings.Join(sets, ", "), pk, len(sets)+1)
+	data.DeleteSQL = fmt.Sprintf("DELETE FROM %s WHERE %s = $1", table, pk)
+	data.ListSQL = fmt.Sprintf("SELECT %s FROM %s WHERE %s > $1 ORDER BY %s LIMIT $2", selected, table, pk, pk)
+
+	var buf bytes.Buffer
+	if err := repoTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("execute template: %w", err)
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w", err)
+	}
+	return src, nil
+}
+
+// lowerFirst makes identifier unexported, e.g. ID -> id, UserID -> userID.
+func lowerFirst(name string) string {
+	runes := []rune(name)
+	i := 0
+	for i < len(runes) && unicode.IsUpper(runes[i]) {
+		i++
+	}
+	// keep the last upper rune of acronym followed by lower ones, e.g. HTTPServer -> httpServer
+	if i > 1 && i < len(runes) {
+		i--
+	}
+	for j := 0; j < i; j++ {
+		runes[j] = unicode.ToLower(runes[j])
+	}
+	return string(runes)
+}
@@ -0,0 +1,158 @@
+package repogen
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrTypeNotFound = errors.New("struct type not found")
+	ErrNoPrimaryKey = errors.New("no field is tagged as primary key")
+	ErrNoColumns    = errors.New("no db tagged fields")
+)
+
+// Field is a struct field mapped to a column with `db:"column"` tag,
+// `db:"column,pk"` marks the primary key.
+type Field struct {
+	Name   string
+	Column string
+	Type   string
+	PK     bool
+}
+
+// Entity is a db tagged struct repository methods are generated for.
+type Entity struct {
+	Package string
+	Name    string
+	Table   string
+	Fields  []Field
+	// Imports are import specs of packages used by primary key type, it appears in generated method signatures
+	Imports []string
+}
+
+// PK returns primary key field.
+func (e *Entity) PK() Field {
+	for _, field := range e.Fields {
+		if field.PK {
+			return field
+		}
+	}
+	return Field{}
+}
+
+// Parse finds struct typeName in non-test go files of dir.
+func Parse(dir, typeName, table string) (*Entity, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, fmt.Errorf("list go files: %w", err)
+	}
+	fset := token.NewFileSet()
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(name) //nolint:gosec // files of the package being generated
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+		file, err := parser.ParseFile(fset, name, src, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", name, err)
+		}
+		if spec := findStruct(file, typeName); spec != nil {
+			return newEntity(fset, file, typeName, table, spec)
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrTypeNotFound, typeName)
+}
+
+func findStruct(file *ast.File, typeName string) *ast.StructType {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			typeSpec, ok := spec.(*ast.TypeSpec)
+			if !ok || typeSpec.Name.Name != typeName {
+				continue
+			}
+			if st, ok := typeSpec.Type.(*ast.StructType); ok {
+				return st
+			}
+		}
+	}
+	return nil
+}
+
+func newEntity(fset *token.FileSet, file *ast.File, typeName, table string, st *ast.StructType) (*Entity, error) {
+	entity := &Entity{Package: file.Name.Name, Name: typeName, Table: table}
+	var pkType ast.Expr
+	for _, field := range st.Fields.List {
+		if field.Tag == nil || len(field.Names) == 0 {
+			continue
+		}
+		tag, err := strconv.Unquote(field.Tag.Value)
+		if err != nil {
+			return nil, fmt.Errorf("field %s tag: %w", field.Names[0].Name, err)
+		}
+		column, options, _ := strings.Cut(reflect.StructTag(tag).Get("db"), ",")
+		if column == "" || column == "-" {
+			continue
+		}
+		var typ strings.Builder
+		if err := printer.Fprint(&typ, fset, field.Type); err != nil {
+			return nil, fmt.Errorf("field %s type: %w", field.Names[0].Name, err)
+		}
+		pk := options == "pk" && pkType == nil
+		if pk {
+			pkType = field.Type
+		}
+		for _, name := range field.Names {
+			entity.Fields = append(entity.Fields, Field{Name: name.Name, Column: column, Type: typ.String(), PK: pk})
+		}
+	}
+	if len(entity.Fields) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoColumns, typeName)
+	}
+	if pkType == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoPrimaryKey, typeName)
+	}
+	entity.Imports = typeImports(file, pkType)
+	return entity, nil
+}
+
+// typeImports returns import specs of packages referenced by typ.
+func typeImports(file *ast.File, typ ast.Expr) []string {
+	var imports []string
+	ast.Inspect(typ, func(node ast.Node) bool {
+		sel, ok := node.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		pkg, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		for _, spec := range file.Imports {
+			importPath, _ := strconv.Unquote(spec.Path.Value)
+			if spec.Name == nil && path.Base(importPath) == pkg.Name {
+				imports = append(imports, spec.Path.Value)
+			} else if spec.Name != nil && spec.Name.Name == pkg.Name {
+				imports = append(imports, spec.Name.Name+" "+spec.Path.Value)
+			}
+		}
+		return false
+	})
+	return imports
+}
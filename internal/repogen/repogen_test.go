@@ -0,0 +1,57 @@
+package repogen_test
+
+import (
+	"flag"
+	"go_project_template/internal/repogen"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+func TestParse(t *testing.T) {
+	entity, err := repogen.Parse("testdata", "Order", "shop.orders")
+	require.NoError(t, err)
+	require.Equal(t, "shop", entity.Package)
+	require.Equal(t, []repogen.Field{
+		{Name: "ID", Column: "id", Type: "OrderID", PK: true},
+		{Name: "Customer", Column: "customer", Type: "string"},
+		{Name: "Total", Column: "total", Type: "dec.Decimal"},
+	}, entity.Fields)
+	require.Empty(t, entity.Imports)
+
+	_, err = repogen.Parse("testdata", "Invoice", "invoices")
+	require.ErrorIs(t, err, repogen.ErrNoPrimaryKey)
+	_, err = repogen.Parse("testdata", "Missing", "missing")
+	require.ErrorIs(t, err, repogen.ErrTypeNotFound)
+}
+
+func TestGenerate(t *testing.T) {
+	entity, err := repogen.Parse("testdata", "Order", "shop.orders")
+	require.NoError(t, err)
+	src, err := repogen.Generate(entity)
+	require.NoError(t, err)
+
+	path := filepath.Join("testdata", "order_repo_gen.golden")
+	if *updateGolden {
+		require.NoError(t, os.WriteFile(path, src, 0o600))
+	}
+	expected, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, string(expected), string(src))
+}
+
+func TestGeneratedRepositoryIsUpToDate(t *testing.T) {
+	entity, err := repogen.Parse("../repository", "User", "users")
+	require.NoError(t, err)
+	require.Equal(t, []string{`"github.com/google/uuid"`}, entity.Imports)
+	src, err := repogen.Generate(entity)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile("../repository/user_repo_gen.go")
+	require.NoError(t, err)
+	require.Equal(t, string(generated), string(src), "run make gogen")
+}
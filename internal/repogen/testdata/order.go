@@ -0,0 +1,19 @@
+package shop
+
+import (
+	dec "github.com/shopspring/decimal"
+)
+
+type OrderID int64
+
+type Order struct {
+	ID       OrderID     `db:"id,pk"`
+	Customer string      `db:"customer"`
+	Total    dec.Decimal `db:"total"`
+	Comment  string      `db:"-"`
+	internal int
+}
+
+type Invoice struct {
+	Number string `db:"number"`
+}
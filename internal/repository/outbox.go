@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// OutboxEvent is an event stored in outbox table in the same transaction as the change it describes,
+// relay publishes it after commit.
+type OutboxEvent struct {
+	ID        int64           `db:"id" json:"id"`
+	Topic     string          `db:"topic" json:"topic"`
+	Key       string          `db:"event_key" json:"key"`
+	Payload   json.RawMessage `db:"payload" json:"payload"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+	Attempts  int             `db:"attempts" json:"attempts"`
+}
+
+// RunInTx runs fn in transaction which is committed if fn returns nil and rolled back otherwise.
+func (r *Repo) RunInTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	tx, err := r.db.Client().BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		// no-op after commit
+		_ = tx.Rollback()
+	}()
+	if err = fn(tx); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+// EnqueueEvent stores event with payload encoded to JSON in outbox within tx,
+// so it is published only if tx is committed.
+func (r *Repo) EnqueueEvent(ctx context.Context, tx *sqlx.Tx, topic, key string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal %s event payload: %w", topic, err)
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO outbox (topic, event_key, payload) VALUES ($1, $2, $3)`, topic, key, data)
+	if err != nil {
+		return fmt.Errorf("enqueue %s event: %w", topic, err)
+	}
+	return nil
+}
+
+// DeliverOutboxEvents locks up to limit due events with FOR UPDATE SKIP LOCKED, so concurrent relays
+// take different events, and passes them to deliver in order of enqueueing. Delivered events are marked,
+// failed ones are retried after retryDelay of their attempts number. Returns number of taken events.
+func (r *Repo) DeliverOutboxEvents(
+	ctx context.Context, limit int, deliver func(event OutboxEvent) error, retryDelay func(attempts int) time.Duration,
+) (int, error) {
+	taken := 0
+	err := r.RunInTx(ctx, func(tx *sqlx.Tx) error {
+		var events []OutboxEvent
+		err := tx.SelectContext(ctx, &events, `SELECT id, topic, event_key, payload, created_at, attempts FROM outbox
+			WHERE delivered_at IS NULL AND next_attempt_at <= now()
+			ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED`, limit)
+		if err != nil {
+			return fmt.Errorf("select outbox events: %w", err)
+		}
+		taken = len(events)
+		for _, event := range events {
+			if deliverErr := deliver(event); deliverErr != nil {
+				_, err = tx.ExecContext(ctx, `UPDATE outbox SET attempts = attempts + 1, last_error = $2,
+					next_attempt_at = now() + make_interval(secs => $3) WHERE id = $1`,
+					event.ID, deliverErr.Error(), retryDelay(event.Attempts+1).Seconds())
+			} else {
+				_, err = tx.ExecContext(ctx, `UPDATE outbox SET attempts = attempts + 1, delivered_at = now() WHERE id = $1`, event.ID)
+			}
+			if err != nil {
+				return fmt.Errorf("mark outbox event %d: %w", event.ID, err)
+			}
+		}
+		return nil
+	})
+	return taken, err
+}
+
+// DeleteDeliveredOutboxEvents deletes events delivered before the time, returns number of deleted events.
+func (r *Repo) DeleteDeliveredOutboxEvents(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.Client().ExecContext(ctx, `DELETE FROM outbox WHERE delivered_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("delete delivered outbox events: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,72 @@
+package repository_test
+
+import (
+	"errors"
+	"go_project_template/internal/repository"
+	testhelpers "go_project_template/internal/test_helpers"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutbox(t *testing.T) {
+	container := testhelpers.GetClean(t)
+	repo, ctx := container.Repo, container.Ctx
+	noRetry := func(int) time.Duration { return time.Hour }
+
+	t.Run("should enqueue events only with committed changes", func(t *testing.T) {
+		user := newUser("outbox")
+		require.NoError(t, repo.RunInTx(ctx, func(tx *sqlx.Tx) error {
+			return repo.EnqueueEvent(ctx, tx, "user.created", user.ID.String(), user)
+		}))
+		errRollback := errors.New("rollback")
+		require.ErrorIs(t, repo.RunInTx(ctx, func(tx *sqlx.Tx) error {
+			require.NoError(t, repo.EnqueueEvent(ctx, tx, "user.deleted", user.ID.String(), user))
+			return errRollback
+		}), errRollback)
+
+		var delivered []repository.OutboxEvent
+		taken, err := repo.DeliverOutboxEvents(ctx, 10, func(event repository.OutboxEvent) error {
+			delivered = append(delivered, event)
+			return nil
+		}, noRetry)
+		require.NoError(t, err)
+		require.Equal(t, 1, taken)
+		require.Equal(t, "user.created", delivered[0].Topic)
+		require.Equal(t, user.ID.String(), delivered[0].Key)
+
+		// delivered events are not taken again
+		taken, err = repo.DeliverOutboxEvents(ctx, 10, func(repository.OutboxEvent) error { return nil }, noRetry)
+		require.NoError(t, err)
+		require.Zero(t, taken)
+		deleted, err := repo.DeleteDeliveredOutboxEvents(ctx, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, int64(1), deleted)
+	})
+	t.Run("should postpone failed events and skip locked ones", func(t *testing.T) {
+		require.NoError(t, repo.RunInTx(ctx, func(tx *sqlx.Tx) error {
+			for range 2 {
+				if err := repo.EnqueueEvent(ctx, tx, "user.updated", "", map[string]int{"version": 2}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}))
+
+		// while the first relay holds one event, the second one takes the other
+		_, err := repo.DeliverOutboxEvents(ctx, 1, func(repository.OutboxEvent) error {
+			taken, err := repo.DeliverOutboxEvents(ctx, 10, func(repository.OutboxEvent) error { return nil }, noRetry)
+			require.NoError(t, err)
+			require.Equal(t, 1, taken)
+			return errors.New("broker is down")
+		}, noRetry)
+		require.NoError(t, err)
+
+		// the failed event is postponed
+		taken, err := repo.DeliverOutboxEvents(ctx, 10, func(repository.OutboxEvent) error { return nil }, noRetry)
+		require.NoError(t, err)
+		require.Zero(t, taken)
+	})
+}
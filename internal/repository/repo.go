@@ -1,15 +1,74 @@
 package repository
 
-import "go_project_template/internal/storage/database"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go_project_template/internal/storage/database"
+	"go_project_template/internal/utils"
+)
+
+var ErrNotFound = errors.New("not found")
 
 type Repo struct {
-	db database.DBConnector
+	db   database.DBConnector
+	jobs *database.JobQueue
 }
 
 var AllTables = []string{
 	// add table names here
+	"users",
+	"outbox",
+	"jobs",
 }
 
 func InitRepo(db database.DBConnector) *Repo {
-	return &Repo{db: db}
+	return &Repo{db: db, jobs: database.NewJobQueue(db)}
+}
+
+// Jobs returns queue of background jobs.
+func (r *Repo) Jobs() *database.JobQueue {
+	return r.jobs
+}
+
+// requireAffected returns ErrNotFound if statement changed no rows.
+func requireAffected(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// execBatches writes entities with builder in one transaction, split into statements
+// under database.PostgresMaxParams parameters.
+func execBatches[T any](ctx context.Context, db database.DBConnector, builder *utils.InsertBuilder[T], entities []T, columns int) error {
+	if len(entities) == 0 {
+		return nil
+	}
+	tx, err := db.Client().BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		// no-op after commit
+		_ = tx.Rollback()
+	}()
+	for _, chunk := range utils.ChunkSlice(entities, database.PostgresMaxParams/columns) {
+		query, params, err := builder.Build(chunk)
+		if err != nil {
+			return fmt.Errorf("build batch: %w", err)
+		}
+		if _, err = tx.ExecContext(ctx, query, params...); err != nil {
+			return fmt.Errorf("exec batch: %w", err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
 }
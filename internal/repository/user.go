@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"go_project_template/internal/pagination"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//go:generate go run go_project_template/cmd/repogen -type User -table users
+
+// UsersChangesChannel receives database.RowChange notifications on every users row change.
+const UsersChangesChannel = "users_changes"
+
+type User struct {
+	ID        uuid.UUID `db:"u_id,pk" json:"id"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	Version   int       `db:"user_version" json:"version"`
+	Email     string    `db:"email" json:"email"`
+	Locale    string    `db:"user_locale" json:"locale"`
+	Name      string    `db:"user_name" json:"name"`
+}
+
+var userListSchema = pagination.NewSchema(pagination.Field[User]{
+	Name: "id", Column: "u_id", Value: func(u User) any { return u.ID },
+}).
+	WithField(pagination.Field[User]{
+		Name: "created_at", Column: "created_at", Value: func(u User) any { return u.CreatedAt },
+		Parse: pagination.ParseTime, Sortable: true, Filterable: true,
+	}).
+	WithField(pagination.Field[User]{
+		Name: "email", Column: "email", Value: func(u User) any { return u.Email }, Sortable: true, Filterable: true,
+	}).
+	WithField(pagination.Field[User]{Name: "locale", Column: "user_locale", Filterable: true}).
+	WithField(pagination.Field[User]{Name: "name", Column: "user_name", Filterable: true}).
+	WithDefaultSort(pagination.SortField{Field: "created_at", Desc: true})
+
+// FindUsers returns page of users matching q, newest first by default.
+func (r *Repo) FindUsers(ctx context.Context, q pagination.Query) (pagination.Page[User], error) {
+	query, args, err := userListSchema.Query(`SELECT * FROM "users"`, q)
+	if err != nil {
+		return pagination.Page[User]{}, err
+	}
+	var users []User
+	if err = r.db.Client().SelectContext(ctx, &users, query, args...); err != nil {
+		return pagination.Page[User]{}, fmt.Errorf("find users: %w", err)
+	}
+	return userListSchema.Page(users, q)
+}
@@ -0,0 +1,82 @@
+package repository_test
+
+import (
+	"go_project_template/internal/repository"
+	testhelpers "go_project_template/internal/test_helpers"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func newUser(name string) repository.User {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	return repository.User{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Version:   1,
+		Email:     name + "@example.com",
+		Locale:    "en",
+		Name:      name,
+	}
+}
+
+func requireSameUser(t *testing.T, expected repository.User, actual *repository.User) {
+	t.Helper()
+	require.NotNil(t, actual)
+	require.True(t, expected.CreatedAt.Equal(actual.CreatedAt))
+	require.True(t, expected.UpdatedAt.Equal(actual.UpdatedAt))
+	actual.CreatedAt, actual.UpdatedAt = expected.CreatedAt, expected.UpdatedAt
+	require.Equal(t, expected, *actual)
+}
+
+func TestUserRepository(t *testing.T) {
+	container := testhelpers.GetClean(t)
+	repo, ctx := container.Repo, container.Ctx
+
+	t.Run("should create, update and delete user", func(t *testing.T) {
+		user := newUser("alice")
+		require.NoError(t, repo.CreateUser(ctx, &user))
+		stored, err := repo.GetUser(ctx, user.ID)
+		require.NoError(t, err)
+		requireSameUser(t, user, stored)
+
+		user.Name, user.Version = "alice2", 2
+		require.NoError(t, repo.UpdateUser(ctx, &user))
+		stored, err = repo.GetUser(ctx, user.ID)
+		require.NoError(t, err)
+		requireSameUser(t, user, stored)
+
+		require.NoError(t, repo.DeleteUser(ctx, user.ID))
+		_, err = repo.GetUser(ctx, user.ID)
+		require.ErrorIs(t, err, repository.ErrNotFound)
+		require.ErrorIs(t, repo.DeleteUser(ctx, user.ID), repository.ErrNotFound)
+		require.ErrorIs(t, repo.UpdateUser(ctx, &user), repository.ErrNotFound)
+	})
+	t.Run("should bulk insert, upsert and list users", func(t *testing.T) {
+		users := []repository.User{newUser("a"), newUser("b"), newUser("c")}
+		require.NoError(t, repo.BulkInsertUsers(ctx, users))
+
+		users[0].Name = "a2"
+		users = append(users, newUser("d"))
+		require.NoError(t, repo.UpsertUsers(ctx, users))
+		stored, err := repo.GetUser(ctx, users[0].ID)
+		require.NoError(t, err)
+		require.Equal(t, "a2", stored.Name)
+
+		var listed []repository.User
+		after := uuid.UUID{}
+		for {
+			page, err := repo.ListUsers(ctx, after, 3)
+			require.NoError(t, err)
+			if len(page) == 0 {
+				break
+			}
+			listed = append(listed, page...)
+			after = page[len(page)-1].ID
+		}
+		require.Len(t, listed, len(users))
+	})
+}
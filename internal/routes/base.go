@@ -1,11 +1,13 @@
 package routes
 
 import (
+	"crypto/subtle"
 	"go_project_template/internal/logger"
 	"go_project_template/internal/service/sampler"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/gofiber/fiber/v2/middleware/keyauth"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
@@ -20,7 +22,10 @@ type Server struct {
 }
 
 // InitAppRouter initializes the HTTP Server.
-func InitAppRouter(log logger.AppLogger, service *sampler.Service, address string, enableTelemetry bool) *Server {
+// Extra collectors, e.g. utils.CacheCollector with named caches, are exposed on /metrics with telemetry enabled.
+func InitAppRouter(
+	log logger.AppLogger, service *sampler.Service, address string, enableTelemetry bool, extraCollectors ...prometheus.Collector,
+) *Server {
 	app := &Server{
 		appAddr:    address,
 		httpEngine: fiber.New(fiber.Config{}),
@@ -35,6 +40,7 @@ func InitAppRouter(log logger.AppLogger, service *sampler.Service, address strin
 			collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
 			collectors.NewBuildInfoCollector(),
 		)
+		reg.MustRegister(extraCollectors...)
 		app.httpEngine.Get("/metrics", adaptor.HTTPHandler(promhttp.HandlerFor(reg, promhttp.HandlerOpts{})))
 	}
 	app.initRoutes()
@@ -47,6 +53,37 @@ func (s *Server) initRoutes() {
 	})
 }
 
+// WithAdminToken serves /admin endpoints to requests with the token as bearer credentials,
+// they are not registered without a token.
+func (s *Server) WithAdminToken(token string) *Server {
+	if token == "" {
+		return s
+	}
+	admin := s.httpEngine.Group("/admin", keyauth.New(keyauth.Config{
+		Validator: func(_ *fiber.Ctx, key string) (bool, error) {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(token)) != 1 {
+				return false, keyauth.ErrMissingOrMalformedAPIKey
+			}
+			return true, nil
+		},
+	}))
+	admin.Get("/users", s.listUsers)
+	s.initJobRoutes(admin)
+	return s
+}
+
+func (s *Server) listUsers(ctx *fiber.Ctx) error {
+	q, err := listQuery(ctx)
+	if err != nil {
+		return err
+	}
+	page, err := s.service.ListUsers(ctx.UserContext(), q)
+	if err != nil {
+		return s.listError("users", err)
+	}
+	return ctx.JSON(page)
+}
+
 // Run starts the HTTP Server.
 func (s *Server) Run() error {
 	s.log.Info("Starting HTTP server", logger.WithString("port", s.appAddr))
@@ -1,8 +1,14 @@
 package routes_test
 
 import (
+	"go_project_template/internal/pagination"
+	"go_project_template/internal/repository"
 	testhelpers "go_project_template/internal/test_helpers"
 	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCheckPing(t *testing.T) {
@@ -19,3 +25,43 @@ func TestCheckPing(t *testing.T) {
 		srv.Get(t, "/unknown").RequireStatus(t, 404)
 	})
 }
+
+func TestListUsers(t *testing.T) {
+	container := testhelpers.GetClean(t)
+	srv := testhelpers.NewTestServer(t, container)
+	for _, name := range []string{"a", "b", "c"} {
+		require.NoError(t, container.Repo.CreateUser(container.Ctx, &repository.User{
+			ID: uuid.New(), CreatedAt: time.Now(), Email: name + "@example.com", Name: name,
+		}))
+	}
+
+	t.Run("401 without token", func(t *testing.T) {
+		srv.Get(t, "/admin/users").RequireUnauthorized(t)
+		srv.Get(t, "/users").RequireStatus(t, 404)
+	})
+	srv.AuthUser("admin")
+	t.Run("200 with pages", func(t *testing.T) {
+		var first, second pagination.Page[repository.User]
+		srv.Get(t, "/admin/users?limit=2&sort=email").RequireOk(t).RequireUnmarshal(t, &first)
+		require.Len(t, first.Items, 2)
+		require.Equal(t, "a", first.Items[0].Name)
+		require.NotEmpty(t, first.NextCursor)
+
+		srv.Get(t, "/admin/users?limit=2&sort=email&cursor="+first.NextCursor).RequireOk(t).RequireUnmarshal(t, &second)
+		require.Len(t, second.Items, 1)
+		require.Equal(t, "c", second.Items[0].Name)
+		require.Empty(t, second.NextCursor)
+	})
+	t.Run("200 with filters", func(t *testing.T) {
+		var page pagination.Page[repository.User]
+		srv.Get(t, "/admin/users?filter=name:in:a,c&filter=email:ne:a@example.com").RequireOk(t).RequireUnmarshal(t, &page)
+		require.Len(t, page.Items, 1)
+		require.Equal(t, "c", page.Items[0].Name)
+	})
+	t.Run("400 on invalid query", func(t *testing.T) {
+		srv.Get(t, "/admin/users?limit=-1").RequireBadRequest(t)
+		srv.Get(t, "/admin/users?sort=user_name").RequireBadRequest(t)
+		srv.Get(t, "/admin/users?filter=locale:regex:en").RequireBadRequest(t)
+		srv.Get(t, "/admin/users?cursor=broken").RequireBadRequest(t)
+	})
+}
@@ -0,0 +1,58 @@
+package routes
+
+import (
+	"errors"
+	"go_project_template/internal/storage/database"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// initJobRoutes registers admin endpoints of background jobs.
+func (s *Server) initJobRoutes(admin fiber.Router) {
+	jobs := admin.Group("/jobs")
+	jobs.Get("/", s.listJobs)
+	jobs.Post("/:id/retry", s.retryJob)
+	jobs.Post("/:id/cancel", s.cancelJob)
+}
+
+func (s *Server) listJobs(ctx *fiber.Ctx) error {
+	q, err := listQuery(ctx)
+	if err != nil {
+		return err
+	}
+	page, err := s.service.ListJobs(ctx.UserContext(), q)
+	if err != nil {
+		return s.listError("jobs", err)
+	}
+	return ctx.JSON(page)
+}
+
+func (s *Server) retryJob(ctx *fiber.Ctx) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	return s.jobResult(ctx, s.service.RetryJob(ctx.UserContext(), int64(id)))
+}
+
+func (s *Server) cancelJob(ctx *fiber.Ctx) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	return s.jobResult(ctx, s.service.CancelJob(ctx.UserContext(), int64(id)))
+}
+
+func (s *Server) jobResult(ctx *fiber.Ctx, err error) error {
+	switch {
+	case err == nil:
+		return ctx.SendStatus(fiber.StatusNoContent)
+	case errors.Is(err, database.ErrJobNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, database.ErrJobState):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	default:
+		s.log.Error("unable to update job", err)
+		return fiber.ErrInternalServerError
+	}
+}
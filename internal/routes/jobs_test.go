@@ -0,0 +1,49 @@
+package routes_test
+
+import (
+	"go_project_template/internal/pagination"
+	"go_project_template/internal/storage/database"
+	testhelpers "go_project_template/internal/test_helpers"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobsAdmin(t *testing.T) {
+	container := testhelpers.GetClean(t)
+	srv := testhelpers.NewTestServer(t, container)
+	jobs := container.Repo.Jobs()
+	delayed, err := jobs.Enqueue(container.Ctx, "report", map[string]int{"day": 1}, database.WithDelay(time.Hour))
+	require.NoError(t, err)
+	_, err = jobs.Enqueue(container.Ctx, "report", map[string]int{"day": 2})
+	require.NoError(t, err)
+
+	t.Run("401 without token", func(t *testing.T) {
+		srv.Get(t, "/admin/jobs").RequireUnauthorized(t)
+		srv.Post(t, "/admin/jobs/"+strconv.FormatInt(delayed, 10)+"/cancel", nil).RequireUnauthorized(t)
+	})
+	srv.AuthUser("admin")
+	t.Run("200 on list", func(t *testing.T) {
+		var page pagination.Page[database.Job]
+		srv.Get(t, "/admin/jobs?filter=type:eq:report&limit=1").RequireOk(t).RequireUnmarshal(t, &page)
+		require.Len(t, page.Items, 1)
+		require.Equal(t, database.JobPending, page.Items[0].Status)
+		require.NotEmpty(t, page.NextCursor)
+	})
+	t.Run("204 on cancel and retry", func(t *testing.T) {
+		path := "/admin/jobs/" + strconv.FormatInt(delayed, 10)
+		srv.Post(t, path+"/retry", nil).RequireConflict(t)
+		srv.Post(t, path+"/cancel", nil).RequireNoContent(t)
+		srv.Post(t, path+"/retry", nil).RequireNoContent(t)
+
+		job, err := jobs.Get(container.Ctx, delayed)
+		require.NoError(t, err)
+		require.Equal(t, database.JobPending, job.Status)
+	})
+	t.Run("404 on unknown job", func(t *testing.T) {
+		srv.Post(t, "/admin/jobs/100500/cancel", nil).RequireNotFound(t)
+		srv.Post(t, "/admin/jobs/abc/cancel", nil).RequireBadRequest(t)
+	})
+}
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"errors"
+	"go_project_template/internal/pagination"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// listQuery parses ?limit&cursor&sort&filter params of list endpoints, filter may be repeated.
+func listQuery(ctx *fiber.Ctx) (pagination.Query, error) {
+	rawFilters := ctx.Context().QueryArgs().PeekMulti("filter")
+	filters := make([]string, len(rawFilters))
+	for i, filter := range rawFilters {
+		filters[i] = string(filter)
+	}
+	q, err := pagination.ParseQuery(ctx.Query("limit"), ctx.Query("cursor"), ctx.Query("sort"), filters)
+	if err != nil {
+		return pagination.Query{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return q, nil
+}
+
+// listError responds with 400 to invalid list queries, other errors are logged and hidden from clients.
+func (s *Server) listError(name string, err error) error {
+	if errors.Is(err, pagination.ErrInvalidQuery) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	s.log.Error("unable to list "+name, err)
+	return fiber.ErrInternalServerError
+}
@@ -3,23 +3,61 @@ package sampler
 import (
 	"context"
 	"go_project_template/internal/logger"
+	"go_project_template/internal/pagination"
 	"go_project_template/internal/repository"
+	"go_project_template/internal/storage/database"
+	"time"
 )
 
+// jobsStopTimeout limits waiting for running jobs on Stop
+const jobsStopTimeout = 30 * time.Second
+
 type Service struct {
 	ctx  context.Context
 	log  logger.AppLogger
 	repo *repository.Repo
+	jobs *database.JobWorker
 }
 
 func InitService(ctx context.Context, log logger.AppLogger, repo *repository.Repo) *Service {
-	return &Service{
+	s := &Service{
 		ctx:  ctx,
 		repo: repo,
 		log:  log.With(logger.WithService("sampler")),
 	}
+	s.jobs = database.NewJobWorker(repo.Jobs()).WithOnError(func(err error) {
+		s.log.Error("background job failed", err)
+	})
+	// register job handlers here, e.g. database.HandleJobs(s.jobs, sendEmailJob, s.sendEmail)
+	s.jobs.Start(ctx)
+	return s
 }
 
 func (s *Service) Stop() {
 	s.log.Info("stopping service")
+	ctx, cancel := context.WithTimeout(context.Background(), jobsStopTimeout)
+	defer cancel()
+	if err := s.jobs.Stop(ctx); err != nil {
+		s.log.Error("unable to wait for running jobs", err)
+	}
+}
+
+// ListUsers returns page of users, see pagination.ParseQuery for supported params.
+func (s *Service) ListUsers(ctx context.Context, q pagination.Query) (pagination.Page[repository.User], error) {
+	return s.repo.FindUsers(ctx, q)
+}
+
+// ListJobs returns page of background jobs, see database.JobQueue.List.
+func (s *Service) ListJobs(ctx context.Context, q pagination.Query) (pagination.Page[database.Job], error) {
+	return s.repo.Jobs().List(ctx, q)
+}
+
+// RetryJob makes dead or canceled job pending again.
+func (s *Service) RetryJob(ctx context.Context, id int64) error {
+	return s.repo.Jobs().Retry(ctx, id)
+}
+
+// CancelJob stops pending job from running.
+func (s *Service) CancelJob(ctx context.Context, id int64) error {
+	return s.repo.Jobs().Cancel(ctx, id)
 }
@@ -8,7 +8,6 @@ import (
 	"go_project_template/internal/service/web3"
 	"go_project_template/internal/utils"
 	"math/big"
-	"net/http"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -52,11 +51,14 @@ type inchResponse struct {
 		Gas      uint64 `json:"gas"`
 		GasPrice string `json:"gasPrice"`
 	} `json:"tx"`
-	EstimatedGas int    `json:"estimatedGas"`
-	StatusCode   int    `json:"statusCode"`
-	Error        string `json:"error"`
-	Description  string `json:"description"`
-	Meta         []struct {
+	EstimatedGas int `json:"estimatedGas"`
+}
+
+type inchErrorResponse struct {
+	StatusCode  int    `json:"statusCode"`
+	Error       string `json:"error"`
+	Description string `json:"description"`
+	Meta        []struct {
 		Type  string `json:"type"`
 		Value string `json:"value"`
 	} `json:"meta"`
@@ -182,13 +184,13 @@ func (s *Service) getInchData(ctx context.Context, chain *big.Int, slippagePerce
 		slippagePercent,
 	)
 
-	resp, respCode, err := utils.GetCurl[inchResponse](ctx, url, nil)
+	resp, _, err := utils.GetCurl(ctx, url, nil, utils.WithTypedHTTPErrors[inchResponse, inchErrorResponse]())
 	if err != nil {
+		if inchErr, ok := utils.HTTPErrorBody[inchErrorResponse](err); ok {
+			return nil, fmt.Errorf("unable to get 1inch data: %s, %s: %w", inchErr.Error, inchErr.Description, err)
+		}
 		return nil, fmt.Errorf("unable to get 1inch data: %w", err)
 	}
-	if respCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d, %s, %s", respCode, resp.Error, resp.Description)
-	}
 	return resp, nil
 }
 
@@ -197,12 +199,12 @@ func (s *Service) getInchSpender(ctx context.Context, chainID *big.Int) (string,
 	type inchSpenderResponse struct {
 		Address string `json:"address"`
 	}
-	resp, respCode, err := utils.GetCurl[inchSpenderResponse](ctx, url, nil)
+	resp, _, err := utils.GetCurl(ctx, url, nil,
+		utils.WithHTTPErrors[inchSpenderResponse](),
+		utils.WithResponseCache[inchSpenderResponse](s.inchCache),
+	)
 	if err != nil {
 		return "", fmt.Errorf("unable to get 1inch data: %w", err)
 	}
-	if respCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code: %d", respCode)
-	}
 	return resp.Address, nil
 }
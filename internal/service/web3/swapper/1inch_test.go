@@ -16,6 +16,7 @@ func TestService_Swap1Inch(t *testing.T) {
 	appLog := logger.NewAppSLogger()
 	erc20Approver := approver.InitService(appLog)
 	service := swapper.NewService(appLog, erc20Approver)
+	defer service.Stop()
 	ethClient, privateKey, address := initTest(t)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 360*time.Second)
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"go_project_template/internal/logger"
 	"go_project_template/internal/service/web3/approver"
+	"go_project_template/internal/utils"
 	"math/big"
 	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
@@ -16,18 +18,25 @@ type Service struct {
 	approver        *approver.Service
 	log             logger.AppLogger
 	stargateAddress map[string]common.Address
+	inchCache       *utils.HTTPResponseCache
 }
 
 func NewService(appLog logger.AppLogger, erc20 *approver.Service) *Service {
 	return &Service{
-		log:      appLog,
-		approver: erc20,
+		log:       appLog,
+		approver:  erc20,
+		inchCache: utils.NewHTTPResponseCache("1inch", 24*time.Hour, time.Hour).WithDefaultTTL(time.Hour),
 		stargateAddress: map[string]common.Address{
 			"1": common.HexToAddress("0x150f94b44927f078737562f0fcf3c95c01cc2376"), // in eth mainnet
 		},
 	}
 }
 
+// Stop releases 1inch response cache and its cleanup goroutine.
+func (s *Service) Stop() {
+	s.inchCache.Close()
+}
+
 func (s *Service) suggestGas(ctx context.Context, web3Client *ethclient.Client, holder common.Address) (nonce uint64, gasPrice, gasTipCap *big.Int, err error) {
 	var wg sync.WaitGroup
 	var (
@@ -16,6 +16,7 @@ import (
 func TestService_TransferETH(t *testing.T) {
 	appLog := logger.NewAppSLogger()
 	service := swapper.NewService(appLog, nil)
+	defer service.Stop()
 	ethClient, privateKey, address := initTest(t)
 
 	approveTxHash, err := service.TransferETH(ethClient, privateKey, swapper.LZArbitrumChainId, address, "0.001", 0.5)
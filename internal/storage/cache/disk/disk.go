@@ -0,0 +1,736 @@
+package disk
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"go_project_template/internal/storage/cache/lru"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultSegmentSize     = 64 * 1024 * 1024
+	DefaultCompactionRatio = 0.5
+
+	segmentExt = ".seg"
+)
+
+var (
+	ErrItemTooLarge = errors.New("item is larger than l2 cache")
+	ErrClosed       = errors.New("l2 cache is closed")
+)
+
+var _ lru.Cacher[string, []byte] = (*Cache)(nil)
+
+type segment struct {
+	id   uint64
+	file *os.File
+	size int64 // bytes written
+	dead int64 // bytes taken by overwritten and removed records and by tombstones
+}
+
+// location points to the latest record of the key, locations form LRU list, head is the most recently used one.
+type location struct {
+	key         string
+	seg         *segment
+	offset      int64
+	size        int64
+	createdNano int64
+	expiryNano  int64 // 0 means item never expires
+	prev        *location
+	next        *location
+}
+
+func (l *location) expired(now int64) bool {
+	return l.expiryNano != 0 && now > l.expiryNano
+}
+
+// Cache is a size bounded L2 cache keeping items in append-only segment files.
+// Index of records is kept in memory and rebuilt from segments by Open, every record is protected by checksum,
+// damaged tail of a segment (e.g. after crash) is truncated. Removed items are marked with tombstones,
+// space of dead records is reclaimed by compaction, which rewrites live records into the active segment.
+// Size limit is applied to records on disk including headers, all operations are serialized by a single mutex.
+type Cache struct {
+	mu              sync.Mutex
+	dir             string
+	items           map[string]*location
+	head            *location
+	tail            *location
+	segments        []*segment // ordered from the oldest, the last one is active
+	liveSize        int64
+	maxSize         int64
+	segmentSize     int64
+	compactionRatio float64
+	defaultTTL      time.Duration
+	evictedItems    chan lru.EvictedItem[string, []byte]
+	closed          bool
+}
+
+// Open loads cache from dir or creates an empty one, maxSize is a limit of live records in bytes.
+func Open(dir string, maxSize int64) (*Cache, error) {
+	if maxSize <= 0 {
+		return nil, lru.ErrInvalidMaxSize
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create l2 cache dir: %w", err)
+	}
+	ids, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	c := &Cache{
+		dir:             dir,
+		items:           make(map[string]*location),
+		maxSize:         maxSize,
+		segmentSize:     DefaultSegmentSize,
+		compactionRatio: DefaultCompactionRatio,
+	}
+	for _, id := range ids {
+		if err := c.loadSegment(id); err != nil {
+			_ = c.closeFiles()
+			return nil, err
+		}
+	}
+	c.removeEmptySegments()
+	if err := c.rollSegment(); err != nil {
+		_ = c.closeFiles()
+		return nil, err
+	}
+	// items expired while cache was closed
+	now := time.Now().UnixNano()
+	for e := c.tail; e != nil; {
+		prev := e.prev
+		if e.expired(now) {
+			if err := c.remove(e, lru.EvictReasonExpired); err != nil {
+				_ = c.closeFiles()
+				return nil, err
+			}
+		}
+		e = prev
+	}
+	for c.liveSize > c.maxSize {
+		if err := c.evict(c.tail, lru.EvictReasonSize); err != nil {
+			_ = c.closeFiles()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// WithDefaultTTL sets TTL applied by Store, zero means items never expire.
+func (c *Cache) WithDefaultTTL(ttl time.Duration) *Cache {
+	c.mu.Lock()
+	c.defaultTTL = ttl
+	c.mu.Unlock()
+	return c
+}
+
+// WithSegmentSize sets size after which active segment is rolled, default is DefaultSegmentSize.
+func (c *Cache) WithSegmentSize(size int64) *Cache {
+	c.mu.Lock()
+	c.segmentSize = size
+	c.mu.Unlock()
+	return c
+}
+
+// WithCompactionRatio sets share of dead bytes on disk which triggers compaction, default is DefaultCompactionRatio.
+func (c *Cache) WithCompactionRatio(ratio float64) *Cache {
+	c.mu.Lock()
+	c.compactionRatio = ratio
+	c.mu.Unlock()
+	return c
+}
+
+// Close syncs and closes segment files, cache can't be used after it.
+func (c *Cache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	var errs []error
+	for _, seg := range c.segments {
+		if err := seg.file.Sync(); err != nil {
+			errs = append(errs, fmt.Errorf("sync l2 segment %d: %w", seg.id, err))
+		}
+	}
+	if err := c.closeFiles(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Cache) EvictListener() <-chan lru.EvictedItem[string, []byte] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.evictedItems == nil {
+		c.evictedItems = make(chan lru.EvictedItem[string, []byte], 1000)
+	}
+	return c.evictedItems
+}
+
+// Store stores data with default TTL, evicting the least recently used items if cache is full.
+func (c *Cache) Store(key string, value []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.store(key, value, c.expiryNano(c.defaultTTL))
+}
+
+// StoreWithTTL stores data which expires after ttl, zero ttl means no expiration.
+func (c *Cache) StoreWithTTL(key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.store(key, value, c.expiryNano(ttl))
+}
+
+// storeWithExpiry keeps absolute expiration of item moved from another tier.
+func (c *Cache) storeWithExpiry(key string, value []byte, expiryNano int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.store(key, value, expiryNano)
+}
+
+func (c *Cache) expiryNano(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	return time.Now().Add(ttl).UnixNano()
+}
+
+func (c *Cache) store(key string, value []byte, expiryNano int64) error {
+	if c.closed {
+		return ErrClosed
+	}
+	rec := record{key: key, value: value, createdNano: time.Now().UnixNano(), expiryNano: expiryNano}
+	if rec.size() > c.maxSize {
+		return ErrItemTooLarge
+	}
+	seg, offset, err := c.append(rec)
+	if err != nil {
+		return err
+	}
+	if old, ok := c.items[key]; ok {
+		c.unindex(old)
+	}
+	e := &location{
+		key:         key,
+		seg:         seg,
+		offset:      offset,
+		size:        rec.size(),
+		createdNano: rec.createdNano,
+		expiryNano:  expiryNano,
+	}
+	c.items[key] = e
+	c.pushFront(e)
+	c.liveSize += e.size
+	for c.tail != e && c.liveSize > c.maxSize {
+		if err := c.evict(c.tail, lru.EvictReasonSize); err != nil {
+			return err
+		}
+	}
+	return c.compact()
+}
+
+func (c *Cache) Get(key string) (value []byte, exist bool) {
+	value, _, exist = c.GetWithMeta(key)
+	return value, exist
+}
+
+// GetWithMeta reads item from disk. Expired and damaged items are removed and not returned.
+func (c *Cache) GetWithMeta(key string) (value []byte, meta lru.ItemMeta, exist bool) {
+	now := time.Now().UnixNano()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.lookup(key, now)
+	if !ok {
+		return nil, meta, false
+	}
+	rec, err := c.read(e)
+	if err != nil {
+		c.unindex(e)
+		return nil, meta, false
+	}
+	c.moveToFront(e)
+	meta.Age = time.Duration(now - e.createdNano)
+	if e.expiryNano != 0 {
+		meta.TTL = time.Duration(e.expiryNano - now)
+	}
+	return rec.value, meta, true
+}
+
+// take removes item without notifying EvictListener, it is used to move item to another tier.
+func (c *Cache) take(key string) (value []byte, createdNano, expiryNano int64, exist bool) {
+	now := time.Now().UnixNano()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.lookup(key, now)
+	if !ok {
+		return nil, 0, 0, false
+	}
+	rec, err := c.read(e)
+	if err != nil {
+		c.unindex(e)
+		return nil, 0, 0, false
+	}
+	if err := c.remove(e, lru.EvictReasonManual); err != nil {
+		return nil, 0, 0, false
+	}
+	return rec.value, e.createdNano, e.expiryNano, true
+}
+
+// discard removes item without notification, it is used when newer value is stored in another tier.
+func (c *Cache) discard(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	if e, ok := c.items[key]; ok {
+		_ = c.remove(e, lru.EvictReasonManual)
+	}
+}
+
+// lookup returns location of not expired item. Must be called under lock.
+func (c *Cache) lookup(key string, now int64) (*location, bool) {
+	if c.closed {
+		return nil, false
+	}
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	if e.expired(now) {
+		_ = c.evict(e, lru.EvictReasonExpired)
+		return nil, false
+	}
+	return e, true
+}
+
+// Delete removes item from cache, EvictListener is notified with EvictReasonManual.
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	if e, ok := c.items[key]; ok {
+		_ = c.evict(e, lru.EvictReasonManual)
+	}
+}
+
+// remove deletes item without notification. Must be called under lock.
+// Expired record hides older records of the key on load only until its segment is compacted,
+// so it needs tombstone as well unless nothing older than its segment exists.
+func (c *Cache) remove(e *location, reason lru.EvictReason) error {
+	if reason != lru.EvictReasonExpired || e.seg != c.segments[0] {
+		tombstone := record{tombstone: true, key: e.key}
+		seg, _, err := c.append(tombstone)
+		if err != nil {
+			return err
+		}
+		seg.dead += tombstone.size()
+	}
+	c.unindex(e)
+	return nil
+}
+
+// evict removes item and notifies EvictListener if it was requested. Must be called under lock.
+func (c *Cache) evict(e *location, reason lru.EvictReason) error {
+	var item lru.EvictedItem[string, []byte]
+	if c.evictedItems != nil {
+		item = lru.EvictedItem[string, []byte]{Key: e.key, Reason: reason}
+		if e.expiryNano != 0 {
+			item.ExpiresAt = time.Unix(0, e.expiryNano)
+		}
+		if rec, err := c.read(e); err == nil {
+			item.Value = rec.value
+		}
+	}
+	if err := c.remove(e, reason); err != nil {
+		return err
+	}
+	if c.evictedItems != nil {
+		c.evictedItems <- item
+	}
+	return nil
+}
+
+// RemoveExpired removes all expired items, returns number of removed items.
+func (c *Cache) RemoveExpired() int {
+	now := time.Now().UnixNano()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	removed := 0
+	for e := c.tail; e != nil && !c.closed; {
+		prev := e.prev
+		if e.expired(now) {
+			_ = c.evict(e, lru.EvictReasonExpired)
+			removed++
+		}
+		e = prev
+	}
+	return removed
+}
+
+// Purge removes all items and segment files.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	if c.evictedItems != nil {
+		for e := c.tail; e != nil; e = e.prev {
+			item := lru.EvictedItem[string, []byte]{Key: e.key, Reason: lru.EvictReasonPurge}
+			if rec, err := c.read(e); err == nil {
+				item.Value = rec.value
+			}
+			c.evictedItems <- item
+		}
+	}
+	for _, seg := range c.segments {
+		_ = seg.file.Close()
+		_ = os.Remove(seg.file.Name())
+	}
+	c.segments = nil
+	c.items = make(map[string]*location)
+	c.head, c.tail = nil, nil
+	c.liveSize = 0
+	if err := c.rollSegment(); err != nil {
+		// nothing can be written without active segment
+		c.closed = true
+	}
+}
+
+// Resize changes capacity in bytes evicting the least recently used items.
+func (c *Cache) Resize(size int64) error {
+	if size <= 0 {
+		return lru.ErrInvalidMaxSize
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	for c.tail != nil && c.liveSize > size {
+		if err := c.evict(c.tail, lru.EvictReasonSize); err != nil {
+			return err
+		}
+	}
+	c.maxSize = size
+	return c.compact()
+}
+
+func (c *Cache) GetMaxSize() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.maxSize
+}
+
+// Len returns number of items in cache.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+// Size returns size of live records on disk.
+func (c *Cache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.liveSize
+}
+
+// DiskSize returns size of all segment files including dead records.
+func (c *Cache) DiskSize() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	total := int64(0)
+	for _, seg := range c.segments {
+		total += seg.size
+	}
+	return total
+}
+
+func (c *Cache) read(e *location) (record, error) {
+	buf := make([]byte, e.size)
+	if _, err := e.seg.file.ReadAt(buf, e.offset); err != nil {
+		return record{}, fmt.Errorf("read l2 record: %w", err)
+	}
+	rec, err := decodeRecord(buf)
+	if err != nil {
+		return record{}, err
+	}
+	if rec.tombstone || rec.key != e.key {
+		return record{}, ErrCorrupted
+	}
+	return rec, nil
+}
+
+// append writes record to the active segment, rolling it when it is full.
+func (c *Cache) append(rec record) (*segment, int64, error) {
+	buf := encodeRecord(rec)
+	active := c.segments[len(c.segments)-1]
+	if active.size > 0 && active.size+int64(len(buf)) > c.segmentSize {
+		if err := c.rollSegment(); err != nil {
+			return nil, 0, err
+		}
+		active = c.segments[len(c.segments)-1]
+	}
+	offset := active.size
+	if _, err := active.file.WriteAt(buf, offset); err != nil {
+		// partially written record is overwritten by the next one
+		return nil, 0, fmt.Errorf("write l2 record: %w", err)
+	}
+	active.size += int64(len(buf))
+	return active, offset, nil
+}
+
+func (c *Cache) rollSegment() error {
+	id := uint64(1)
+	if len(c.segments) > 0 {
+		id = c.segments[len(c.segments)-1].id + 1
+	}
+	file, err := os.OpenFile(segmentPath(c.dir, id), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return fmt.Errorf("create l2 segment: %w", err)
+	}
+	c.segments = append(c.segments, &segment{id: id, file: file})
+	return nil
+}
+
+// loadSegment replays records of the segment into index, damaged tail is truncated.
+func (c *Cache) loadSegment(id uint64) error {
+	file, err := os.OpenFile(segmentPath(c.dir, id), os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("open l2 segment: %w", err)
+	}
+	seg := &segment{id: id, file: file}
+	c.segments = append(c.segments, seg)
+	r := bufio.NewReader(file)
+	for {
+		rec, size, err := readRecord(r)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			if err := file.Truncate(seg.size); err != nil {
+				return fmt.Errorf("truncate damaged l2 segment: %w", err)
+			}
+			return nil
+		}
+		c.replay(seg, rec, size)
+	}
+}
+
+func (c *Cache) replay(seg *segment, rec record, size int64) {
+	offset := seg.size
+	seg.size += size
+	if old, ok := c.items[rec.key]; ok {
+		c.unindex(old)
+	}
+	if rec.tombstone {
+		seg.dead += size
+		return
+	}
+	e := &location{
+		key:         rec.key,
+		seg:         seg,
+		offset:      offset,
+		size:        size,
+		createdNano: rec.createdNano,
+		expiryNano:  rec.expiryNano,
+	}
+	c.items[rec.key] = e
+	c.pushFront(e)
+	c.liveSize += size
+}
+
+// compact rewrites segments with the most dead bytes while their share on disk exceeds compactionRatio.
+// Every segment is compacted at most once per call, so carried tombstones can't make it loop.
+func (c *Cache) compact() error {
+	candidates := slices.Clone(c.segments[:len(c.segments)-1])
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].dead*candidates[j].size > candidates[j].dead*candidates[i].size
+	})
+	for _, seg := range candidates {
+		if seg.dead == 0 || !c.needsCompaction() {
+			return nil
+		}
+		if err := c.compactSegment(seg); err != nil {
+			return fmt.Errorf("compact l2 segment %d: %w", seg.id, err)
+		}
+	}
+	return nil
+}
+
+func (c *Cache) needsCompaction() bool {
+	total, dead := int64(0), int64(0)
+	for _, seg := range c.segments {
+		total += seg.size
+		dead += seg.dead
+	}
+	return total > 0 && float64(dead) >= c.compactionRatio*float64(total)
+}
+
+// compactSegment moves live records of seg to the active segment and removes its file.
+// Tombstone is dropped when the key was stored again or nothing older than seg exists, otherwise it is carried.
+func (c *Cache) compactSegment(seg *segment) error {
+	oldest := c.segments[0] == seg
+	now := time.Now().UnixNano()
+	r := bufio.NewReader(io.NewSectionReader(seg.file, 0, seg.size))
+	for offset := int64(0); offset < seg.size; {
+		rec, size, err := readRecord(r)
+		if err != nil {
+			// the rest of segment is unreadable, items pointing to it are lost
+			c.dropSegmentItems(seg)
+			break
+		}
+		if err := c.compactRecord(seg, offset, rec, oldest, now); err != nil {
+			return err
+		}
+		offset += size
+	}
+	_ = seg.file.Close()
+	if err := os.Remove(seg.file.Name()); err != nil {
+		return fmt.Errorf("remove l2 segment: %w", err)
+	}
+	c.segments = slices.DeleteFunc(c.segments, func(s *segment) bool { return s == seg })
+	return nil
+}
+
+func (c *Cache) compactRecord(seg *segment, offset int64, rec record, oldest bool, now int64) error {
+	e, indexed := c.items[rec.key]
+	if rec.tombstone {
+		if indexed || oldest {
+			return nil
+		}
+		dst, _, err := c.append(rec)
+		if err != nil {
+			return err
+		}
+		dst.dead += rec.size()
+		return nil
+	}
+	if !indexed || e.seg != seg || e.offset != offset {
+		return nil
+	}
+	if e.expired(now) {
+		return c.remove(e, lru.EvictReasonExpired)
+	}
+	dst, dstOffset, err := c.append(rec)
+	if err != nil {
+		return err
+	}
+	e.seg, e.offset = dst, dstOffset
+	return nil
+}
+
+// removeEmptySegments removes files of active segments left empty by previous runs.
+func (c *Cache) removeEmptySegments() {
+	c.segments = slices.DeleteFunc(c.segments, func(seg *segment) bool {
+		if seg.size > 0 {
+			return false
+		}
+		_ = seg.file.Close()
+		_ = os.Remove(seg.file.Name())
+		return true
+	})
+}
+
+func (c *Cache) dropSegmentItems(seg *segment) {
+	for e := c.tail; e != nil; {
+		prev := e.prev
+		if e.seg == seg {
+			c.unindex(e)
+		}
+		e = prev
+	}
+}
+
+// unindex forgets item, its record becomes dead. Must be called under lock.
+func (c *Cache) unindex(e *location) {
+	e.seg.dead += e.size
+	c.liveSize -= e.size
+	delete(c.items, e.key)
+	c.unlink(e)
+}
+
+func (c *Cache) closeFiles() error {
+	var errs []error
+	for _, seg := range c.segments {
+		if err := seg.file.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close l2 segment %d: %w", seg.id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Cache) pushFront(e *location) {
+	e.prev = nil
+	e.next = c.head
+	if c.head != nil {
+		c.head.prev = e
+	}
+	c.head = e
+	if c.tail == nil {
+		c.tail = e
+	}
+}
+
+func (c *Cache) unlink(e *location) {
+	if e.prev != nil {
+		e.prev.next = e.next
+	} else {
+		c.head = e.next
+	}
+	if e.next != nil {
+		e.next.prev = e.prev
+	} else {
+		c.tail = e.prev
+	}
+	e.prev, e.next = nil, nil
+}
+
+func (c *Cache) moveToFront(e *location) {
+	if c.head == e {
+		return
+	}
+	c.unlink(e)
+	c.pushFront(e)
+}
+
+func segmentPath(dir string, id uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d%s", id, segmentExt))
+}
+
+// listSegments returns ids of segment files in dir in order they were written.
+func listSegments(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read l2 cache dir: %w", err)
+	}
+	ids := make([]uint64, 0, len(entries))
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), segmentExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		id, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
@@ -0,0 +1,181 @@
+package disk_test
+
+import (
+	"fmt"
+	"go_project_template/internal/storage/cache/disk"
+	"go_project_template/internal/storage/cache/lru"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func openCache(t *testing.T, dir string, maxSize int64) *disk.Cache {
+	t.Helper()
+	cache, err := disk.Open(dir, maxSize)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = cache.Close() })
+	return cache
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*.seg"))
+	require.NoError(t, err)
+	return files
+}
+
+func TestCache(t *testing.T) {
+	t.Run("should keep items between restarts", func(t *testing.T) {
+		dir := t.TempDir()
+		cache := openCache(t, dir, 1024*1024)
+		require.NoError(t, cache.Store("a", []byte("first")))
+		require.NoError(t, cache.Store("b", []byte("second")))
+		require.NoError(t, cache.Store("a", []byte("third")))
+		cache.Delete("b")
+		require.NoError(t, cache.Close())
+
+		cache = openCache(t, dir, 1024*1024)
+		res, ok := cache.Get("a")
+		require.True(t, ok)
+		require.Equal(t, []byte("third"), res)
+		_, ok = cache.Get("b")
+		require.False(t, ok, "deleted item should not be restored")
+		require.Equal(t, 1, cache.Len())
+	})
+	t.Run("should evict least recently used items", func(t *testing.T) {
+		cache := openCache(t, t.TempDir(), 300)
+		evicted := cache.EvictListener()
+		value := make([]byte, 70) // 100 bytes with header and key
+		require.NoError(t, cache.Store("a", value))
+		require.NoError(t, cache.Store("b", value))
+		require.NoError(t, cache.Store("c", value))
+		_, ok := cache.Get("a")
+		require.True(t, ok)
+		require.NoError(t, cache.Store("d", value))
+
+		item := <-evicted
+		require.Equal(t, "b", item.Key)
+		require.Equal(t, lru.EvictReasonSize, item.Reason)
+		require.Equal(t, value, item.Value)
+		require.Equal(t, int64(300), cache.Size())
+		require.ErrorIs(t, cache.Store("big", make([]byte, 300)), disk.ErrItemTooLarge)
+	})
+	t.Run("should expire items", func(t *testing.T) {
+		dir := t.TempDir()
+		cache := openCache(t, dir, 1024)
+		require.NoError(t, cache.StoreWithTTL("short", []byte("1"), 20*time.Millisecond))
+		require.NoError(t, cache.StoreWithTTL("long", []byte("2"), time.Hour))
+		_, meta, ok := cache.GetWithMeta("long")
+		require.True(t, ok)
+		require.InDelta(t, time.Hour, meta.TTL, float64(time.Second))
+		require.NoError(t, cache.Close())
+
+		time.Sleep(30 * time.Millisecond)
+		cache = openCache(t, dir, 1024)
+		_, ok = cache.Get("short")
+		require.False(t, ok)
+		_, ok = cache.Get("long")
+		require.True(t, ok)
+	})
+	t.Run("should truncate damaged tail on open", func(t *testing.T) {
+		dir := t.TempDir()
+		cache := openCache(t, dir, 1024)
+		require.NoError(t, cache.Store("a", []byte("first")))
+		require.NoError(t, cache.Store("b", []byte("second")))
+		require.NoError(t, cache.Close())
+		files := segmentFiles(t, dir)
+		require.Len(t, files, 1)
+		info, err := os.Stat(files[0])
+		require.NoError(t, err)
+		// torn write of the last record
+		require.NoError(t, os.Truncate(files[0], info.Size()-3))
+
+		cache = openCache(t, dir, 1024)
+		_, ok := cache.Get("a")
+		require.True(t, ok)
+		_, ok = cache.Get("b")
+		require.False(t, ok)
+		require.NoError(t, cache.Store("c", []byte("third")))
+		res, ok := cache.Get("c")
+		require.True(t, ok)
+		require.Equal(t, []byte("third"), res)
+	})
+	t.Run("should detect corrupted record", func(t *testing.T) {
+		dir := t.TempDir()
+		cache := openCache(t, dir, 1024)
+		require.NoError(t, cache.Store("a", []byte("first")))
+		files := segmentFiles(t, dir)
+		file, err := os.OpenFile(files[len(files)-1], os.O_RDWR, 0)
+		require.NoError(t, err)
+		_, err = file.WriteAt([]byte("X"), 31) // inside value
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+
+		_, ok := cache.Get("a")
+		require.False(t, ok)
+		require.Zero(t, cache.Len())
+	})
+	t.Run("should compact dead records", func(t *testing.T) {
+		dir := t.TempDir()
+		cache := openCache(t, dir, 1024*1024)
+		cache.WithSegmentSize(1024)
+		for i := 0; i < 1000; i++ {
+			require.NoError(t, cache.Store(fmt.Sprintf("key_%d", i%10), []byte(fmt.Sprintf("value_%d", i))))
+			if i%3 == 0 {
+				cache.Delete(fmt.Sprintf("key_%d", (i+5)%10))
+			}
+		}
+		require.Less(t, cache.DiskSize(), int64(4*1024), "dead records should be reclaimed")
+		require.Less(t, len(segmentFiles(t, dir)), 6)
+		want := make(map[string][]byte)
+		for i := 0; i < 10; i++ {
+			key := fmt.Sprintf("key_%d", i)
+			if value, ok := cache.Get(key); ok {
+				want[key] = value
+			}
+		}
+		require.NotEmpty(t, want)
+		require.NoError(t, cache.Close())
+
+		cache = openCache(t, dir, 1024*1024)
+		require.Equal(t, len(want), cache.Len())
+		for key, value := range want {
+			res, ok := cache.Get(key)
+			require.True(t, ok, key)
+			require.Equal(t, value, res)
+		}
+	})
+	t.Run("should not restore overwritten item after expired one is compacted", func(t *testing.T) {
+		dir := t.TempDir()
+		cache := openCache(t, dir, 1024)
+		cache.WithSegmentSize(200).WithCompactionRatio(0.4)
+		// first segment keeps overwritten record of k alive on disk
+		require.NoError(t, cache.Store("k", []byte("old")))
+		require.NoError(t, cache.Store("filler", make([]byte, 130)))
+		require.NoError(t, cache.StoreWithTTL("k", []byte("new"), 20*time.Millisecond))
+		require.NoError(t, cache.Store("x", make([]byte, 130)))
+		cache.Delete("x")
+		time.Sleep(30 * time.Millisecond)
+		// compacts the second segment only
+		require.NoError(t, cache.Store("y", []byte("1")))
+		require.NoError(t, cache.Close())
+
+		cache = openCache(t, dir, 1024)
+		_, ok := cache.Get("k")
+		require.False(t, ok, "overwritten item should not be restored")
+		_, ok = cache.Get("filler")
+		require.True(t, ok)
+	})
+	t.Run("should purge files", func(t *testing.T) {
+		dir := t.TempDir()
+		cache := openCache(t, dir, 1024)
+		require.NoError(t, cache.Store("a", []byte("first")))
+		cache.Purge()
+		require.Zero(t, cache.Len())
+		require.Zero(t, cache.DiskSize())
+		require.Len(t, segmentFiles(t, dir), 1)
+	})
+}
@@ -0,0 +1,96 @@
+package disk
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// record layout: crc32(4) | flags(1) | key len(4) | value len(4) | created nano(8) | expiry nano(8) | key | value.
+// Checksum covers everything after itself.
+const (
+	recordHeaderSize = 29
+	maxRecordBody    = 1 << 31
+
+	flagTombstone = 1
+)
+
+var ErrCorrupted = errors.New("l2 record is corrupted")
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+type record struct {
+	tombstone   bool
+	key         string
+	value       []byte
+	createdNano int64
+	expiryNano  int64
+}
+
+func (r record) size() int64 {
+	return int64(recordHeaderSize + len(r.key) + len(r.value))
+}
+
+func encodeRecord(r record) []byte {
+	buf := make([]byte, r.size())
+	if r.tombstone {
+		buf[4] = flagTombstone
+	}
+	binary.LittleEndian.PutUint32(buf[5:], uint32(len(r.key)))   //nolint:gosec // bounded by maxRecordBody
+	binary.LittleEndian.PutUint32(buf[9:], uint32(len(r.value))) //nolint:gosec // bounded by maxRecordBody
+	binary.LittleEndian.PutUint64(buf[13:], uint64(r.createdNano))
+	binary.LittleEndian.PutUint64(buf[21:], uint64(r.expiryNano))
+	copy(buf[recordHeaderSize:], r.key)
+	copy(buf[recordHeaderSize+len(r.key):], r.value)
+	binary.LittleEndian.PutUint32(buf, crc32.Checksum(buf[4:], crcTable))
+	return buf
+}
+
+// decodeRecord parses whole record, value references buf.
+func decodeRecord(buf []byte) (record, error) {
+	if len(buf) < recordHeaderSize {
+		return record{}, ErrCorrupted
+	}
+	keyLen, valueLen := bodyLen(buf)
+	if int64(len(buf)) != recordHeaderSize+keyLen+valueLen {
+		return record{}, ErrCorrupted
+	}
+	if binary.LittleEndian.Uint32(buf) != crc32.Checksum(buf[4:], crcTable) {
+		return record{}, ErrCorrupted
+	}
+	return record{
+		tombstone:   buf[4]&flagTombstone != 0,
+		key:         string(buf[recordHeaderSize : recordHeaderSize+keyLen]),
+		value:       buf[recordHeaderSize+keyLen:],
+		createdNano: int64(binary.LittleEndian.Uint64(buf[13:])), //nolint:gosec // written from int64
+		expiryNano:  int64(binary.LittleEndian.Uint64(buf[21:])), //nolint:gosec // written from int64
+	}, nil
+}
+
+func bodyLen(header []byte) (keyLen, valueLen int64) {
+	return int64(binary.LittleEndian.Uint32(header[5:])), int64(binary.LittleEndian.Uint32(header[9:]))
+}
+
+// readRecord reads next record from r, returns io.EOF when r is exhausted exactly at record boundary.
+func readRecord(r io.Reader) (record, int64, error) {
+	header := make([]byte, recordHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if errors.Is(err, io.EOF) {
+			return record{}, 0, io.EOF
+		}
+		return record{}, 0, fmt.Errorf("%w: %w", ErrCorrupted, err)
+	}
+	keyLen, valueLen := bodyLen(header)
+	if keyLen+valueLen > maxRecordBody {
+		return record{}, 0, ErrCorrupted
+	}
+	buf := make([]byte, recordHeaderSize+keyLen+valueLen)
+	copy(buf, header)
+	if _, err := io.ReadFull(r, buf[recordHeaderSize:]); err != nil {
+		return record{}, 0, fmt.Errorf("%w: %w", ErrCorrupted, err)
+	}
+	rec, err := decodeRecord(buf)
+	return rec, int64(len(buf)), err
+}
@@ -0,0 +1,278 @@
+package disk
+
+import (
+	"errors"
+	"go_project_template/internal/storage/cache/lru"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+const keyLockStripes = 64
+
+var _ lru.Cacher[string, []byte] = (*TieredCache)(nil)
+
+// TieredCache keeps hot items in memory L1 and spills items evicted from it by size to disk L2.
+// Reading an item from L2 promotes it back to L1, so an item lives in one tier at a time.
+// Spills are fed asynchronously by L1 EvictListener, L1 must not be used directly while it is wrapped.
+// Item evicted from L1 is missing from both tiers until its spill is written.
+//
+// EvictListener reports items leaving both tiers: L1 evictions other than by size, L2 evictions
+// and items which could not be spilled.
+type TieredCache struct {
+	l1        lru.Cacher[string, []byte]
+	l2        *Cache
+	l1Evicted <-chan lru.EvictedItem[string, []byte]
+	l2Evicted <-chan lru.EvictedItem[string, []byte]
+
+	seed     maphash.Seed
+	keyLocks [keyLockStripes]sync.Mutex // serialize writes and promotion of the same key
+
+	// spillMu makes check of stale spills and L2 writes atomic, it is never held during L1 calls
+	spillMu sync.Mutex
+	// inL1 holds values written to L1, eviction of any other value of the key is outdated and not spilled
+	inL1 map[string][]byte
+
+	evictMu      sync.Mutex
+	evictedItems chan lru.EvictedItem[string, []byte]
+
+	wg        sync.WaitGroup
+	stopCh    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewTieredCache wraps l1 and l2 and starts goroutines spilling L1 evictions and forwarding L2 evictions,
+// call Close to stop them.
+func NewTieredCache(l1 lru.Cacher[string, []byte], l2 *Cache) *TieredCache {
+	c := &TieredCache{
+		l1:        l1,
+		l2:        l2,
+		l1Evicted: l1.EvictListener(),
+		l2Evicted: l2.EvictListener(),
+		seed:      maphash.MakeSeed(),
+		inL1:      make(map[string][]byte),
+		stopCh:    make(chan struct{}),
+	}
+	c.wg.Add(2)
+	go c.runSpills()
+	go c.runL2Evictions()
+	return c
+}
+
+// L2 returns disk tier, e.g. to resize it.
+func (c *TieredCache) L2() *Cache {
+	return c.l2
+}
+
+// Close stops spilling, moves items waiting in L1 listener to L2 and closes L2.
+// Evictions happening during Close are not reported.
+func (c *TieredCache) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.stopCh)
+		c.wg.Wait()
+		for {
+			select {
+			case item := <-c.l1Evicted:
+				c.spill(item)
+			case <-c.l2Evicted:
+			default:
+				err = c.l2.Close()
+				return
+			}
+		}
+	})
+	return err
+}
+
+func (c *TieredCache) runSpills() {
+	defer c.wg.Done()
+	for {
+		select {
+		case item := <-c.l1Evicted:
+			if !c.spill(item) {
+				c.notify(item)
+			}
+		case <-c.stopCh:
+			return
+		}
+	}
+}
+
+// runL2Evictions drains L2 listener separately, L2 is written under spillMu and must never wait for spills.
+func (c *TieredCache) runL2Evictions() {
+	defer c.wg.Done()
+	for {
+		select {
+		case item := <-c.l2Evicted:
+			c.notify(item)
+		case <-c.stopCh:
+			return
+		}
+	}
+}
+
+// spill moves item evicted from L1 by size to L2, returns false if item left the cache.
+func (c *TieredCache) spill(item lru.EvictedItem[string, []byte]) bool {
+	c.spillMu.Lock()
+	defer c.spillMu.Unlock()
+	current, ok := c.inL1[item.Key]
+	if !ok || !sameSlice(current, item.Value) {
+		// key was written again or deleted after eviction, nothing left the cache
+		return true
+	}
+	delete(c.inL1, item.Key)
+	if item.Reason != lru.EvictReasonSize {
+		return false
+	}
+	expiryNano := int64(0)
+	if !item.ExpiresAt.IsZero() {
+		expiryNano = item.ExpiresAt.UnixNano()
+		if expiryNano <= time.Now().UnixNano() {
+			return false
+		}
+	}
+	return c.l2.storeWithExpiry(item.Key, item.Value, expiryNano) == nil
+}
+
+// sameSlice reports if a and b are the same slice, not just equal content.
+func sameSlice(a, b []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	return len(a) == 0 || &a[0] == &b[0]
+}
+
+func (c *TieredCache) notify(item lru.EvictedItem[string, []byte]) {
+	c.evictMu.Lock()
+	ch := c.evictedItems
+	c.evictMu.Unlock()
+	if ch != nil {
+		ch <- item
+	}
+}
+
+func (c *TieredCache) EvictListener() <-chan lru.EvictedItem[string, []byte] {
+	c.evictMu.Lock()
+	defer c.evictMu.Unlock()
+	if c.evictedItems == nil {
+		c.evictedItems = make(chan lru.EvictedItem[string, []byte], 1000)
+	}
+	return c.evictedItems
+}
+
+// Store stores item in L1 with its default TTL, item which doesn't fit L1 goes to L2 with L2 default TTL.
+func (c *TieredCache) Store(key string, value []byte) error {
+	return c.store(key, value, func() error {
+		return c.l1.Store(key, value)
+	}, func() error {
+		return c.l2.Store(key, value)
+	})
+}
+
+func (c *TieredCache) StoreWithTTL(key string, value []byte, ttl time.Duration) error {
+	return c.store(key, value, func() error {
+		return c.l1.StoreWithTTL(key, value, ttl)
+	}, func() error {
+		return c.l2.StoreWithTTL(key, value, ttl)
+	})
+}
+
+func (c *TieredCache) store(key string, value []byte, toL1, toL2 func() error) error {
+	lock := c.keyLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// new value is registered before it gets to L1, so its eviction can't outrun it
+	c.spillMu.Lock()
+	c.inL1[key] = value
+	c.l2.discard(key)
+	c.spillMu.Unlock()
+
+	err := toL1()
+	if err == nil {
+		return nil
+	}
+	c.spillMu.Lock()
+	defer c.spillMu.Unlock()
+	delete(c.inL1, key)
+	if errors.Is(err, lru.ErrL1CacheIsFull) {
+		return toL2()
+	}
+	return err
+}
+
+func (c *TieredCache) Get(key string) (value []byte, exist bool) {
+	value, _, exist = c.GetWithMeta(key)
+	return value, exist
+}
+
+// GetWithMeta looks item up in L1, then in L2 promoting found item to L1.
+// Age of promoted item is counted from the moment it was spilled.
+func (c *TieredCache) GetWithMeta(key string) (value []byte, meta lru.ItemMeta, exist bool) {
+	if value, meta, exist = c.l1.GetWithMeta(key); exist {
+		return value, meta, true
+	}
+	lock := c.keyLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+	// item could be promoted while we were waiting for the lock
+	if value, meta, exist = c.l1.GetWithMeta(key); exist {
+		return value, meta, true
+	}
+	c.spillMu.Lock()
+	value, createdNano, expiryNano, exist := c.l2.take(key)
+	if exist {
+		c.inL1[key] = value
+	}
+	c.spillMu.Unlock()
+	if !exist {
+		return nil, meta, false
+	}
+	meta.Age = time.Since(time.Unix(0, createdNano))
+	ttl := time.Duration(0)
+	if expiryNano != 0 {
+		ttl = time.Until(time.Unix(0, expiryNano))
+		meta.TTL = ttl
+	}
+	if err := c.l1.StoreWithTTL(key, value, ttl); err != nil {
+		// item doesn't fit L1, keep it on disk
+		c.spillMu.Lock()
+		delete(c.inL1, key)
+		_ = c.l2.storeWithExpiry(key, value, expiryNano)
+		c.spillMu.Unlock()
+	}
+	return value, meta, true
+}
+
+func (c *TieredCache) Delete(key string) {
+	lock := c.keyLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+	c.spillMu.Lock()
+	delete(c.inL1, key)
+	c.l2.Delete(key)
+	c.spillMu.Unlock()
+	c.l1.Delete(key)
+}
+
+func (c *TieredCache) Purge() {
+	c.spillMu.Lock()
+	clear(c.inL1)
+	c.l2.Purge()
+	c.spillMu.Unlock()
+	c.l1.Purge()
+}
+
+func (c *TieredCache) Resize(size int64) error {
+	return c.l1.Resize(size)
+}
+
+// GetMaxSize returns capacity of L1.
+func (c *TieredCache) GetMaxSize() int64 {
+	return c.l1.GetMaxSize()
+}
+
+func (c *TieredCache) keyLock(key string) *sync.Mutex {
+	return &c.keyLocks[maphash.String(c.seed, key)%keyLockStripes]
+}
@@ -0,0 +1,151 @@
+package disk_test
+
+import (
+	"fmt"
+	"go_project_template/internal/storage/cache/disk"
+	"go_project_template/internal/storage/cache/lru"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTiered(t *testing.T, dir string, l1Items int64) *disk.TieredCache {
+	t.Helper()
+	l1, err := lru.New[string, []byte](l1Items*10+1, lru.BytesSizer) // l1 keeps l1Items of 10 bytes
+	require.NoError(t, err)
+	l2, err := disk.Open(dir, 1024*1024)
+	require.NoError(t, err)
+	cache := disk.NewTieredCache(l1, l2)
+	t.Cleanup(func() { _ = cache.Close() })
+	return cache
+}
+
+func value(i int) []byte {
+	return []byte(fmt.Sprintf("value_%04d", i)) // 10 bytes
+}
+
+func TestTieredCache(t *testing.T) {
+	t.Run("should spill to l2 and promote back", func(t *testing.T) {
+		cache := newTiered(t, t.TempDir(), 2)
+		for i := 0; i < 10; i++ {
+			require.NoError(t, cache.StoreWithTTL(strconv.Itoa(i), value(i), time.Hour))
+		}
+		require.Eventually(t, func() bool {
+			return cache.L2().Len() == 8
+		}, time.Second, time.Millisecond)
+
+		res, meta, ok := cache.GetWithMeta("0")
+		require.True(t, ok)
+		require.Equal(t, value(0), res)
+		require.InDelta(t, time.Hour, meta.TTL, float64(time.Second))
+		require.Eventually(t, func() bool {
+			// promoted item left l2 and evicted one took its place
+			return cache.L2().Len() == 8
+		}, time.Second, time.Millisecond)
+		for i := 0; i < 10; i++ {
+			// item evicted by previous promotion may be on its way to l2
+			require.Eventually(t, func() bool {
+				res, ok := cache.Get(strconv.Itoa(i))
+				return ok && string(res) == string(value(i))
+			}, time.Second, time.Millisecond, i)
+		}
+	})
+	t.Run("should not resurrect deleted and overwritten items", func(t *testing.T) {
+		dir := t.TempDir()
+		cache := newTiered(t, dir, 2)
+		for i := 0; i < 10; i++ {
+			require.NoError(t, cache.Store(strconv.Itoa(i), value(i)))
+		}
+		cache.Delete("0")
+		require.NoError(t, cache.Store("1", value(100)))
+		for i := 10; i < 20; i++ {
+			require.NoError(t, cache.Store(strconv.Itoa(i), value(i)))
+		}
+		require.NoError(t, cache.Close())
+
+		cache = newTiered(t, dir, 2)
+		_, ok := cache.Get("0")
+		require.False(t, ok)
+		res, ok := cache.Get("1")
+		require.True(t, ok)
+		require.Equal(t, value(100), res)
+	})
+	t.Run("should report items leaving both tiers", func(t *testing.T) {
+		cache := newTiered(t, t.TempDir(), 2)
+		evicted := cache.EvictListener()
+		require.NoError(t, cache.StoreWithTTL("short", value(1), 10*time.Millisecond))
+		time.Sleep(20 * time.Millisecond)
+		_, ok := cache.Get("short")
+		require.False(t, ok)
+		item := <-evicted
+		require.Equal(t, "short", item.Key)
+		require.Equal(t, lru.EvictReasonExpired, item.Reason)
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, cache.Store(strconv.Itoa(i), value(i)))
+		}
+		require.Eventually(t, func() bool {
+			_, ok := cache.L2().Get("0")
+			return ok
+		}, time.Second, time.Millisecond)
+		cache.Delete("0")
+		item = <-evicted
+		require.Equal(t, "0", item.Key)
+		require.Equal(t, lru.EvictReasonManual, item.Reason)
+		require.Equal(t, value(0), item.Value)
+	})
+	t.Run("should keep tiers consistent under concurrent access", func(t *testing.T) {
+		cache := newTiered(t, t.TempDir(), 16)
+		var mu sync.Mutex
+		latest := make(map[string][]byte)
+		var wg sync.WaitGroup
+		for w := 0; w < 8; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < 500; i++ {
+					// every worker owns its keys, so the latest value is known
+					key := fmt.Sprintf("%d_%d", w, rand.IntN(20)) //nolint:gosec // test data
+					switch i % 4 {
+					case 0:
+						cache.Delete(key)
+						mu.Lock()
+						delete(latest, key)
+						mu.Unlock()
+					case 1, 2:
+						v := value(i)
+						require.NoError(t, cache.Store(key, v))
+						mu.Lock()
+						latest[key] = v
+						mu.Unlock()
+					default:
+						res, ok := cache.Get(key)
+						if !ok {
+							// item may be on its way to l2, miss is fine but stale value is not
+							continue
+						}
+						mu.Lock()
+						want := latest[key]
+						mu.Unlock()
+						require.Equal(t, want, res, key)
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+		for w := 0; w < 8; w++ {
+			for k := 0; k < 20; k++ {
+				key := fmt.Sprintf("%d_%d", w, k)
+				want, exist := latest[key]
+				require.Eventually(t, func() bool {
+					res, ok := cache.Get(key)
+					return ok == exist && string(res) == string(want)
+				}, time.Second, time.Millisecond, key)
+			}
+		}
+	})
+}
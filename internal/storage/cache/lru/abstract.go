@@ -1,15 +1,55 @@
 package lru
 
-type EvictedItem struct {
-	Key   string
-	Value []byte
+import "time"
+
+type EvictReason int
+
+const (
+	EvictReasonSize    EvictReason = iota // item was evicted to free space for other items or after Resize
+	EvictReasonExpired                    // item TTL passed
+	EvictReasonManual                     // item was removed with Delete
+	EvictReasonPurge                      // cache was purged
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case EvictReasonSize:
+		return "size"
+	case EvictReasonExpired:
+		return "expired"
+	case EvictReasonManual:
+		return "manual"
+	case EvictReasonPurge:
+		return "purge"
+	default:
+		return "unknown"
+	}
+}
+
+type EvictedItem[K comparable, V any] struct {
+	Key       K
+	Value     V
+	Reason    EvictReason
+	ExpiresAt time.Time // zero if item never expires
+}
+
+// ItemMeta describes cached item, TTL is zero for items which never expire.
+type ItemMeta struct {
+	Age time.Duration
+	TTL time.Duration
 }
 
-type Cacher interface {
-	Store(key string, value []byte) error
-	Get(key string) (value []byte, exist bool)
-	EvictListener() <-chan EvictedItem
+type Cacher[K comparable, V any] interface {
+	Store(key K, value V) error
+	// StoreWithTTL stores item which expires after ttl, zero ttl means no expiration.
+	StoreWithTTL(key K, value V, ttl time.Duration) error
+	Get(key K) (value V, exist bool)
+	GetWithMeta(key K) (value V, meta ItemMeta, exist bool)
+	Delete(key K)
+	EvictListener() <-chan EvictedItem[K, V]
 	Purge()
-	Resize(sizeMB int64) error
-	GetMaxSizeBytes() int64
+	// Resize changes capacity, size is measured in units cache was created with: MB for CreateL1Cache, sizer units for New.
+	Resize(size int64) error
+	// GetMaxSize returns capacity in sizer units, e.g. bytes for BytesSizer.
+	GetMaxSize() int64
 }
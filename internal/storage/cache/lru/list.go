@@ -0,0 +1,55 @@
+package lru
+
+// listID identifies list holding an entry. Plain LRU keeps all entries in listMain,
+// with admission policy listMain is the probation segment of the main area.
+type listID uint8
+
+const (
+	listMain listID = iota
+	listWindow
+	listProtected
+	listsCount
+)
+
+// entryList is an intrusive doubly linked list, head is the most recently used entry.
+type entryList[K comparable, V any] struct {
+	head *entry[K, V]
+	tail *entry[K, V]
+	size int64 // sum of weights of entries
+}
+
+func (l *entryList[K, V]) pushFront(e *entry[K, V]) {
+	e.prev = nil
+	e.next = l.head
+	if l.head != nil {
+		l.head.prev = e
+	}
+	l.head = e
+	if l.tail == nil {
+		l.tail = e
+	}
+	l.size += e.size
+}
+
+func (l *entryList[K, V]) unlink(e *entry[K, V]) {
+	if e.prev != nil {
+		e.prev.next = e.next
+	} else {
+		l.head = e.next
+	}
+	if e.next != nil {
+		e.next.prev = e.prev
+	} else {
+		l.tail = e.prev
+	}
+	e.prev, e.next = nil, nil
+	l.size -= e.size
+}
+
+func (l *entryList[K, V]) moveToFront(e *entry[K, V]) {
+	if l.head == e {
+		return
+	}
+	l.unlink(e)
+	l.pushFront(e)
+}
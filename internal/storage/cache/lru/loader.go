@@ -0,0 +1,34 @@
+package lru
+
+import (
+	"context"
+	appUtils "go_project_template/internal/utils"
+)
+
+// WithLoader configures GetOrLoad, call it before the cache is used.
+func (s *Cache[K, V]) WithLoader(conf appUtils.LoaderConf) *Cache[K, V] {
+	s.mu.Lock()
+	s.loader = appUtils.NewLoader(conf, s.storeLoaded, s.counters.ObserveLoad)
+	s.mu.Unlock()
+	return s
+}
+
+// GetOrLoad returns value of key, missing value is loaded with loader and stored with default TTL.
+// Concurrent calls for the same key share one loader call, its error is returned as is
+// and cached for LoaderConf.ErrorTTL. Item in the last LoaderConf.StaleWhileRevalidate
+// of its TTL is returned and reloaded in background.
+func (s *Cache[K, V]) GetOrLoad(ctx context.Context, key K, loader appUtils.LoadFunc[K, V]) (V, error) {
+	s.mu.Lock()
+	l := s.loader
+	s.mu.Unlock()
+	if value, meta, ok := s.GetWithMeta(key); ok {
+		l.Revalidate(ctx, key, meta.TTL, loader)
+		return value, nil
+	}
+	return l.Load(ctx, key, loader)
+}
+
+// storeLoaded stores loaded value, value which doesn't fit the cache is still returned to callers.
+func (s *Cache[K, V]) storeLoaded(key K, value V) {
+	_ = s.Store(key, value)
+}
@@ -0,0 +1,88 @@
+package lru_test
+
+import (
+	"context"
+	"errors"
+	"go_project_template/internal/storage/cache/lru"
+	"go_project_template/internal/utils"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_GetOrLoad(t *testing.T) {
+	t.Run("should load missing key once", func(t *testing.T) {
+		cache, err := lru.NewSharded[string, []byte](1024, 2, lru.BytesSizer)
+		require.NoError(t, err)
+		var calls atomic.Int32
+		loader := func(_ context.Context, key string) ([]byte, error) {
+			calls.Add(1)
+			time.Sleep(20 * time.Millisecond)
+			return []byte(key), nil
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				value, err := cache.GetOrLoad(context.Background(), "key", loader)
+				require.NoError(t, err)
+				require.Equal(t, []byte("key"), value)
+			}()
+		}
+		wg.Wait()
+
+		require.Equal(t, int32(1), calls.Load())
+		require.Equal(t, uint64(1), cache.Stats().Loads)
+		value, ok := cache.Get("key")
+		require.True(t, ok)
+		require.Equal(t, []byte("key"), value)
+	})
+	t.Run("should return loaded value which doesn't fit", func(t *testing.T) {
+		cache, err := lru.New[string, []byte](4, lru.BytesSizer)
+		require.NoError(t, err)
+		value, err := cache.GetOrLoad(context.Background(), "key", func(context.Context, string) ([]byte, error) {
+			return make([]byte, 10), nil
+		})
+		require.NoError(t, err)
+		require.Len(t, value, 10)
+		require.Equal(t, 0, cache.Len())
+	})
+	t.Run("should cache loader error", func(t *testing.T) {
+		cache, err := lru.New[string, int](10, lru.CountSizer[int])
+		require.NoError(t, err)
+		cache.WithLoader(utils.LoaderConf{ErrorTTL: time.Minute})
+		errLoad := errors.New("not found")
+		var calls atomic.Int32
+		loader := func(context.Context, string) (int, error) {
+			calls.Add(1)
+			return 0, errLoad
+		}
+		for i := 0; i < 3; i++ {
+			_, err := cache.GetOrLoad(context.Background(), "key", loader)
+			require.ErrorIs(t, err, errLoad)
+		}
+		require.Equal(t, int32(1), calls.Load())
+		require.Equal(t, uint64(1), cache.Stats().LoadErrors)
+	})
+	t.Run("should revalidate stale item", func(t *testing.T) {
+		cache, err := lru.New[string, int](10, lru.CountSizer[int])
+		require.NoError(t, err)
+		cache.WithDefaultTTL(time.Minute).WithLoader(utils.LoaderConf{StaleWhileRevalidate: 30 * time.Second})
+		require.NoError(t, cache.StoreWithTTL("key", 1, 10*time.Second))
+
+		value, err := cache.GetOrLoad(context.Background(), "key", func(context.Context, string) (int, error) {
+			return 2, nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, value)
+		require.Eventually(t, func() bool {
+			value, meta, ok := cache.GetWithMeta("key")
+			return ok && value == 2 && meta.TTL > 30*time.Second
+		}, time.Second, 10*time.Millisecond)
+	})
+}
@@ -1,11 +1,12 @@
 package lru
 
 import (
+	"context"
 	"errors"
-	"fmt"
 	"go_project_template/internal/storage/cache/lru/utils"
-
-	lru "github.com/hashicorp/golang-lru"
+	appUtils "go_project_template/internal/utils"
+	"sync"
+	"time"
 )
 
 const (
@@ -13,134 +14,348 @@ const (
 )
 
 var (
-	ErrL1CacheIsFull = errors.New("l1 cache is full")
+	ErrL1CacheIsFull  = errors.New("l1 cache is full")
+	ErrInvalidMaxSize = errors.New("max size should be positive")
+	ErrNilSizer       = errors.New("sizer is required")
 )
 
-type Cache struct {
-	lruCache       *lru.Cache        // LRU cache only satisfies for cdn
-	sizerL1        *utils.CacheSizer // LRU library does not track size of items, so we need to track it manually
-	evictedItems   chan EvictedItem
-	maxSizeInBytes int64
+var _ Cacher[string, []byte] = (*Cache[string, []byte])(nil)
+
+// entry is an element of intrusive doubly linked list.
+type entry[K comparable, V any] struct {
+	key         K
+	value       V
+	size        int64
+	createdNano int64
+	expiryNano  int64 // 0 means item never expires
+	list        listID
+	prev        *entry[K, V]
+	next        *entry[K, V]
+}
+
+func (e *entry[K, V]) expired(now int64) bool {
+	return e.expiryNano != 0 && now > e.expiryNano
+}
+
+type Cache[K comparable, V any] struct {
+	mu           sync.Mutex
+	items        map[K]*entry[K, V]
+	lists        [listsCount]entryList[K, V]
+	admission    *tinyLFU // nil for plain LRU
+	sizer        Sizer[V]
+	sizerL1      *utils.CacheSizer // tracks sum of item weights and number of items
+	evictedItems chan EvictedItem[K, V]
+	maxSize      int64
+	resizeUnit   int64 // multiplier applied to Resize argument
+	defaultTTL   time.Duration
+	snapshot     *appUtils.SnapshotConf[K, V]
+	counters     appUtils.CacheCounters
+	loader       *appUtils.Loader[K, V]
+
+	wg        sync.WaitGroup
+	stopCh    chan struct{}
+	closeOnce sync.Once
+}
+
+// New creates LRU cache limited by sum of sizer weights of its items.
+func New[K comparable, V any](maxSize int64, sizer Sizer[V]) (*Cache[K, V], error) {
+	return newCache[K, V](maxSize, 1, sizer)
 }
 
-func CreateL1Cache(lruMaxSizeMB int64) (*Cache, error) {
+func CreateL1Cache(lruMaxSizeMB int64) (*Cache[string, []byte], error) {
 	// Get MaxSize in MB and convert it to bytes.
-	// This is max size of L1 cache in items (as we suggest, that each item from 1 byte to 100MB)
-	// If simply memory divine to 100MB - that it will not be efficient, because we can have a lot of small items.
-	// So max size of L1 cache in items is 100MB * MaxSize.
 	// l1BytesSize is used to track size of items in L1 cache and prevent memory overflow.
-	maxSizeInBytes := lruMaxSizeMB * bytesInMB
-	l1 := &Cache{
-		sizerL1:        utils.CreateCacheSizer(maxSizeInBytes),
-		maxSizeInBytes: maxSizeInBytes,
+	return newCache[string, []byte](lruMaxSizeMB*bytesInMB, bytesInMB, BytesSizer)
+}
+
+func newCache[K comparable, V any](maxSize, resizeUnit int64, sizer Sizer[V]) (*Cache[K, V], error) {
+	if maxSize <= 0 {
+		return nil, ErrInvalidMaxSize
+	}
+	if sizer == nil {
+		return nil, ErrNilSizer
 	}
-	lruCache, err := lru.NewWithEvict(int(maxSizeInBytes), l1.onEvict)
-	if err != nil {
-		return nil, fmt.Errorf("error while creating LRU cache: %w", err)
+	s := &Cache[K, V]{
+		items:      make(map[K]*entry[K, V]),
+		sizer:      sizer,
+		sizerL1:    utils.CreateCacheSizer(maxSize),
+		maxSize:    maxSize,
+		resizeUnit: resizeUnit,
+		stopCh:     make(chan struct{}),
 	}
-	l1.lruCache = lruCache
-	return l1, nil
+	s.loader = appUtils.NewLoader(appUtils.LoaderConf{}, s.storeLoaded, s.counters.ObserveLoad)
+	return s, nil
 }
 
-func (s *Cache) GetMaxSizeBytes() int64 {
-	return s.maxSizeInBytes
+// WithDefaultTTL sets TTL applied by Store, zero means items never expire.
+func (s *Cache[K, V]) WithDefaultTTL(ttl time.Duration) *Cache[K, V] {
+	s.mu.Lock()
+	s.defaultTTL = ttl
+	s.mu.Unlock()
+	return s
 }
 
-func (s *Cache) EvictListener() <-chan EvictedItem {
+// WithSweeper starts background goroutine removing expired items every interval.
+// Expired items are removed on access anyway, sweeper frees space taken by items nobody asks for.
+// It stops when ctx is done or Close is called.
+func (s *Cache[K, V]) WithSweeper(ctx context.Context, interval time.Duration) *Cache[K, V] {
+	ticker := time.NewTicker(interval)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.RemoveExpired()
+			case <-s.stopCh:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return s
+}
+
+// Close stops the sweeper and waits for it to exit, safe to call multiple times.
+func (s *Cache[K, V]) Close() {
+	s.closeOnce.Do(func() {
+		close(s.stopCh)
+		s.wg.Wait()
+	})
+}
+
+// RemoveExpired removes all expired items, returns number of removed items.
+func (s *Cache[K, V]) RemoveExpired() int {
+	now := time.Now().UnixNano()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for id := range s.lists {
+		for e := s.lists[id].tail; e != nil; {
+			prev := e.prev
+			if e.expired(now) {
+				s.evict(e, EvictReasonExpired)
+				removed++
+			}
+			e = prev
+		}
+	}
+	return removed
+}
+
+func (s *Cache[K, V]) GetMaxSize() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.maxSize
+}
+
+func (s *Cache[K, V]) EvictListener() <-chan EvictedItem[K, V] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.evictedItems == nil {
-		s.evictedItems = make(chan EvictedItem, 1000)
+		s.evictedItems = make(chan EvictedItem[K, V], 1000)
 	}
 	return s.evictedItems
 }
 
-// Store stores data in L1 cache. If L1 cache is full, returns ErrL1CacheIsFull.
-func (s *Cache) Store(key string, value []byte) error {
+// Store stores data in L1 cache with default TTL. If L1 cache is full, returns ErrL1CacheIsFull.
+func (s *Cache[K, V]) Store(key K, value V) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.store(key, value, s.defaultTTL)
+}
+
+// StoreWithTTL stores data in L1 cache, item expires after ttl, zero ttl means no expiration.
+func (s *Cache[K, V]) StoreWithTTL(key K, value V, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.store(key, value, ttl)
+}
+
+func (s *Cache[K, V]) store(key K, value V, ttl time.Duration) error {
+	size := s.sizer(value)
+	now := time.Now().UnixNano()
+	expiryNano := int64(0)
+	if ttl > 0 {
+		expiryNano = now + ttl.Nanoseconds()
+	}
+	s.recordAccess(key)
+
+	if e, ok := s.items[key]; ok {
+		if size > s.maxSize {
+			// evictToFit never evicts e itself, so cache would stay over budget
+			return ErrL1CacheIsFull
+		}
+		// replace value in place, only the weight difference matters
+		s.sizerL1.Remove(int(e.size))
+		s.sizerL1.Add(int(size))
+		s.lists[e.list].size += size - e.size
+		e.value = value
+		e.size = size
+		e.createdNano = now
+		e.expiryNano = expiryNano
+		s.lists[e.list].moveToFront(e)
+		s.evictToFit(e)
+		if s.admission != nil {
+			s.drainWindow()
+		}
+		return nil
+	}
+	if s.admission != nil {
+		return s.storeAdmitted(key, value, size, now, expiryNano)
+	}
 	// check that storage is less than maximum size
-	if s.sizerL1.CanAddWithoutEvicting(len(value)) {
+	if s.sizerL1.CanAddWithoutEvicting(int(size)) {
 		// we are lucky, just add it to object storage
-		s.lruCache.Add(key, value)
-		s.sizerL1.Add(len(value))
+		s.add(key, value, size, now, expiryNano)
 		return nil
 	}
 	// cache will evict some items
-	_, valueOld, ok := s.lruCache.GetOldest()
-	if !ok {
+	oldest := s.lists[listMain].tail
+	if oldest == nil {
 		// there are no items to evict, so adding this item will increase size of storage
 		return ErrL1CacheIsFull
 	}
-	valueOldBytes, ok := valueOld.([]byte)
-	if !ok {
-		// unknown type of value, skip it
-		return ErrL1CacheIsFull
-	}
-	if len(valueOldBytes) < len(value) {
+	if oldest.size < size {
 		// new item is bigger than old item, so we can't replace it
 		return ErrL1CacheIsFull
 	}
 	// we can replace old item with new item
-	s.lruCache.RemoveOldest() // as we manually track size of items, we remove onEvict
-	s.lruCache.Add(key, value)
-	s.sizerL1.Add(len(value))
+	reason := EvictReasonSize
+	if oldest.expired(now) {
+		reason = EvictReasonExpired
+	}
+	s.evict(oldest, reason)
+	s.add(key, value, size, now, expiryNano)
 	return nil
 }
 
 // Purge removes all items from L1 cache and reset bytes counter.
-func (s *Cache) Purge() {
-	s.lruCache.Purge()
+func (s *Cache[K, V]) Purge() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id := range s.lists {
+		for s.lists[id].tail != nil {
+			s.evict(s.lists[id].tail, EvictReasonPurge)
+		}
+	}
 	s.sizerL1.Purge()
 }
 
-func (s *Cache) Get(key string) (value []byte, exist bool) {
-	if data, ok := s.lruCache.Get(key); ok {
-		if value, ok = data.([]byte); ok {
-			return value, true
-		}
+// Delete removes item from cache, EvictListener is notified with EvictReasonManual.
+func (s *Cache[K, V]) Delete(key K) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.items[key]; ok {
+		s.evict(e, EvictReasonManual)
 	}
-	return nil, false
 }
 
-func (s *Cache) onEvict(key, value interface{}) {
-	valueBytes, ok := value.([]byte)
+func (s *Cache[K, V]) Get(key K) (value V, exist bool) {
+	value, _, exist = s.GetWithMeta(key)
+	return value, exist
+}
+
+// GetWithMeta returns value with its age and remaining TTL. Expired item is removed and not returned.
+func (s *Cache[K, V]) GetWithMeta(key K) (value V, meta ItemMeta, exist bool) {
+	now := time.Now().UnixNano()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recordAccess(key)
+	e, ok := s.items[key]
 	if !ok {
-		// unknown type of value, skip it
-		return
+		s.counters.Miss()
+		return value, meta, false
 	}
-	s.sizerL1.Remove(len(valueBytes))
-	if s.evictedItems != nil {
-		s.evictedItems <- EvictedItem{Key: key.(string), Value: valueBytes}
+	if e.expired(now) {
+		s.counters.Miss()
+		s.evict(e, EvictReasonExpired)
+		return value, meta, false
+	}
+	s.counters.Hit()
+	s.promote(e)
+	meta.Age = time.Duration(now - e.createdNano)
+	if e.expiryNano != 0 {
+		meta.TTL = time.Duration(e.expiryNano - now)
 	}
+	return e.value, meta, true
 }
 
-func (s *Cache) Resize(sizeMB int64) error {
-	newSizeInBytes := sizeMB * bytesInMB
-	if s.maxSizeInBytes < newSizeInBytes {
-		// we simply increase size of L2 cache without any eviction
-		s.maxSizeInBytes = newSizeInBytes
-		s.sizerL1.Resize(int(newSizeInBytes))
-		return nil
+// Len returns number of items in cache.
+func (s *Cache[K, V]) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}
+
+// Size returns sum of sizer weights of items in cache, expired but not yet removed items included.
+func (s *Cache[K, V]) Size() int64 {
+	return s.sizerL1.Size()
+}
+
+// Stats returns lookup and eviction counters, Bytes is the sum of sizer weights.
+func (s *Cache[K, V]) Stats() appUtils.CacheStats {
+	s.mu.Lock()
+	items := int64(len(s.items))
+	s.mu.Unlock()
+	return s.counters.Stats(items, s.sizerL1.Size())
+}
+
+// ObserveLoad records loading of a missing value, it is meant for code filling the cache on miss.
+func (s *Cache[K, V]) ObserveLoad(duration time.Duration, err error) {
+	s.counters.ObserveLoad(duration, err)
+}
+
+func (s *Cache[K, V]) Resize(size int64) error {
+	return s.resize(size * s.resizeUnit)
+}
+
+func (s *Cache[K, V]) resize(newMaxSize int64) error {
+	if newMaxSize <= 0 {
+		return ErrInvalidMaxSize
 	}
-	// we need to evict some items from L2 cache
-	delta := s.maxSizeInBytes - newSizeInBytes
-	evictKeysSize := 0
-	for {
-		_, value, ok := s.lruCache.GetOldest()
-		if !ok {
-			// there are no items to evict, so adding this item will increase size of storage
-			break
-		}
-		valueBytes, ok := value.([]byte)
-		if !ok {
-			// we expect only []byte, so trigger error here
-			return errors.New("unknown type of value in L2 cache")
-		}
-		s.lruCache.RemoveOldest() // it will call onEvict
-		s.sizerL1.Remove(len(valueBytes))
-		evictKeysSize += len(valueBytes)
-		if evictKeysSize >= int(delta) {
-			break
-		}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// evict the least recently used items until rest fits into new size
+	s.maxSize = newMaxSize
+	s.sizerL1.Resize(int(newMaxSize))
+	s.evictToFit(nil)
+	if s.admission != nil {
+		s.drainWindow()
 	}
-	s.maxSizeInBytes = newSizeInBytes
-	s.lruCache.Resize(int(s.maxSizeInBytes))
 	return nil
 }
+
+func (s *Cache[K, V]) add(key K, value V, size, createdNano, expiryNano int64) {
+	e := &entry[K, V]{key: key, value: value, size: size, createdNano: createdNano, expiryNano: expiryNano}
+	if s.admission != nil {
+		e.list = listWindow
+	}
+	s.items[key] = e
+	s.lists[e.list].pushFront(e)
+	s.sizerL1.Add(int(size))
+}
+
+// evict removes entry and notifies EvictListener if it was requested. Must be called under lock.
+func (s *Cache[K, V]) evict(e *entry[K, V], reason EvictReason) {
+	s.lists[e.list].unlink(e)
+	delete(s.items, e.key)
+	s.sizerL1.Remove(int(e.size))
+	s.counters.Evicted(reason.String(), 1)
+	if s.evictedItems != nil {
+		item := EvictedItem[K, V]{Key: e.key, Value: e.value, Reason: reason}
+		if e.expiryNano != 0 {
+			item.ExpiresAt = time.Unix(0, e.expiryNano)
+		}
+		s.evictedItems <- item
+	}
+}
+
+// moveTo moves entry to the head of another list.
+func (s *Cache[K, V]) moveTo(e *entry[K, V], id listID) {
+	s.lists[e.list].unlink(e)
+	e.list = id
+	s.lists[id].pushFront(e)
+}
@@ -0,0 +1,95 @@
+package lru_test
+
+import (
+	"go_project_template/internal/storage/cache/lru"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type cachedUser struct {
+	ID   int
+	Name string
+}
+
+func TestCache_Generic(t *testing.T) {
+	t.Run("should validate arguments", func(t *testing.T) {
+		_, err := lru.New[string, int](0, lru.CountSizer[int])
+		require.ErrorIs(t, err, lru.ErrInvalidMaxSize)
+		_, err = lru.New[string, int](1, nil)
+		require.ErrorIs(t, err, lru.ErrNilSizer)
+	})
+	t.Run("should store structs limited by count", func(t *testing.T) {
+		cache, err := lru.New[int, cachedUser](3, lru.CountSizer[cachedUser])
+		require.NoError(t, err)
+		evicted := cache.EvictListener()
+		for i := 0; i < 2; i++ {
+			require.NoError(t, cache.Store(i, cachedUser{ID: i}))
+		}
+		_, ok := cache.Get(0) // 1 becomes the oldest
+		require.True(t, ok)
+		require.NoError(t, cache.Store(2, cachedUser{ID: 2}))
+
+		item := <-evicted
+		require.Equal(t, lru.EvictedItem[int, cachedUser]{Key: 1, Value: cachedUser{ID: 1}}, item)
+		require.Equal(t, 2, cache.Len())
+		user, ok := cache.Get(0)
+		require.True(t, ok)
+		require.Equal(t, cachedUser{ID: 0}, user)
+		require.Equal(t, int64(3), cache.GetMaxSize())
+	})
+	t.Run("should use custom weight", func(t *testing.T) {
+		cache, err := lru.New[string, cachedUser](10, func(v cachedUser) int64 {
+			return int64(len(v.Name))
+		})
+		require.NoError(t, err)
+		require.NoError(t, cache.Store("a", cachedUser{Name: "12345"}))
+		require.NoError(t, cache.Store("b", cachedUser{Name: "1234"}))
+		require.ErrorIs(t, cache.Store("c", cachedUser{Name: "123456"}), lru.ErrL1CacheIsFull)
+		require.NoError(t, cache.Store("c", cachedUser{Name: "12"}))
+		_, ok := cache.Get("a")
+		require.False(t, ok)
+	})
+	t.Run("should account overwritten values once", func(t *testing.T) {
+		cache, err := lru.New[string, []byte](10, lru.BytesSizer)
+		require.NoError(t, err)
+		for i := 0; i < 100; i++ {
+			require.NoError(t, cache.Store("a", make([]byte, 4)))
+		}
+		require.NoError(t, cache.Store("b", make([]byte, 5)))
+		require.Equal(t, 2, cache.Len())
+	})
+	t.Run("should resize in sizer units", func(t *testing.T) {
+		cache, err := lru.New[int, int](10, lru.CountSizer[int])
+		require.NoError(t, err)
+		for i := 0; i < 9; i++ {
+			require.NoError(t, cache.Store(i, i))
+		}
+		require.NoError(t, cache.Resize(5))
+		require.Equal(t, 5, cache.Len())
+		_, ok := cache.Get(3)
+		require.False(t, ok)
+		_, ok = cache.Get(4)
+		require.True(t, ok)
+	})
+}
+
+func TestCache_Stats(t *testing.T) {
+	cache, err := lru.NewSharded[string, []byte](64, 2, lru.BytesSizer)
+	require.NoError(t, err)
+	require.NoError(t, cache.Store("a", make([]byte, 10)))
+	require.NoError(t, cache.Store("b", make([]byte, 20)))
+	cache.Get("a")
+	cache.Get("missing")
+	cache.Delete("b")
+	cache.ObserveLoad(time.Millisecond, nil)
+
+	stats := cache.Stats()
+	require.Equal(t, uint64(1), stats.Hits)
+	require.Equal(t, uint64(1), stats.Misses)
+	require.Equal(t, uint64(1), stats.Evictions[lru.EvictReasonManual.String()])
+	require.Equal(t, int64(1), stats.Items)
+	require.Equal(t, int64(10), stats.Bytes)
+	require.Equal(t, uint64(1), stats.Loads)
+}
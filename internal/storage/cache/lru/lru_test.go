@@ -85,16 +85,29 @@ func TestService_StoreL1(t *testing.T) {
 		// then
 		require.NoError(t, service.Store("key_abc", make([]byte, 100*kbBytes-1)))
 	})
+	t.Run("replace piece with bigger than cache", func(t *testing.T) {
+		service, err := lru.New[string, []byte](100, lru.BytesSizer)
+		require.NoError(t, err)
+		require.NoError(t, service.Store("key", []byte("small")))
+		// when
+		err = service.Store("key", make([]byte, 1000))
+		// then
+		require.ErrorIs(t, err, lru.ErrL1CacheIsFull)
+		require.Equal(t, int64(5), service.Size())
+		res, ok := service.Get("key")
+		require.True(t, ok)
+		require.Equal(t, []byte("small"), res)
+	})
 }
 
-func storeInL1Cache(t *testing.T, service *lru.Cache, times int, data []byte) {
+func storeInL1Cache(t *testing.T, service *lru.Cache[string, []byte], times int, data []byte) {
 	for i := 0; i < times; i++ {
 		key := fmt.Sprintf("key_%d", i)
 		require.NoErrorf(t, service.Store(key, data), "unexpected error for `%s`", key)
 	}
 }
 
-func checkHashL1(t *testing.T, data []byte, service *lru.Cache, num int) {
+func checkHashL1(t *testing.T, data []byte, service *lru.Cache[string, []byte], num int) {
 	for i := 0; i < num; i++ {
 		res, ok := service.Get(fmt.Sprintf("key_%d", i))
 		require.True(t, ok, "get key_%d", i)
@@ -0,0 +1,80 @@
+package lru_test
+
+import (
+	"context"
+	"go_project_template/internal/storage/cache/lru"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_TTL(t *testing.T) {
+	t.Run("should expire item lazily on get", func(t *testing.T) {
+		cache, err := lru.New[string, int](10, lru.CountSizer[int])
+		require.NoError(t, err)
+		evicted := cache.EvictListener()
+		require.NoError(t, cache.StoreWithTTL("short", 1, 20*time.Millisecond))
+		require.NoError(t, cache.Store("forever", 2))
+
+		time.Sleep(30 * time.Millisecond)
+		_, ok := cache.Get("short")
+		require.False(t, ok)
+		item := <-evicted
+		require.Equal(t, "short", item.Key)
+		require.Equal(t, 1, item.Value)
+		require.Equal(t, lru.EvictReasonExpired, item.Reason)
+		require.False(t, item.ExpiresAt.IsZero())
+		_, ok = cache.Get("forever")
+		require.True(t, ok)
+	})
+	t.Run("should apply default ttl and return meta", func(t *testing.T) {
+		cache, err := lru.New[string, int](10, lru.CountSizer[int])
+		require.NoError(t, err)
+		cache.WithDefaultTTL(time.Minute)
+		require.NoError(t, cache.Store("a", 1))
+		require.NoError(t, cache.StoreWithTTL("b", 2, 0))
+		time.Sleep(10 * time.Millisecond)
+
+		value, meta, ok := cache.GetWithMeta("a")
+		require.True(t, ok)
+		require.Equal(t, 1, value)
+		require.GreaterOrEqual(t, meta.Age, 10*time.Millisecond)
+		require.Greater(t, meta.TTL, 59*time.Second)
+		require.LessOrEqual(t, meta.TTL, time.Minute-10*time.Millisecond)
+
+		_, meta, ok = cache.GetWithMeta("b")
+		require.True(t, ok)
+		require.Zero(t, meta.TTL)
+	})
+	t.Run("should sweep expired items in background", func(t *testing.T) {
+		cache, err := lru.New[string, int](10, lru.CountSizer[int])
+		require.NoError(t, err)
+		cache.WithSweeper(context.Background(), 10*time.Millisecond)
+		defer cache.Close()
+		for _, key := range []string{"a", "b", "c"} {
+			require.NoError(t, cache.StoreWithTTL(key, 1, 20*time.Millisecond))
+		}
+		require.NoError(t, cache.Store("d", 1))
+		require.Eventually(t, func() bool {
+			return cache.Len() == 1
+		}, time.Second, 10*time.Millisecond)
+		cache.Close()
+	})
+	t.Run("should notify with eviction reason", func(t *testing.T) {
+		cache, err := lru.New[string, int](2, lru.CountSizer[int])
+		require.NoError(t, err)
+		evicted := cache.EvictListener()
+		require.NoError(t, cache.Store("a", 1))
+		cache.Delete("a")
+		require.Equal(t, lru.EvictReasonManual, (<-evicted).Reason)
+
+		require.NoError(t, cache.Store("b", 1))
+		require.NoError(t, cache.Store("c", 1))
+		require.Equal(t, lru.EvictReasonSize, (<-evicted).Reason)
+
+		cache.Purge()
+		require.Equal(t, lru.EvictReasonPurge, (<-evicted).Reason)
+		require.Equal(t, "purge", lru.EvictReasonPurge.String())
+	})
+}
@@ -0,0 +1,246 @@
+package lru
+
+import (
+	"context"
+	"errors"
+	appUtils "go_project_template/internal/utils"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// DefaultShards matches lock striping of utils.TTLMap.
+const DefaultShards = 32
+
+var ErrInvalidShards = errors.New("number of shards should be a power of two")
+
+var _ Cacher[string, []byte] = (*ShardedCache[string, []byte])(nil)
+
+// ShardedCache splits keys between independent LRU shards, each with its own lock and part of the budget.
+// Budget of every shard is enforced under the shard lock, so concurrent writers can't overshoot it.
+// Recency is tracked per shard, so eviction is approximate LRU across the whole cache,
+// and single item can't be bigger than budget of one shard.
+type ShardedCache[K comparable, V any] struct {
+	shards     []*Cache[K, V]
+	mask       uint64
+	seed       maphash.Seed
+	resizeUnit int64
+
+	evictMu      sync.Mutex
+	evictedItems chan EvictedItem[K, V]
+	loads        appUtils.CacheCounters // lookups and evictions are counted by shards
+
+	wg        sync.WaitGroup
+	stopCh    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewSharded creates cache with maxSize split between shards, shards must be a power of two.
+func NewSharded[K comparable, V any](maxSize int64, shards int, sizer Sizer[V]) (*ShardedCache[K, V], error) {
+	return newShardedCache[K, V](maxSize, shards, 1, sizer)
+}
+
+// CreateShardedL1Cache is a sharded version of CreateL1Cache.
+func CreateShardedL1Cache(lruMaxSizeMB int64, shards int) (*ShardedCache[string, []byte], error) {
+	return newShardedCache[string, []byte](lruMaxSizeMB*bytesInMB, shards, bytesInMB, BytesSizer)
+}
+
+func newShardedCache[K comparable, V any](maxSize int64, shards int, resizeUnit int64, sizer Sizer[V]) (*ShardedCache[K, V], error) {
+	if shards <= 0 || shards&(shards-1) != 0 {
+		return nil, ErrInvalidShards
+	}
+	if maxSize < int64(shards) {
+		return nil, ErrInvalidMaxSize
+	}
+	c := &ShardedCache[K, V]{
+		shards:     make([]*Cache[K, V], shards),
+		mask:       uint64(shards - 1),
+		seed:       maphash.MakeSeed(),
+		resizeUnit: resizeUnit,
+		stopCh:     make(chan struct{}),
+	}
+	for i, size := range splitBudget(maxSize, shards) {
+		shard, err := newCache[K, V](size, 1, sizer)
+		if err != nil {
+			return nil, err
+		}
+		c.shards[i] = shard
+	}
+	return c, nil
+}
+
+// splitBudget divides total between shards, remainder goes to the first shards.
+func splitBudget(total int64, shards int) []int64 {
+	res := make([]int64, shards)
+	base, rem := total/int64(shards), total%int64(shards)
+	for i := range res {
+		res[i] = base
+		if int64(i) < rem {
+			res[i]++
+		}
+	}
+	return res
+}
+
+func (c *ShardedCache[K, V]) shardOf(key K) *Cache[K, V] {
+	return c.shards[maphash.Comparable(c.seed, key)&c.mask]
+}
+
+// WithDefaultTTL sets TTL applied by Store in every shard.
+func (c *ShardedCache[K, V]) WithDefaultTTL(ttl time.Duration) *ShardedCache[K, V] {
+	for _, shard := range c.shards {
+		shard.WithDefaultTTL(ttl)
+	}
+	return c
+}
+
+// WithTinyLFU enables W-TinyLFU admission in every shard, expectedItems is split between shards.
+func (c *ShardedCache[K, V]) WithTinyLFU(expectedItems int) *ShardedCache[K, V] {
+	for _, shard := range c.shards {
+		shard.WithTinyLFU(expectedItems / len(c.shards))
+	}
+	return c
+}
+
+// WithLoader configures GetOrLoad of every shard.
+func (c *ShardedCache[K, V]) WithLoader(conf appUtils.LoaderConf) *ShardedCache[K, V] {
+	for _, shard := range c.shards {
+		shard.WithLoader(conf)
+	}
+	return c
+}
+
+// WithSweeper starts single background goroutine removing expired items from all shards every interval.
+func (c *ShardedCache[K, V]) WithSweeper(ctx context.Context, interval time.Duration) *ShardedCache[K, V] {
+	ticker := time.NewTicker(interval)
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.RemoveExpired()
+			case <-c.stopCh:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return c
+}
+
+// Close stops the sweeper and waits for it to exit, safe to call multiple times.
+func (c *ShardedCache[K, V]) Close() {
+	c.closeOnce.Do(func() {
+		close(c.stopCh)
+		c.wg.Wait()
+	})
+}
+
+func (c *ShardedCache[K, V]) RemoveExpired() int {
+	removed := 0
+	for _, shard := range c.shards {
+		removed += shard.RemoveExpired()
+	}
+	return removed
+}
+
+func (c *ShardedCache[K, V]) Store(key K, value V) error {
+	return c.shardOf(key).Store(key, value)
+}
+
+func (c *ShardedCache[K, V]) StoreWithTTL(key K, value V, ttl time.Duration) error {
+	return c.shardOf(key).StoreWithTTL(key, value, ttl)
+}
+
+func (c *ShardedCache[K, V]) Get(key K) (value V, exist bool) {
+	return c.shardOf(key).Get(key)
+}
+
+func (c *ShardedCache[K, V]) GetWithMeta(key K) (value V, meta ItemMeta, exist bool) {
+	return c.shardOf(key).GetWithMeta(key)
+}
+
+// GetOrLoad loads missing item within its shard, see Cache.GetOrLoad.
+func (c *ShardedCache[K, V]) GetOrLoad(ctx context.Context, key K, loader appUtils.LoadFunc[K, V]) (V, error) {
+	return c.shardOf(key).GetOrLoad(ctx, key, loader)
+}
+
+func (c *ShardedCache[K, V]) Delete(key K) {
+	c.shardOf(key).Delete(key)
+}
+
+// EvictListener returns single channel shared by all shards.
+func (c *ShardedCache[K, V]) EvictListener() <-chan EvictedItem[K, V] {
+	c.evictMu.Lock()
+	defer c.evictMu.Unlock()
+	if c.evictedItems == nil {
+		c.evictedItems = make(chan EvictedItem[K, V], 1000)
+		for _, shard := range c.shards {
+			shard.mu.Lock()
+			shard.evictedItems = c.evictedItems
+			shard.mu.Unlock()
+		}
+	}
+	return c.evictedItems
+}
+
+func (c *ShardedCache[K, V]) Purge() {
+	for _, shard := range c.shards {
+		shard.Purge()
+	}
+}
+
+// Resize changes capacity of every shard, size is measured in units cache was created with.
+func (c *ShardedCache[K, V]) Resize(size int64) error {
+	newMaxSize := size * c.resizeUnit
+	if newMaxSize < int64(len(c.shards)) {
+		return ErrInvalidMaxSize
+	}
+	for i, shardSize := range splitBudget(newMaxSize, len(c.shards)) {
+		if err := c.shards[i].resize(shardSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *ShardedCache[K, V]) GetMaxSize() int64 {
+	total := int64(0)
+	for _, shard := range c.shards {
+		total += shard.GetMaxSize()
+	}
+	return total
+}
+
+func (c *ShardedCache[K, V]) Len() int {
+	total := 0
+	for _, shard := range c.shards {
+		total += shard.Len()
+	}
+	return total
+}
+
+func (c *ShardedCache[K, V]) Size() int64 {
+	total := int64(0)
+	for _, shard := range c.shards {
+		total += shard.Size()
+	}
+	return total
+}
+
+// Stats returns counters summed over all shards.
+func (c *ShardedCache[K, V]) Stats() appUtils.CacheStats {
+	stats := c.loads.Stats(0, 0)
+	for _, shard := range c.shards {
+		stats = stats.Add(shard.Stats())
+	}
+	return stats
+}
+
+// ObserveLoad records loading of a missing value, it is meant for code filling the cache on miss.
+func (c *ShardedCache[K, V]) ObserveLoad(duration time.Duration, err error) {
+	c.loads.ObserveLoad(duration, err)
+}
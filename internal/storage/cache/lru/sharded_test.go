@@ -0,0 +1,141 @@
+package lru_test
+
+import (
+	"fmt"
+	"go_project_template/internal/storage/cache/lru"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedCache(t *testing.T) {
+	t.Run("should validate shards", func(t *testing.T) {
+		_, err := lru.NewSharded[string, int](100, 3, lru.CountSizer[int])
+		require.ErrorIs(t, err, lru.ErrInvalidShards)
+		_, err = lru.NewSharded[string, int](2, 4, lru.CountSizer[int])
+		require.ErrorIs(t, err, lru.ErrInvalidMaxSize)
+	})
+	t.Run("should split budget between shards", func(t *testing.T) {
+		cache, err := lru.NewSharded[string, int](1000, 8, lru.CountSizer[int])
+		require.NoError(t, err)
+		require.Equal(t, int64(1000), cache.GetMaxSize())
+		for i := 0; i < 10_000; i++ {
+			_ = cache.Store(strconv.Itoa(i), i)
+		}
+		require.LessOrEqual(t, cache.Len(), 1000)
+		require.Greater(t, cache.Len(), 900, "keys should be spread evenly")
+		require.NoError(t, cache.Resize(80))
+		require.LessOrEqual(t, cache.Len(), 80)
+		require.Equal(t, int64(80), cache.GetMaxSize())
+	})
+	t.Run("should share evict listener between shards", func(t *testing.T) {
+		cache, err := lru.NewSharded[int, int](16, 4, lru.CountSizer[int])
+		require.NoError(t, err)
+		evicted := cache.EvictListener()
+		for i := 0; i < 100; i++ {
+			_ = cache.Store(i, i)
+		}
+		cache.Purge()
+		cnt := 0
+		for len(evicted) > 0 {
+			<-evicted
+			cnt++
+		}
+		require.Equal(t, 100, cnt, "every stored item should be evicted either by size or purge")
+		require.Zero(t, cache.Len())
+	})
+	t.Run("mb based l1 cache", func(t *testing.T) {
+		cache, err := lru.CreateShardedL1Cache(1, 4)
+		require.NoError(t, err)
+		require.NoError(t, cache.Store("key", make([]byte, 100*kbBytes)))
+		res, ok := cache.Get("key")
+		require.True(t, ok)
+		require.Len(t, res, 100*kbBytes)
+		require.NoError(t, cache.Resize(2))
+		require.Equal(t, int64(2*1024*kbBytes), cache.GetMaxSize())
+	})
+}
+
+// TestCaches_ConcurrentAccounting is meant to be run with -race.
+func TestCaches_ConcurrentAccounting(t *testing.T) {
+	const itemSize = 64
+	single, err := lru.New[string, []byte](64*itemSize, lru.BytesSizer)
+	require.NoError(t, err)
+	sharded, err := lru.NewSharded[string, []byte](64*itemSize, 8, lru.BytesSizer)
+	require.NoError(t, err)
+
+	for name, cache := range map[string]interface {
+		lru.Cacher[string, []byte]
+		Len() int
+		Size() int64
+	}{"single": single, "sharded": sharded} {
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			for w := 0; w < 16; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					for i := 0; i < 2000; i++ {
+						key := strconv.Itoa(rand.IntN(512)) //nolint:gosec // test data
+						switch {
+						case w == 0 && i%500 == 0:
+							cache.Purge()
+						case w == 1 && i%200 == 0:
+							require.NoError(t, cache.Resize(int64(32+rand.IntN(64))*itemSize)) //nolint:gosec // test data
+						case i%3 == 0:
+							cache.Delete(key)
+						case i%2 == 0:
+							_ = cache.Store(key, make([]byte, itemSize))
+						default:
+							cache.Get(key)
+						}
+					}
+				}(w)
+			}
+			wg.Wait()
+			// totals of sharded cache are summed shard by shard, so invariants are checked once writers are done
+			require.Equal(t, int64(cache.Len())*itemSize, cache.Size(), "size should match items")
+			require.LessOrEqual(t, cache.Size(), cache.GetMaxSize())
+			cache.Purge()
+			require.Zero(t, cache.Size())
+			require.Zero(t, cache.Len())
+		})
+	}
+}
+
+func BenchmarkCache_Parallel(b *testing.B) {
+	cache, err := lru.New[string, []byte](1024*1024, lru.BytesSizer)
+	require.NoError(b, err)
+	benchmarkParallel(b, cache)
+}
+
+func BenchmarkShardedCache_Parallel(b *testing.B) {
+	cache, err := lru.NewSharded[string, []byte](1024*1024, lru.DefaultShards, lru.BytesSizer)
+	require.NoError(b, err)
+	benchmarkParallel(b, cache)
+}
+
+func benchmarkParallel(b *testing.B, cache lru.Cacher[string, []byte]) {
+	keys := make([]string, 4096)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key_%d", i)
+		_ = cache.Store(keys[i], make([]byte, 128))
+	}
+	payload := make([]byte, 128)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := rand.IntN(len(keys)) //nolint:gosec // test data
+		for pb.Next() {
+			key := keys[i%len(keys)]
+			if i%10 == 0 {
+				_ = cache.Store(key, payload)
+			} else {
+				cache.Get(key)
+			}
+			i++
+		}
+	})
+}
@@ -0,0 +1,14 @@
+package lru
+
+// Sizer returns weight of value, the sum of weights is limited by cache max size.
+type Sizer[V any] func(value V) int64
+
+// BytesSizer weights []byte by its length, so cache max size is a byte budget.
+func BytesSizer(value []byte) int64 {
+	return int64(len(value))
+}
+
+// CountSizer weights every item as 1, so cache max size is a number of items.
+func CountSizer[V any](V) int64 {
+	return 1
+}
@@ -0,0 +1,62 @@
+package lru
+
+const (
+	sketchDepth    = 4
+	sketchMaxCount = 15 // counters saturate like 4-bit ones, old popularity fades faster after reset
+	sketchMinWidth = 64
+)
+
+// countMinSketch estimates access frequency of keys in fixed memory.
+// Every key increments one counter in each row, estimate is the minimum of them, so it may only overestimate.
+// Counters are halved after sampleSize increments, so frequency reflects recent history.
+type countMinSketch struct {
+	table      []uint8
+	mask       uint64
+	additions  int
+	sampleSize int
+}
+
+func newCountMinSketch(expectedItems int) *countMinSketch {
+	width := sketchMinWidth
+	for width < expectedItems {
+		width <<= 1
+	}
+	return &countMinSketch{
+		table:      make([]uint8, sketchDepth*width),
+		mask:       uint64(width - 1),
+		sampleSize: 10 * width,
+	}
+}
+
+// index uses double hashing to derive row positions from a single hash.
+func (s *countMinSketch) index(hash uint64, row int) uint64 {
+	h1, h2 := hash, hash>>32|1
+	return uint64(row)*(s.mask+1) + (h1+uint64(row)*h2)&s.mask
+}
+
+func (s *countMinSketch) increment(hash uint64) {
+	for row := 0; row < sketchDepth; row++ {
+		if i := s.index(hash, row); s.table[i] < sketchMaxCount {
+			s.table[i]++
+		}
+	}
+	s.additions++
+	if s.additions >= s.sampleSize {
+		s.reset()
+	}
+}
+
+func (s *countMinSketch) estimate(hash uint64) int {
+	res := sketchMaxCount
+	for row := 0; row < sketchDepth; row++ {
+		res = min(res, int(s.table[s.index(hash, row)]))
+	}
+	return res
+}
+
+func (s *countMinSketch) reset() {
+	for i := range s.table {
+		s.table[i] >>= 1
+	}
+	s.additions /= 2
+}
@@ -0,0 +1,73 @@
+package lru
+
+import (
+	"fmt"
+	appUtils "go_project_template/internal/utils"
+	"time"
+)
+
+// WithSnapshot configures file used by SaveSnapshot and LoadSnapshot.
+func (s *Cache[K, V]) WithSnapshot(conf appUtils.SnapshotConf[K, V]) *Cache[K, V] {
+	s.mu.Lock()
+	s.snapshot = &conf
+	s.mu.Unlock()
+	return s
+}
+
+// SaveSnapshot writes live items from the least to the most recently used one with their expiry.
+func (s *Cache[K, V]) SaveSnapshot() error {
+	now := time.Now().UnixNano()
+	s.mu.Lock()
+	conf := s.snapshot
+	entries := make([]appUtils.SnapshotEntry[K, V], 0, len(s.items))
+	// with admission policy the order is probation, protected and window, from the least valuable items
+	for _, id := range []listID{listMain, listProtected, listWindow} {
+		for e := s.lists[id].tail; e != nil; e = e.prev {
+			if e.expired(now) {
+				continue
+			}
+			entry := appUtils.SnapshotEntry[K, V]{Key: e.key, Value: e.value}
+			if e.expiryNano != 0 {
+				entry.ExpiresAt = time.Unix(0, e.expiryNano)
+			}
+			entries = append(entries, entry)
+		}
+	}
+	s.mu.Unlock()
+	if conf == nil {
+		return appUtils.ErrSnapshotNotConfigured
+	}
+	if err := appUtils.WriteSnapshot(*conf, entries); err != nil {
+		return fmt.Errorf("save lru snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot stores items from configured file restoring their recency and remaining TTL.
+// Expired items and items which don't fit cache are skipped, missing file loads nothing.
+// Returns number of loaded items.
+func (s *Cache[K, V]) LoadSnapshot() (int, error) {
+	s.mu.Lock()
+	conf := s.snapshot
+	s.mu.Unlock()
+	if conf == nil {
+		return 0, appUtils.ErrSnapshotNotConfigured
+	}
+	entries, err := appUtils.ReadSnapshot(*conf)
+	if err != nil {
+		return 0, fmt.Errorf("load lru snapshot: %w", err)
+	}
+	loaded := 0
+	for _, entry := range entries {
+		ttl := time.Duration(0)
+		if !entry.ExpiresAt.IsZero() {
+			if ttl = time.Until(entry.ExpiresAt); ttl <= 0 {
+				continue
+			}
+		}
+		if s.StoreWithTTL(entry.Key, entry.Value, ttl) == nil {
+			loaded++
+		}
+	}
+	return loaded, nil
+}
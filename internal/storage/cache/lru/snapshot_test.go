@@ -0,0 +1,50 @@
+package lru_test
+
+import (
+	"go_project_template/internal/storage/cache/lru"
+	"go_project_template/internal/utils"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_Snapshot(t *testing.T) {
+	conf := utils.SnapshotConf[string, []byte]{
+		Path:   filepath.Join(t.TempDir(), "l1.snapshot"),
+		Keys:   utils.StringCodec(),
+		Values: utils.BytesCodec(),
+	}
+	cache, err := lru.New[string, []byte](100, lru.CountSizer[[]byte])
+	require.NoError(t, err)
+	cache.WithSnapshot(conf)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, cache.Store(strconv.Itoa(i), []byte{byte(i)}))
+	}
+	require.NoError(t, cache.StoreWithTTL("ttl", []byte("ttl"), time.Hour))
+	require.NoError(t, cache.StoreWithTTL("expired", []byte("expired"), time.Millisecond))
+	time.Sleep(2 * time.Millisecond)
+	_, ok := cache.Get("0") // the most recently used one
+	require.True(t, ok)
+	require.NoError(t, cache.SaveSnapshot())
+
+	// restored cache is smaller, so only 3 the most recently used items fit
+	restored, err := lru.New[string, []byte](4, lru.CountSizer[[]byte])
+	require.NoError(t, err)
+	restored.WithSnapshot(conf)
+	loaded, err := restored.LoadSnapshot()
+	require.NoError(t, err)
+	require.Equal(t, 6, loaded, "expired item should be skipped")
+	require.Equal(t, 3, restored.Len())
+	for _, key := range []string{"0", "ttl", "4"} {
+		_, ok := restored.Get(key)
+		require.True(t, ok, key)
+	}
+	_, meta, ok := restored.GetWithMeta("ttl")
+	require.True(t, ok)
+	require.InDelta(t, time.Hour, meta.TTL, float64(time.Second))
+	_, ok = restored.Get("3")
+	require.False(t, ok)
+}
@@ -0,0 +1,146 @@
+package lru
+
+import "hash/maphash"
+
+const (
+	DefaultWindowRatio    = 0.01
+	DefaultProtectedRatio = 0.8
+)
+
+// tinyLFU holds W-TinyLFU state: new items enter small LRU window, items leaving the window
+// compete for place in main area with its victims by estimated access frequency.
+// Main area is segmented: items hit in probation move to protected, protected overflow goes back to probation.
+type tinyLFU struct {
+	sketch         *countMinSketch
+	seed           maphash.Seed
+	windowRatio    float64
+	protectedRatio float64
+}
+
+// WithTinyLFU enables W-TinyLFU admission, so one-hit wonders can't flush frequently used items.
+// expectedItems sizes frequency sketch, it should be close to number of items cache holds.
+// Unlike plain LRU, Store admits item bigger than the oldest one evicting as many victims as needed,
+// but stored item may be evicted right away if it is accessed less often than its victims.
+// Call it before storing items.
+func (s *Cache[K, V]) WithTinyLFU(expectedItems int) *Cache[K, V] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.admission = &tinyLFU{
+		sketch:         newCountMinSketch(expectedItems),
+		seed:           maphash.MakeSeed(),
+		windowRatio:    DefaultWindowRatio,
+		protectedRatio: DefaultProtectedRatio,
+	}
+	return s
+}
+
+func (s *Cache[K, V]) recordAccess(key K) {
+	if s.admission != nil {
+		s.admission.sketch.increment(maphash.Comparable(s.admission.seed, key))
+	}
+}
+
+func (s *Cache[K, V]) frequency(key K) int {
+	return s.admission.sketch.estimate(maphash.Comparable(s.admission.seed, key))
+}
+
+func (s *Cache[K, V]) windowMaxSize() int64 {
+	return max(1, int64(float64(s.maxSize)*s.admission.windowRatio))
+}
+
+func (s *Cache[K, V]) protectedMaxSize() int64 {
+	return int64(float64(s.maxSize-s.windowMaxSize()) * s.admission.protectedRatio)
+}
+
+// storeAdmitted puts new item to the window and lets window overflow compete for main area. Must be called under lock.
+func (s *Cache[K, V]) storeAdmitted(key K, value V, size, now, expiryNano int64) error {
+	if size > s.maxSize {
+		return ErrL1CacheIsFull
+	}
+	s.add(key, value, size, now, expiryNano)
+	s.drainWindow()
+	return nil
+}
+
+// drainWindow moves the oldest window items to main area while window exceeds its budget.
+func (s *Cache[K, V]) drainWindow() {
+	window := &s.lists[listWindow]
+	for window.tail != nil && window.size > s.windowMaxSize() {
+		candidate := window.tail
+		overflow := s.sizerL1.Size() - s.maxSize
+		if overflow <= 0 {
+			s.moveTo(candidate, listMain)
+			continue
+		}
+		victims, ok := s.victims(overflow)
+		if !ok || !s.admit(candidate, victims) {
+			s.evict(candidate, EvictReasonSize)
+			continue
+		}
+		for _, victim := range victims {
+			s.evict(victim, EvictReasonSize)
+		}
+		s.moveTo(candidate, listMain)
+	}
+}
+
+// victims collects the least valuable main area items whose weight covers needed, probation goes first.
+func (s *Cache[K, V]) victims(needed int64) ([]*entry[K, V], bool) {
+	var res []*entry[K, V]
+	freed := int64(0)
+	for _, id := range []listID{listMain, listProtected} {
+		for e := s.lists[id].tail; e != nil && freed < needed; e = e.prev {
+			res = append(res, e)
+			freed += e.size
+		}
+	}
+	return res, freed >= needed
+}
+
+// admit reports if candidate is accessed more often than all its victims together.
+func (s *Cache[K, V]) admit(candidate *entry[K, V], victims []*entry[K, V]) bool {
+	candidateFreq := s.frequency(candidate.key)
+	victimsFreq := 0
+	for _, victim := range victims {
+		victimsFreq += s.frequency(victim.key)
+		if victimsFreq >= candidateFreq {
+			return false
+		}
+	}
+	return true
+}
+
+// promote updates recency of hit entry, probation hit moves entry to protected segment.
+func (s *Cache[K, V]) promote(e *entry[K, V]) {
+	if s.admission == nil || e.list != listMain {
+		s.lists[e.list].moveToFront(e)
+		return
+	}
+	s.moveTo(e, listProtected)
+	protected := &s.lists[listProtected]
+	for protected.tail != nil && protected.size > s.protectedMaxSize() {
+		s.moveTo(protected.tail, listMain)
+	}
+}
+
+// evictToFit evicts the least valuable items until cache fits max size, keep is evicted last.
+func (s *Cache[K, V]) evictToFit(keep *entry[K, V]) {
+	for s.sizerL1.Size() > s.maxSize {
+		victim := s.nextVictim(keep)
+		if victim == nil {
+			return
+		}
+		s.evict(victim, EvictReasonSize)
+	}
+}
+
+func (s *Cache[K, V]) nextVictim(keep *entry[K, V]) *entry[K, V] {
+	for _, id := range []listID{listMain, listProtected, listWindow} {
+		for e := s.lists[id].tail; e != nil; e = e.prev {
+			if e != keep {
+				return e
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package lru_test
+
+import (
+	"go_project_template/internal/storage/cache/lru"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	zipfKeys     = 10000
+	zipfCapacity = 500
+	zipfRequests = 200000
+)
+
+// zipfHitRatio replays Zipf distributed lookups storing every missed key, half of the requests
+// are interleaved with a scan of keys never seen again.
+func zipfHitRatio(t testing.TB, cache *lru.Cache[uint64, uint64], withScan bool) float64 {
+	zipf := rand.NewZipf(rand.New(rand.NewPCG(1, 2)), 1.1, 1, zipfKeys-1) //nolint:gosec // deterministic workload
+	scanKey := uint64(zipfKeys)
+	for i := 0; i < zipfRequests; i++ {
+		key := zipf.Uint64()
+		if withScan && i%2 == 1 {
+			key = scanKey
+			scanKey++
+		}
+		if _, ok := cache.Get(key); !ok {
+			require.NoError(t, cache.Store(key, key))
+		}
+	}
+	return cache.Stats().HitRatio()
+}
+
+func newZipfCache(t testing.TB, tinyLFU bool) *lru.Cache[uint64, uint64] {
+	cache, err := lru.New[uint64, uint64](zipfCapacity+1, lru.CountSizer[uint64])
+	require.NoError(t, err)
+	if tinyLFU {
+		cache.WithTinyLFU(zipfCapacity)
+	}
+	return cache
+}
+
+func TestCache_TinyLFU(t *testing.T) {
+	t.Run("should beat LRU hit ratio on Zipf workload", func(t *testing.T) {
+		for _, withScan := range []bool{false, true} {
+			plain := zipfHitRatio(t, newZipfCache(t, false), withScan)
+			admitted := zipfHitRatio(t, newZipfCache(t, true), withScan)
+			require.Greater(t, admitted, plain, "scan %v", withScan)
+		}
+	})
+	t.Run("should evict multiple victims for frequent large item", func(t *testing.T) {
+		cache, err := lru.New[string, []byte](101, lru.BytesSizer)
+		require.NoError(t, err)
+		cache.WithTinyLFU(100)
+		evicted := cache.EvictListener()
+		keys := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+		for _, key := range keys {
+			require.NoError(t, cache.Store(key, make([]byte, 10)))
+		}
+		for i := 0; i < 5; i++ {
+			_, ok := cache.Get("big")
+			require.False(t, ok)
+		}
+		require.NoError(t, cache.Store("big", make([]byte, 30)))
+
+		for _, key := range keys[:3] {
+			item := <-evicted
+			require.Equal(t, key, item.Key)
+			require.Equal(t, lru.EvictReasonSize, item.Reason)
+		}
+		_, ok := cache.Get("big")
+		require.True(t, ok)
+		require.Equal(t, 8, cache.Len())
+		require.Equal(t, int64(100), cache.Size())
+	})
+	t.Run("should reject rare large item", func(t *testing.T) {
+		cache, err := lru.New[string, []byte](101, lru.BytesSizer)
+		require.NoError(t, err)
+		cache.WithTinyLFU(100)
+		evicted := cache.EvictListener()
+		for _, key := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+			require.NoError(t, cache.Store(key, make([]byte, 10)))
+		}
+		require.NoError(t, cache.Store("rare", make([]byte, 30)))
+
+		item := <-evicted
+		require.Equal(t, "rare", item.Key)
+		require.Equal(t, 10, cache.Len())
+		require.ErrorIs(t, cache.Store("huge", make([]byte, 102)), lru.ErrL1CacheIsFull)
+	})
+	t.Run("should keep frequent items on resize", func(t *testing.T) {
+		cache, err := lru.New[int, int](11, lru.CountSizer[int])
+		require.NoError(t, err)
+		cache.WithTinyLFU(10)
+		for i := 0; i < 10; i++ {
+			require.NoError(t, cache.Store(i, i))
+		}
+		for i := 0; i < 3; i++ {
+			_, ok := cache.Get(0)
+			require.True(t, ok)
+		}
+		require.NoError(t, cache.Resize(5))
+		require.Equal(t, 5, cache.Len())
+		_, ok := cache.Get(0)
+		require.True(t, ok)
+	})
+}
+
+func BenchmarkCache_Zipf(b *testing.B) {
+	for _, bench := range []struct {
+		name     string
+		tinyLFU  bool
+		withScan bool
+	}{
+		{name: "LRU", tinyLFU: false},
+		{name: "TinyLFU", tinyLFU: true},
+		{name: "LRU_scan", tinyLFU: false, withScan: true},
+		{name: "TinyLFU_scan", tinyLFU: true, withScan: true},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			ratio := 0.0
+			for i := 0; i < b.N; i++ {
+				ratio = zipfHitRatio(b, newZipfCache(b, bench.tinyLFU), bench.withScan)
+			}
+			b.ReportMetric(ratio, "hit-ratio")
+		})
+	}
+}
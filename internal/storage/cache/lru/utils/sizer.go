@@ -37,3 +37,13 @@ func (c *CacheSizer) Purge() {
 func (c *CacheSizer) Resize(bytesSize int) {
 	atomic.StoreInt64(&c.maxSize, int64(bytesSize))
 }
+
+// Size returns sum of sizes of items in cache.
+func (c *CacheSizer) Size() int64 {
+	return atomic.LoadInt64(&c.bytesSize)
+}
+
+// Len returns number of items in cache.
+func (c *CacheSizer) Len() int64 {
+	return atomic.LoadInt64(&c.currentSize)
+}
@@ -0,0 +1,215 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go_project_template/internal/storage/cache/lru"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const DefaultLocalTTL = time.Minute
+
+var _ lru.Cacher[string, []byte] = (*NearCache)(nil)
+
+// invalidation is published on every write, so other instances drop their local copy of the key.
+type invalidation struct {
+	Source string `json:"source"`
+	Key    string `json:"key,omitempty"`
+	Purge  bool   `json:"purge,omitempty"`
+}
+
+// NearCache keeps local copies of remote items in memory. Writes go to remote cache and
+// publish invalidation to channel, every instance subscribed to it drops its copy of the key.
+// Messages missed during reconnect can't be recovered, so the whole local cache is purged
+// after resubscribing, and local TTL bounds staleness in case of other failures.
+type NearCache struct {
+	local    *lru.Cache[string, []byte]
+	remote   *Cache
+	pubsub   *redis.PubSub
+	channel  string
+	id       string
+	localTTL time.Duration
+
+	// fillMu orders local writes and invalidations, value read before an invalidation is not stored locally
+	fillMu     sync.Mutex
+	generation uint64
+
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewNearCache subscribes to channel and starts goroutine applying invalidations, call Close to stop it.
+func NewNearCache(ctx context.Context, local *lru.Cache[string, []byte], remote *Cache, channel string) (*NearCache, error) {
+	pubsub := remote.client.Subscribe(ctx, channel)
+	// wait for confirmation, so writes of other instances made after return are not missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, fmt.Errorf("subscribe to cache invalidations: %w", err)
+	}
+	c := &NearCache{
+		local:    local,
+		remote:   remote,
+		pubsub:   pubsub,
+		channel:  channel,
+		id:       uuid.NewString(),
+		localTTL: DefaultLocalTTL,
+	}
+	c.wg.Add(1)
+	go c.runInvalidations()
+	return c, nil
+}
+
+// WithLocalTTL limits how long local copy is used, default is DefaultLocalTTL.
+func (c *NearCache) WithLocalTTL(ttl time.Duration) *NearCache {
+	c.localTTL = ttl
+	return c
+}
+
+// Local returns in-memory tier, e.g. to read its stats.
+func (c *NearCache) Local() *lru.Cache[string, []byte] {
+	return c.local
+}
+
+// Close unsubscribes from invalidations, local copies are not used after it.
+func (c *NearCache) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		err = c.pubsub.Close()
+		c.wg.Wait()
+		c.local.Purge()
+	})
+	return err
+}
+
+func (c *NearCache) runInvalidations() {
+	defer c.wg.Done()
+	for msg := range c.pubsub.ChannelWithSubscriptions() {
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			// resubscribed after reconnect, invalidations could be lost meanwhile
+			c.invalidate(invalidation{Purge: true})
+		case *redis.Message:
+			var inv invalidation
+			if err := json.Unmarshal([]byte(msg.Payload), &inv); err != nil {
+				c.remote.onError(fmt.Errorf("decode cache invalidation: %w", err))
+				continue
+			}
+			if inv.Source != c.id {
+				c.invalidate(inv)
+			}
+		}
+	}
+}
+
+func (c *NearCache) invalidate(inv invalidation) {
+	c.fillMu.Lock()
+	defer c.fillMu.Unlock()
+	c.generation++
+	if inv.Purge {
+		c.local.Purge()
+		return
+	}
+	c.local.Delete(inv.Key)
+}
+
+func (c *NearCache) currentGeneration() uint64 {
+	c.fillMu.Lock()
+	defer c.fillMu.Unlock()
+	return c.generation
+}
+
+// fill stores local copy unless any invalidation came after generation was read.
+func (c *NearCache) fill(key string, value []byte, ttl time.Duration, generation uint64) {
+	if ttl <= 0 || ttl > c.localTTL {
+		ttl = c.localTTL
+	}
+	c.fillMu.Lock()
+	defer c.fillMu.Unlock()
+	if c.generation != generation {
+		c.local.Delete(key)
+		return
+	}
+	// local copy which doesn't fit is just not kept
+	_ = c.local.StoreWithTTL(key, value, ttl)
+}
+
+func (c *NearCache) publish(inv invalidation) error {
+	inv.Source = c.id
+	payload, err := json.Marshal(inv)
+	if err != nil {
+		return fmt.Errorf("encode cache invalidation: %w", err)
+	}
+	ctx, cancel := c.remote.context()
+	defer cancel()
+	if err := c.remote.client.Publish(ctx, c.channel, payload).Err(); err != nil {
+		return fmt.Errorf("publish cache invalidation: %w", err)
+	}
+	return nil
+}
+
+func (c *NearCache) EvictListener() <-chan lru.EvictedItem[string, []byte] {
+	return c.remote.EvictListener()
+}
+
+func (c *NearCache) Store(key string, value []byte) error {
+	return c.StoreWithTTL(key, value, c.remote.defaultTTL)
+}
+
+// StoreWithTTL writes item to remote cache and local copy, then invalidates copies of other instances.
+func (c *NearCache) StoreWithTTL(key string, value []byte, ttl time.Duration) error {
+	generation := c.currentGeneration()
+	if err := c.remote.StoreWithTTL(key, value, ttl); err != nil {
+		return err
+	}
+	c.fill(key, value, ttl, generation)
+	return c.publish(invalidation{Key: key})
+}
+
+func (c *NearCache) Get(key string) (value []byte, exist bool) {
+	value, _, exist = c.GetWithMeta(key)
+	return value, exist
+}
+
+// GetWithMeta returns local copy if any, TTL of local copy may be shorter than TTL of remote item.
+func (c *NearCache) GetWithMeta(key string) (value []byte, meta lru.ItemMeta, exist bool) {
+	if value, meta, exist = c.local.GetWithMeta(key); exist {
+		return value, meta, true
+	}
+	generation := c.currentGeneration()
+	if value, meta, exist = c.remote.GetWithMeta(key); !exist {
+		return nil, meta, false
+	}
+	c.fill(key, value, meta.TTL, generation)
+	return value, meta, true
+}
+
+func (c *NearCache) Delete(key string) {
+	c.remote.Delete(key)
+	c.invalidate(invalidation{Key: key})
+	if err := c.publish(invalidation{Key: key}); err != nil {
+		c.remote.onError(err)
+	}
+}
+
+func (c *NearCache) Purge() {
+	c.remote.Purge()
+	c.invalidate(invalidation{Purge: true})
+	if err := c.publish(invalidation{Purge: true}); err != nil {
+		c.remote.onError(err)
+	}
+}
+
+// Resize changes capacity of local cache.
+func (c *NearCache) Resize(size int64) error {
+	return c.local.Resize(size)
+}
+
+// GetMaxSize returns capacity of local cache.
+func (c *NearCache) GetMaxSize() int64 {
+	return c.local.GetMaxSize()
+}
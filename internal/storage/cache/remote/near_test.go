@@ -0,0 +1,79 @@
+package remote_test
+
+import (
+	"context"
+	"go_project_template/internal/storage/cache/lru"
+	"go_project_template/internal/storage/cache/remote"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func newNearCache(t *testing.T, server *miniredis.Miniredis) *remote.NearCache {
+	local, err := lru.New[string, []byte](1024, lru.BytesSizer)
+	require.NoError(t, err)
+	cache, err := remote.NewNearCache(context.Background(), local, remote.NewCache(newClient(t, server), "near:"), "invalidations")
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, cache.Close()) })
+	return cache
+}
+
+func TestNearCache(t *testing.T) {
+	t.Run("should serve items from local copy", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		cache := newNearCache(t, server)
+		require.NoError(t, cache.Store("a", []byte("1")))
+
+		// local copy is used even if remote item is gone
+		server.Del("near:a")
+		value, ok := cache.Get("a")
+		require.True(t, ok)
+		require.Equal(t, []byte("1"), value)
+	})
+	t.Run("should fill local copy on remote hit", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		first, second := newNearCache(t, server), newNearCache(t, server)
+		require.NoError(t, first.StoreWithTTL("a", []byte("1"), time.Hour))
+
+		value, ok := second.Get("a")
+		require.True(t, ok)
+		require.Equal(t, []byte("1"), value)
+		// invalidation published by the first store may drop the copy, the next read fills it again
+		var meta lru.ItemMeta
+		require.Eventually(t, func() bool {
+			second.Get("a")
+			_, meta, ok = second.Local().GetWithMeta("a")
+			return ok
+		}, time.Second, 10*time.Millisecond)
+		require.LessOrEqual(t, meta.TTL, remote.DefaultLocalTTL)
+	})
+	t.Run("should invalidate local copies of other instances", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		first, second := newNearCache(t, server), newNearCache(t, server)
+		require.NoError(t, first.Store("a", []byte("1")))
+		_, ok := second.Get("a")
+		require.True(t, ok)
+
+		require.NoError(t, first.Store("a", []byte("2")))
+		require.Eventually(t, func() bool {
+			value, ok := second.Get("a")
+			return ok && string(value) == "2"
+		}, time.Second, 10*time.Millisecond)
+
+		first.Delete("a")
+		require.Eventually(t, func() bool {
+			_, ok := second.Get("a")
+			return !ok
+		}, time.Second, 10*time.Millisecond)
+
+		require.NoError(t, first.Store("b", []byte("1")))
+		_, ok = second.Get("b")
+		require.True(t, ok)
+		second.Purge()
+		require.Eventually(t, func() bool {
+			return first.Local().Len() == 0
+		}, time.Second, 10*time.Millisecond)
+	})
+}
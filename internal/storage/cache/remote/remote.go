@@ -0,0 +1,226 @@
+package remote
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"go_project_template/internal/storage/cache/lru"
+	appUtils "go_project_template/internal/utils"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	DefaultTimeout = time.Second
+
+	purgeBatch = 500
+	// headerSize is creation time stored before value, so GetWithMeta can report item age
+	headerSize = 8
+)
+
+var ErrResizeNotSupported = errors.New("remote cache size is configured on the server")
+
+var _ lru.Cacher[string, []byte] = (*Cache)(nil)
+
+// Cache keeps items in a Redis-protocol server shared by all instances of the service.
+// Keys are prefixed, so several caches can share one database. Capacity and eviction of items are
+// managed by the server, Cacher methods have no context, so every command is limited by timeout.
+// Server errors are reported to WithOnError handler: Get treats them as misses, Delete and Purge skip them.
+type Cache struct {
+	client     redis.UniversalClient
+	prefix     string
+	timeout    time.Duration
+	defaultTTL time.Duration
+	onError    func(err error)
+	counters   appUtils.CacheCounters
+
+	evictMu      sync.Mutex
+	evictedItems chan lru.EvictedItem[string, []byte]
+}
+
+// NewCache creates cache storing items under prefix in database of client.
+func NewCache(client redis.UniversalClient, prefix string) *Cache {
+	return &Cache{
+		client:  client,
+		prefix:  prefix,
+		timeout: DefaultTimeout,
+		onError: func(error) {},
+	}
+}
+
+// WithDefaultTTL sets TTL applied by Store, zero means items never expire.
+func (c *Cache) WithDefaultTTL(ttl time.Duration) *Cache {
+	c.defaultTTL = ttl
+	return c
+}
+
+// WithTimeout limits time of every server command, default is DefaultTimeout.
+func (c *Cache) WithTimeout(timeout time.Duration) *Cache {
+	c.timeout = timeout
+	return c
+}
+
+// WithOnError sets handler of server errors which can't be returned, e.g. to log them.
+func (c *Cache) WithOnError(onError func(err error)) *Cache {
+	c.onError = onError
+	return c
+}
+
+func (c *Cache) key(key string) string {
+	return c.prefix + key
+}
+
+func (c *Cache) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.timeout)
+}
+
+// EvictListener reports items removed with Delete, items evicted or expired by the server are not reported.
+func (c *Cache) EvictListener() <-chan lru.EvictedItem[string, []byte] {
+	c.evictMu.Lock()
+	defer c.evictMu.Unlock()
+	if c.evictedItems == nil {
+		c.evictedItems = make(chan lru.EvictedItem[string, []byte], 1000)
+	}
+	return c.evictedItems
+}
+
+func (c *Cache) Store(key string, value []byte) error {
+	return c.StoreWithTTL(key, value, c.defaultTTL)
+}
+
+// StoreWithTTL stores item which expires after ttl, zero ttl means no expiration.
+func (c *Cache) StoreWithTTL(key string, value []byte, ttl time.Duration) error {
+	ctx, cancel := c.context()
+	defer cancel()
+	data := make([]byte, headerSize, headerSize+len(value))
+	binary.LittleEndian.PutUint64(data, uint64(time.Now().UnixNano()))
+	data = append(data, value...)
+	if err := c.client.Set(ctx, c.key(key), data, max(ttl, 0)).Err(); err != nil {
+		return fmt.Errorf("store remote cache item: %w", err)
+	}
+	return nil
+}
+
+func (c *Cache) Get(key string) (value []byte, exist bool) {
+	value, _, exist = c.GetWithMeta(key)
+	return value, exist
+}
+
+// GetWithMeta returns item with its age and remaining TTL read in a single round trip.
+func (c *Cache) GetWithMeta(key string) (value []byte, meta lru.ItemMeta, exist bool) {
+	ctx, cancel := c.context()
+	defer cancel()
+	var get *redis.StringCmd
+	var ttl *redis.DurationCmd
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, c.key(key))
+		ttl = pipe.PTTL(ctx, c.key(key))
+		return nil
+	})
+	if errors.Is(err, redis.Nil) {
+		c.counters.Miss()
+		return nil, meta, false
+	}
+	if err != nil {
+		c.counters.Miss()
+		c.onError(fmt.Errorf("get remote cache item: %w", err))
+		return nil, meta, false
+	}
+	data, _ := get.Bytes()
+	if len(data) < headerSize {
+		c.counters.Miss()
+		c.onError(fmt.Errorf("get remote cache item %q: value without header", key))
+		return nil, meta, false
+	}
+	c.counters.Hit()
+	createdNano := int64(binary.LittleEndian.Uint64(data)) //nolint:gosec // written from int64
+	meta.Age = time.Since(time.Unix(0, createdNano))
+	if remaining := ttl.Val(); remaining > 0 {
+		meta.TTL = remaining
+	}
+	return data[headerSize:], meta, true
+}
+
+// Delete removes item, EvictListener is notified with EvictReasonManual.
+func (c *Cache) Delete(key string) {
+	ctx, cancel := c.context()
+	defer cancel()
+	data, err := c.client.GetDel(ctx, c.key(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return
+	}
+	if err != nil {
+		c.onError(fmt.Errorf("delete remote cache item: %w", err))
+		return
+	}
+	c.counters.Evicted(lru.EvictReasonManual.String(), 1)
+	c.evictMu.Lock()
+	ch := c.evictedItems
+	c.evictMu.Unlock()
+	if ch != nil && len(data) >= headerSize {
+		ch <- lru.EvictedItem[string, []byte]{Key: key, Value: data[headerSize:], Reason: lru.EvictReasonManual}
+	}
+}
+
+// Purge removes all items with cache prefix, removed items are not reported to EvictListener.
+func (c *Cache) Purge() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*c.timeout)
+	defer cancel()
+	removed, err := c.purge(ctx)
+	c.counters.Evicted(lru.EvictReasonPurge.String(), removed)
+	if err != nil {
+		c.onError(fmt.Errorf("purge remote cache: %w", err))
+	}
+}
+
+func (c *Cache) purge(ctx context.Context) (int, error) {
+	removed := 0
+	iter := c.client.Scan(ctx, 0, c.prefix+"*", purgeBatch).Iterator()
+	batch := make([]string, 0, purgeBatch)
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) < purgeBatch {
+			continue
+		}
+		n, err := c.client.Unlink(ctx, batch...).Result()
+		if err != nil {
+			return removed, err
+		}
+		removed += int(n)
+		batch = batch[:0]
+	}
+	if err := iter.Err(); err != nil {
+		return removed, err
+	}
+	if len(batch) > 0 {
+		n, err := c.client.Unlink(ctx, batch...).Result()
+		if err != nil {
+			return removed, err
+		}
+		removed += int(n)
+	}
+	return removed, nil
+}
+
+// Resize always fails, memory limit and eviction policy of the server are set in its config.
+func (c *Cache) Resize(int64) error {
+	return ErrResizeNotSupported
+}
+
+// GetMaxSize returns zero, server capacity is not known to the client.
+func (c *Cache) GetMaxSize() int64 {
+	return 0
+}
+
+// Stats returns lookup counters of this instance, number of items is not tracked.
+func (c *Cache) Stats() appUtils.CacheStats {
+	return c.counters.Stats(0, 0)
+}
+
+// ObserveLoad records loading of a missing value, it is meant for code filling the cache on miss.
+func (c *Cache) ObserveLoad(duration time.Duration, err error) {
+	c.counters.ObserveLoad(duration, err)
+}
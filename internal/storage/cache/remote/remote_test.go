@@ -0,0 +1,90 @@
+package remote_test
+
+import (
+	"go_project_template/internal/storage/cache/lru"
+	"go_project_template/internal/storage/cache/remote"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func newClient(t *testing.T, server *miniredis.Miniredis) *redis.Client {
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestCache(t *testing.T) {
+	t.Run("should store and get items", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		cache := remote.NewCache(newClient(t, server), "users:")
+
+		require.NoError(t, cache.Store("a", []byte("1")))
+		require.NoError(t, cache.StoreWithTTL("b", []byte("2"), time.Minute))
+		require.True(t, server.Exists("users:a"))
+
+		value, meta, ok := cache.GetWithMeta("a")
+		require.True(t, ok)
+		require.Equal(t, []byte("1"), value)
+		require.Zero(t, meta.TTL)
+		_, meta, ok = cache.GetWithMeta("b")
+		require.True(t, ok)
+		require.Equal(t, time.Minute, meta.TTL)
+		_, ok = cache.Get("c")
+		require.False(t, ok)
+
+		stats := cache.Stats()
+		require.Equal(t, uint64(2), stats.Hits)
+		require.Equal(t, uint64(1), stats.Misses)
+	})
+	t.Run("should expire items with default TTL", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		cache := remote.NewCache(newClient(t, server), "").WithDefaultTTL(time.Second)
+		require.NoError(t, cache.Store("a", []byte("1")))
+		server.FastForward(2 * time.Second)
+		_, ok := cache.Get("a")
+		require.False(t, ok)
+	})
+	t.Run("should report deleted items", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		cache := remote.NewCache(newClient(t, server), "")
+		evicted := cache.EvictListener()
+		require.NoError(t, cache.Store("a", []byte("1")))
+		cache.Delete("a")
+		cache.Delete("missing")
+		require.Equal(t, lru.EvictedItem[string, []byte]{Key: "a", Value: []byte("1"), Reason: lru.EvictReasonManual}, <-evicted)
+		require.Empty(t, evicted)
+	})
+	t.Run("should purge only own prefix", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		client := newClient(t, server)
+		users := remote.NewCache(client, "users:")
+		orders := remote.NewCache(client, "orders:")
+		for _, key := range []string{"a", "b", "c"} {
+			require.NoError(t, users.Store(key, []byte(key)))
+			require.NoError(t, orders.Store(key, []byte(key)))
+		}
+		users.Purge()
+		_, ok := users.Get("a")
+		require.False(t, ok)
+		_, ok = orders.Get("a")
+		require.True(t, ok)
+		require.Equal(t, uint64(3), users.Stats().Evictions[lru.EvictReasonPurge.String()])
+		require.ErrorIs(t, users.Resize(10), remote.ErrResizeNotSupported)
+	})
+	t.Run("should report server errors", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		var errs []error
+		cache := remote.NewCache(newClient(t, server), "").
+			WithTimeout(100 * time.Millisecond).
+			WithOnError(func(err error) { errs = append(errs, err) })
+		server.Close()
+		require.Error(t, cache.Store("a", []byte("1")))
+		_, ok := cache.Get("a")
+		require.False(t, ok)
+		require.Len(t, errs, 1)
+	})
+}
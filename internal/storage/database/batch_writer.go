@@ -0,0 +1,249 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_project_template/internal/utils"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// PostgresMaxParams is the limit of bind parameters in one Postgres statement.
+	PostgresMaxParams = 65535
+
+	DefaultBatchSize     = 1000
+	DefaultFlushInterval = time.Second
+	DefaultWriteTries    = 3
+	DefaultWriteBackoff  = 100 * time.Millisecond
+)
+
+var ErrBatchWriterClosed = errors.New("batch writer is closed")
+
+// BatchWriter buffers entities and inserts them into table in batches, flushing when buffer reaches
+// batch size or every flush interval. Batches are chunked to stay under PostgresMaxParams,
+// batches of at least copy threshold rows are written with COPY FROM in a transaction.
+// Failed writes are retried, rows which still can't be written are passed to WithOnError handler.
+type BatchWriter[T any] struct {
+	db            DBConnector
+	table         string
+	mapper        func(entity T) map[string]any
+	buffer        *utils.RWSlice[T]
+	batchSize     int
+	flushInterval time.Duration
+	copyThreshold int // 0 disables COPY FROM
+	tries         int
+	backoff       time.Duration
+	onError       func(err error, rows []T)
+
+	flushMu   sync.Mutex // one flush at a time keeps rows in order of Add
+	flushCh   chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+
+	// mu is held for read by Add, so final flush of Close sees rows of every Add which returned nil
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewBatchWriter creates writer inserting entities into table, mapper returns column values of an entity
+// and must return the same columns for every entity.
+func NewBatchWriter[T any](db DBConnector, table string, mapper func(entity T) map[string]any) *BatchWriter[T] {
+	return &BatchWriter[T]{
+		db:            db,
+		table:         table,
+		mapper:        mapper,
+		buffer:        utils.NewRWSlice[T](),
+		batchSize:     DefaultBatchSize,
+		flushInterval: DefaultFlushInterval,
+		tries:         DefaultWriteTries,
+		backoff:       DefaultWriteBackoff,
+		onError:       func(error, []T) {},
+		flushCh:       make(chan struct{}, 1),
+		done:          make(chan struct{}),
+	}
+}
+
+// WithBatchSize sets number of buffered rows which triggers flush, default is DefaultBatchSize.
+func (w *BatchWriter[T]) WithBatchSize(size int) *BatchWriter[T] {
+	w.batchSize = max(1, size)
+	return w
+}
+
+// WithFlushInterval sets period of flushing buffer regardless of its size, default is DefaultFlushInterval.
+// Zero or negative interval disables periodic flushes, buffer is then flushed only when it reaches batch size.
+func (w *BatchWriter[T]) WithFlushInterval(interval time.Duration) *BatchWriter[T] {
+	w.flushInterval = interval
+	return w
+}
+
+// WithCopyThreshold makes batches of at least rows to be written with COPY FROM, zero disables it.
+func (w *BatchWriter[T]) WithCopyThreshold(rows int) *BatchWriter[T] {
+	w.copyThreshold = rows
+	return w
+}
+
+// WithRetries sets number of write attempts of a chunk and base of exponential backoff between them.
+func (w *BatchWriter[T]) WithRetries(tries int, backoff time.Duration) *BatchWriter[T] {
+	w.tries = max(1, tries)
+	w.backoff = backoff
+	return w
+}
+
+// WithOnError sets handler of rows which were not written, e.g. to log them or save elsewhere.
+func (w *BatchWriter[T]) WithOnError(onError func(err error, rows []T)) *BatchWriter[T] {
+	w.onError = onError
+	return w
+}
+
+// Start runs background flushing until Close, ctx bounds writes of background flushes.
+func (w *BatchWriter[T]) Start(ctx context.Context) *BatchWriter[T] {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		var tick <-chan time.Time // nil channel never fires, buffer is flushed by size only
+		if w.flushInterval > 0 {
+			ticker := time.NewTicker(w.flushInterval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for {
+			select {
+			case <-tick:
+			case <-w.flushCh:
+			case <-w.done:
+				return
+			}
+			// errors are reported to onError with the rows
+			_ = w.Flush(ctx)
+		}
+	}()
+	return w
+}
+
+// Add buffers entities, buffer reaching batch size is flushed in background.
+func (w *BatchWriter[T]) Add(entities ...T) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return ErrBatchWriterClosed
+	}
+	w.buffer.AddBulk(entities)
+	if w.buffer.Len() >= w.batchSize {
+		select {
+		case w.flushCh <- struct{}{}:
+		default:
+			// flush is already requested
+		}
+	}
+	return nil
+}
+
+// Pending returns number of buffered rows.
+func (w *BatchWriter[T]) Pending() int {
+	return w.buffer.Len()
+}
+
+// Flush writes all buffered rows, errors of failed chunks are joined.
+func (w *BatchWriter[T]) Flush(ctx context.Context) error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+	rows := w.buffer.LoadAndErase()
+	if len(rows) == 0 {
+		return nil
+	}
+	if w.copyThreshold > 0 && len(rows) >= w.copyThreshold {
+		return w.write(ctx, rows, w.copyRows)
+	}
+	columns := len(w.mapper(rows[0]))
+	chunkSize := min(w.batchSize, PostgresMaxParams/max(1, columns))
+	var errs []error
+	for _, chunk := range utils.ChunkSlice(rows, chunkSize) {
+		if err := w.write(ctx, chunk, w.insertRows); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close stops background flushing and writes remaining rows within ctx.
+func (w *BatchWriter[T]) Close(ctx context.Context) error {
+	w.closeOnce.Do(func() {
+		w.mu.Lock()
+		w.closed = true
+		w.mu.Unlock()
+		close(w.done)
+	})
+	w.wg.Wait()
+	return w.Flush(ctx)
+}
+
+// write runs writeFn with retries, rows which were not written are reported to onError.
+func (w *BatchWriter[T]) write(ctx context.Context, rows []T, writeFn func(ctx context.Context, rows []T) (int64, error)) error {
+	_, err := utils.NewCtxFuncRepeater(func(ctx context.Context) (int64, error) {
+		return writeFn(ctx, rows)
+	}).
+		WithCtx(ctx).
+		WithMaxTries(w.tries).
+		WithExponentialBackoff(w.backoff).
+		WithErrMsg(fmt.Sprintf("write batch to %s", w.table)).
+		Run()
+	if err != nil {
+		err = fmt.Errorf("write %d rows to %s: %w", len(rows), w.table, err)
+		w.onError(err, rows)
+	}
+	return err
+}
+
+func (w *BatchWriter[T]) insertRows(ctx context.Context, rows []T) (int64, error) {
+	query, params := utils.GenerateBulkInsertSQL(w.table, utils.PQParamPlaceholder, rows, w.mapper)
+	res, err := w.db.Client().ExecContext(ctx, query, params...)
+	if err != nil {
+		return 0, fmt.Errorf("insert rows: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// copyRows streams rows with COPY FROM, the whole batch is written in one transaction.
+func (w *BatchWriter[T]) copyRows(ctx context.Context, rows []T) (int64, error) {
+	columns := make([]string, 0, 8)
+	for column := range w.mapper(rows[0]) {
+		columns = append(columns, column)
+	}
+	tx, err := w.db.Client().BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin copy: %w", err)
+	}
+	defer func() {
+		// no-op after commit
+		_ = tx.Rollback()
+	}()
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(w.table, columns...))
+	if err != nil {
+		return 0, fmt.Errorf("prepare copy: %w", err)
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+	values := make([]any, len(columns))
+	for _, row := range rows {
+		mapping := w.mapper(row)
+		for i, column := range columns {
+			values[i] = mapping[column]
+		}
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			return 0, fmt.Errorf("copy row: %w", err)
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return 0, fmt.Errorf("finish copy: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit copy: %w", err)
+	}
+	return int64(len(rows)), nil
+}
@@ -0,0 +1,147 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_project_template/internal/storage/database"
+	testhelpers "go_project_template/internal/test_helpers"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+type event struct {
+	ID   int
+	Name string
+}
+
+func eventColumns(e event) map[string]any {
+	return map[string]any{"id": e.ID, "name": e.Name}
+}
+
+func newMockWriter(t *testing.T) (*database.BatchWriter[event], sqlmock.Sqlmock) {
+	db, mock := testhelpers.NewMockDB(t)
+	writer := database.NewBatchWriter(db, "events", eventColumns).
+		WithRetries(2, time.Millisecond)
+	return writer, mock
+}
+
+func TestBatchWriter(t *testing.T) {
+	t.Run("should flush when batch size is reached", func(t *testing.T) {
+		writer, mock := newMockWriter(t)
+		writer.WithBatchSize(2).WithFlushInterval(time.Hour).Start(context.Background())
+		mock.ExpectExec(`INSERT INTO events \(\w+,\w+\) VALUES \(\$1,\$2\),\(\$3,\$4\)$`).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		require.NoError(t, writer.Add(event{ID: 1}, event{ID: 2}))
+		require.Eventually(t, func() bool {
+			return mock.ExpectationsWereMet() == nil
+		}, time.Second, 10*time.Millisecond)
+		require.NoError(t, writer.Close(context.Background()))
+	})
+	t.Run("should flush remaining rows on close", func(t *testing.T) {
+		writer, mock := newMockWriter(t)
+		writer.WithFlushInterval(time.Hour).Start(context.Background())
+		mock.ExpectExec(`INSERT INTO events .+ VALUES \(\$1,\$2\)$`).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, writer.Add(event{ID: 1}))
+		require.Equal(t, 1, writer.Pending())
+		require.NoError(t, writer.Close(context.Background()))
+		require.NoError(t, mock.ExpectationsWereMet())
+		require.ErrorIs(t, writer.Add(event{ID: 2}), database.ErrBatchWriterClosed)
+	})
+	t.Run("should flush by size only without interval", func(t *testing.T) {
+		writer, mock := newMockWriter(t)
+		writer.WithBatchSize(2).WithFlushInterval(0).Start(context.Background())
+		mock.ExpectExec(`INSERT INTO events .+ VALUES \(\$1,\$2\),\(\$3,\$4\)$`).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		require.NoError(t, writer.Add(event{ID: 1}))
+		time.Sleep(20 * time.Millisecond)
+		require.Equal(t, 1, writer.Pending())
+		require.NoError(t, writer.Add(event{ID: 2}))
+		require.Eventually(t, func() bool {
+			return mock.ExpectationsWereMet() == nil
+		}, time.Second, 10*time.Millisecond)
+		require.NoError(t, writer.Close(context.Background()))
+	})
+	t.Run("should not lose rows added concurrently with close", func(t *testing.T) {
+		writer, _ := newMockWriter(t)
+		// no write is expected by mock, so every flushed row is reported
+		var added, reported atomic.Int32
+		writer.WithFlushInterval(time.Hour).
+			WithOnError(func(_ error, rows []event) { reported.Add(int32(len(rows))) }).
+			Start(context.Background())
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for writer.Add(event{ID: i}) == nil {
+					added.Add(1)
+				}
+			}()
+		}
+		time.Sleep(10 * time.Millisecond)
+		_ = writer.Close(context.Background()) // fails as no write is expected
+		wg.Wait()
+		require.Zero(t, writer.Pending())
+		require.Equal(t, added.Load(), reported.Load())
+	})
+	t.Run("should chunk rows under parameters limit", func(t *testing.T) {
+		db, mock := testhelpers.NewMockDB(t)
+		const columns = 20000 // 3 rows per statement
+		writer := database.NewBatchWriter(db, "wide", func(row int) map[string]any {
+			mapping := make(map[string]any, columns)
+			for i := 0; i < columns; i++ {
+				mapping[fmt.Sprintf("c%d", i)] = row
+			}
+			return mapping
+		})
+		mock.ExpectExec(`\$60000\)$`).WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec(`\$40000\)$`).WillReturnResult(sqlmock.NewResult(0, 2))
+
+		require.NoError(t, writer.Add(1, 2, 3, 4, 5))
+		require.NoError(t, writer.Flush(context.Background()))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("should retry and report rows which were not written", func(t *testing.T) {
+		writer, mock := newMockWriter(t)
+		var failed []event
+		writer.WithBatchSize(1).WithOnError(func(_ error, rows []event) {
+			failed = append(failed, rows...)
+		})
+		errDB := errors.New("connection reset")
+		mock.ExpectExec(`INSERT INTO events`).WillReturnError(errDB)
+		mock.ExpectExec(`INSERT INTO events`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO events`).WillReturnError(errDB)
+		mock.ExpectExec(`INSERT INTO events`).WillReturnError(errDB)
+
+		require.NoError(t, writer.Add(event{ID: 1}, event{ID: 2}))
+		err := writer.Flush(context.Background())
+		require.ErrorIs(t, err, errDB)
+		require.Equal(t, []event{{ID: 2}}, failed)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("should copy large batches", func(t *testing.T) {
+		writer, mock := newMockWriter(t)
+		writer.WithCopyThreshold(3)
+		mock.ExpectBegin()
+		copyIn := mock.ExpectPrepare(`COPY "events" \("\w+", "\w+"\) FROM STDIN`)
+		for i := 0; i < 3; i++ {
+			copyIn.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+		}
+		copyIn.ExpectExec().WithArgs().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		require.NoError(t, writer.Add(event{ID: 1}, event{ID: 2}, event{ID: 3}))
+		require.NoError(t, writer.Flush(context.Background()))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -19,9 +19,13 @@ type DBConnect struct {
 	db *sqlx.DB
 }
 
+// DSN returns Postgres connection string of cnf.
+func DSN(cnf *config.DBConf) string {
+	return fmt.Sprintf("dbname=%s sslmode=disable user=%s password=%s host=%s port=%s connect_timeout=5", cnf.DBName, cnf.User, cnf.Pass, cnf.Address, cnf.Port)
+}
+
 func InitDBConnect(ctx context.Context, cnf *config.DBConf, migratesFolder string) (*DBConnect, error) {
-	dsnStr := fmt.Sprintf("dbname=%s sslmode=disable user=%s password=%s host=%s port=%s connect_timeout=5", cnf.DBName, cnf.User, cnf.Pass, cnf.Address, cnf.Port)
-	db, err := sqlx.Connect("postgres", dsnStr)
+	db, err := sqlx.Connect("postgres", DSN(cnf))
 	if err != nil {
 		return nil, fmt.Errorf("error connect to db: %w", err)
 	}
@@ -0,0 +1,260 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_project_template/internal/pagination"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const DefaultJobMaxAttempts = 5
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobState    = errors.New("job state doesn't allow this action")
+	// ErrJobPermanent wrapped by handler error moves the job to dead state without retries.
+	ErrJobPermanent = errors.New("permanent job error")
+)
+
+type JobStatus string
+
+const (
+	JobPending  JobStatus = "pending"
+	JobRunning  JobStatus = "running"
+	JobDone     JobStatus = "done"
+	JobDead     JobStatus = "dead"
+	JobCanceled JobStatus = "canceled"
+)
+
+// Job is a row of jobs table. Running job is leased by a worker until LockedUntil,
+// after that it is considered crashed and is leased again.
+type Job struct {
+	ID          int64           `db:"id" json:"id"`
+	Type        string          `db:"job_type" json:"type"`
+	Payload     json.RawMessage `db:"payload" json:"payload"`
+	Status      JobStatus       `db:"status" json:"status"`
+	Attempts    int             `db:"attempts" json:"attempts"`
+	MaxAttempts int             `db:"max_attempts" json:"max_attempts"`
+	RunAt       time.Time       `db:"run_at" json:"run_at"`
+	LockedUntil *time.Time      `db:"locked_until" json:"locked_until,omitempty"`
+	LastError   *string         `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt   time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time       `db:"updated_at" json:"updated_at"`
+}
+
+const jobColumns = `id, job_type, payload, status, attempts, max_attempts, run_at, locked_until, last_error, created_at, updated_at`
+
+type jobOptions struct {
+	runAt       time.Time
+	maxAttempts int
+}
+
+type JobOption func(*jobOptions)
+
+// WithRunAt schedules job to run not earlier than at.
+func WithRunAt(at time.Time) JobOption {
+	return func(o *jobOptions) {
+		o.runAt = at
+	}
+}
+
+// WithDelay schedules job to run after delay.
+func WithDelay(delay time.Duration) JobOption {
+	return func(o *jobOptions) {
+		o.runAt = time.Now().Add(delay)
+	}
+}
+
+// WithMaxAttempts sets number of attempts before job becomes dead, default is DefaultJobMaxAttempts.
+func WithMaxAttempts(attempts int) JobOption {
+	return func(o *jobOptions) {
+		o.maxAttempts = max(1, attempts)
+	}
+}
+
+// JobType binds job type name to its payload type, see HandleJobs.
+type JobType[T any] struct {
+	name string
+}
+
+func NewJobType[T any](name string) JobType[T] {
+	return JobType[T]{name: name}
+}
+
+func (t JobType[T]) Name() string {
+	return t.name
+}
+
+// Enqueue adds job of this type with payload, returns job id.
+func (t JobType[T]) Enqueue(ctx context.Context, queue *JobQueue, payload T, opts ...JobOption) (int64, error) {
+	return queue.Enqueue(ctx, t.name, payload, opts...)
+}
+
+// EnqueueTx adds job of this type within tx, so it runs only if tx is committed.
+func (t JobType[T]) EnqueueTx(ctx context.Context, queue *JobQueue, tx *sqlx.Tx, payload T, opts ...JobOption) (int64, error) {
+	return queue.EnqueueTx(ctx, tx, t.name, payload, opts...)
+}
+
+// JobQueue stores jobs in jobs table, jobs are run by JobWorker.
+type JobQueue struct {
+	db DBConnector
+}
+
+func NewJobQueue(db DBConnector) *JobQueue {
+	return &JobQueue{db: db}
+}
+
+// Enqueue adds job with payload encoded to JSON, returns job id.
+func (q *JobQueue) Enqueue(ctx context.Context, jobType string, payload any, opts ...JobOption) (int64, error) {
+	return q.enqueue(ctx, q.db.Client(), jobType, payload, opts)
+}
+
+// EnqueueTx adds job within tx, so it runs only if tx is committed.
+func (q *JobQueue) EnqueueTx(ctx context.Context, tx *sqlx.Tx, jobType string, payload any, opts ...JobOption) (int64, error) {
+	return q.enqueue(ctx, tx, jobType, payload, opts)
+}
+
+func (q *JobQueue) enqueue(ctx context.Context, db sqlx.QueryerContext, jobType string, payload any, opts []JobOption) (int64, error) {
+	options := jobOptions{maxAttempts: DefaultJobMaxAttempts}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("marshal %s job payload: %w", jobType, err)
+	}
+	var runAt any // database time by default
+	if !options.runAt.IsZero() {
+		runAt = options.runAt
+	}
+	var id int64
+	err = sqlx.GetContext(ctx, db, &id, `INSERT INTO jobs (job_type, payload, max_attempts, run_at)
+		VALUES ($1, $2, $3, COALESCE($4, now())) RETURNING id`, jobType, data, options.maxAttempts, runAt)
+	if err != nil {
+		return 0, fmt.Errorf("enqueue %s job: %w", jobType, err)
+	}
+	return id, nil
+}
+
+// Get returns job by id.
+func (q *JobQueue) Get(ctx context.Context, id int64) (*Job, error) {
+	var job Job
+	err := q.db.Client().GetContext(ctx, &job, `SELECT `+jobColumns+` FROM jobs WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get job: %w", err)
+	}
+	return &job, nil
+}
+
+var jobListSchema = pagination.NewSchema(pagination.Field[Job]{
+	Name: "id", Column: "id", Value: func(j Job) any { return j.ID }, Parse: pagination.ParseInt,
+}).
+	WithField(pagination.Field[Job]{Name: "type", Column: "job_type", Filterable: true}).
+	WithField(pagination.Field[Job]{Name: "status", Column: "status", Filterable: true}).
+	WithField(pagination.Field[Job]{
+		Name: "run_at", Column: "run_at", Value: func(j Job) any { return j.RunAt },
+		Parse: pagination.ParseTime, Sortable: true, Filterable: true,
+	}).
+	WithDefaultSort(pagination.SortField{Field: "id", Desc: true})
+
+// List returns page of jobs, newest first by default. Jobs can be filtered by id, type, status and run_at.
+func (q *JobQueue) List(ctx context.Context, query pagination.Query) (pagination.Page[Job], error) {
+	selectSQL, args, err := jobListSchema.Query(`SELECT `+jobColumns+` FROM jobs`, query)
+	if err != nil {
+		return pagination.Page[Job]{}, err
+	}
+	var jobs []Job
+	if err = q.db.Client().SelectContext(ctx, &jobs, selectSQL, args...); err != nil {
+		return pagination.Page[Job]{}, fmt.Errorf("list jobs: %w", err)
+	}
+	return jobListSchema.Page(jobs, query)
+}
+
+// Retry makes dead or canceled job pending again with a fresh attempts budget.
+func (q *JobQueue) Retry(ctx context.Context, id int64) error {
+	return q.transition(ctx, id, `UPDATE jobs SET status = 'pending', attempts = 0, run_at = now(), locked_until = NULL,
+		updated_at = now() WHERE id = $1 AND status IN ('dead', 'canceled')`)
+}
+
+// Cancel stops pending job from running. Running job is not interrupted, but its result is discarded.
+func (q *JobQueue) Cancel(ctx context.Context, id int64) error {
+	return q.transition(ctx, id, `UPDATE jobs SET status = 'canceled', locked_until = NULL, updated_at = now()
+		WHERE id = $1 AND status IN ('pending', 'running')`)
+}
+
+// transition runs update of job state, ErrJobState is returned if job exists but wasn't updated.
+func (q *JobQueue) transition(ctx context.Context, id int64, query string) error {
+	res, err := q.db.Client().ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("update job %d: %w", id, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("update job %d: %w", id, err)
+	}
+	if affected > 0 {
+		return nil
+	}
+	if _, err = q.Get(ctx, id); err != nil {
+		return err
+	}
+	return ErrJobState
+}
+
+// lease locks up to limit due jobs of types for visibility timeout. Running jobs with expired lease
+// belong to crashed workers and are leased again, unless they are out of attempts, then they are dead.
+func (q *JobQueue) lease(ctx context.Context, types []string, limit int, visibility time.Duration) ([]Job, error) {
+	_, err := q.db.Client().ExecContext(ctx, `UPDATE jobs SET status = 'dead', locked_until = NULL, updated_at = now(),
+		last_error = 'visibility timeout expired' WHERE job_type = ANY($1) AND status = 'running'
+		AND locked_until < now() AND attempts >= max_attempts`, pq.Array(types))
+	if err != nil {
+		return nil, fmt.Errorf("expire jobs: %w", err)
+	}
+	var jobs []Job
+	err = q.db.Client().SelectContext(ctx, &jobs, `UPDATE jobs SET status = 'running', attempts = attempts + 1,
+		locked_until = now() + make_interval(secs => $3), updated_at = now()
+		WHERE id IN (
+			SELECT id FROM jobs WHERE job_type = ANY($1)
+			AND ((status = 'pending' AND run_at <= now()) OR (status = 'running' AND locked_until < now()))
+			ORDER BY run_at LIMIT $2 FOR UPDATE SKIP LOCKED
+		) RETURNING `+jobColumns, pq.Array(types), limit, visibility.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("lease jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// complete marks job done, attempts fences out a worker whose lease expired and was taken by another one.
+func (q *JobQueue) complete(ctx context.Context, job Job) error {
+	_, err := q.db.Client().ExecContext(ctx, `UPDATE jobs SET status = 'done', locked_until = NULL, updated_at = now()
+		WHERE id = $1 AND attempts = $2 AND status = 'running'`, job.ID, job.Attempts)
+	if err != nil {
+		return fmt.Errorf("complete job %d: %w", job.ID, err)
+	}
+	return nil
+}
+
+// fail schedules job retry after delay or makes it dead if it is out of attempts or error is permanent.
+func (q *JobQueue) fail(ctx context.Context, job Job, jobErr error, delay time.Duration) error {
+	status := JobPending
+	if job.Attempts >= job.MaxAttempts || errors.Is(jobErr, ErrJobPermanent) {
+		status = JobDead
+	}
+	_, err := q.db.Client().ExecContext(ctx, `UPDATE jobs SET status = $3, last_error = $4, locked_until = NULL,
+		run_at = now() + make_interval(secs => $5), updated_at = now()
+		WHERE id = $1 AND attempts = $2 AND status = 'running'`,
+		job.ID, job.Attempts, status, jobErr.Error(), delay.Seconds())
+	if err != nil {
+		return fmt.Errorf("fail job %d: %w", job.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"go_project_template/internal/storage/database"
+	testhelpers "go_project_template/internal/test_helpers"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+type emailPayload struct {
+	To string `json:"to"`
+}
+
+var sendEmail = database.NewJobType[emailPayload]("send_email")
+
+func newMockQueue(t *testing.T) (*database.JobQueue, sqlmock.Sqlmock) {
+	db, mock := testhelpers.NewMockDB(t)
+	return database.NewJobQueue(db), mock
+}
+
+func expectLease(mock sqlmock.Sqlmock, payload string, attempts, maxAttempts int) {
+	mock.ExpectExec(`UPDATE jobs SET status = 'dead'`).WillReturnResult(sqlmock.NewResult(0, 0))
+	rows := sqlmock.NewRows([]string{
+		"id", "job_type", "payload", "status", "attempts", "max_attempts", "run_at", "locked_until", "last_error", "created_at", "updated_at",
+	})
+	if payload != "" {
+		now := time.Now()
+		rows.AddRow(1, sendEmail.Name(), []byte(payload), "running", attempts, maxAttempts, now, now.Add(time.Minute), nil, now, now)
+	}
+	mock.ExpectQuery(`UPDATE jobs SET status = 'running'.+FOR UPDATE SKIP LOCKED`).
+		WithArgs(sqlmock.AnyArg(), 1, 60.0).
+		WillReturnRows(rows)
+}
+
+func TestJobWorker(t *testing.T) {
+	t.Run("should run job with typed payload", func(t *testing.T) {
+		queue, mock := newMockQueue(t)
+		var sent []string
+		worker := database.NewJobWorker(queue).WithVisibilityTimeout(time.Minute)
+		database.HandleJobs(worker, sendEmail, func(_ context.Context, p emailPayload) error {
+			sent = append(sent, p.To)
+			return nil
+		})
+		expectLease(mock, `{"to":"a@example.com"}`, 1, 5)
+		mock.ExpectExec(`UPDATE jobs SET status = 'done'`).WithArgs(int64(1), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		ran, err := worker.RunOnce(context.Background())
+		require.NoError(t, err)
+		require.True(t, ran)
+		require.Equal(t, []string{"a@example.com"}, sent)
+
+		expectLease(mock, "", 0, 0)
+		ran, err = worker.RunOnce(context.Background())
+		require.NoError(t, err)
+		require.False(t, ran)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("should retry failed job with backoff", func(t *testing.T) {
+		queue, mock := newMockQueue(t)
+		var errs []error
+		worker := database.NewJobWorker(queue).
+			WithVisibilityTimeout(time.Minute).
+			WithRetryBackoff(time.Second, time.Minute).
+			WithOnError(func(err error) { errs = append(errs, err) })
+		database.HandleJobs(worker, sendEmail, func(context.Context, emailPayload) error {
+			return errors.New("smtp is down")
+		})
+		expectLease(mock, `{}`, 3, 5)
+		mock.ExpectExec(`UPDATE jobs SET status = \$3`).
+			WithArgs(int64(1), 3, database.JobPending, "smtp is down", 4.0).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err := worker.RunOnce(context.Background())
+		require.NoError(t, err)
+		require.Len(t, errs, 1)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("should move job to dead state", func(t *testing.T) {
+		queue, mock := newMockQueue(t)
+		worker := database.NewJobWorker(queue).WithVisibilityTimeout(time.Minute)
+		database.HandleJobs(worker, sendEmail, func(context.Context, emailPayload) error {
+			panic("unexpected")
+		})
+		// out of attempts
+		expectLease(mock, `{}`, 5, 5)
+		mock.ExpectExec(`UPDATE jobs SET status = \$3`).
+			WithArgs(int64(1), 5, database.JobDead, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		_, err := worker.RunOnce(context.Background())
+		require.NoError(t, err)
+
+		// payload can't be decoded
+		expectLease(mock, `[]`, 1, 5)
+		mock.ExpectExec(`UPDATE jobs SET status = \$3`).
+			WithArgs(int64(1), 1, database.JobDead, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		_, err = worker.RunOnce(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestJobQueue(t *testing.T) {
+	t.Run("should enqueue delayed job", func(t *testing.T) {
+		queue, mock := newMockQueue(t)
+		runAt := time.Now().Add(time.Hour)
+		mock.ExpectQuery(`INSERT INTO jobs`).
+			WithArgs(sendEmail.Name(), []byte(`{"to":"a@example.com"}`), 3, runAt).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+
+		id, err := sendEmail.Enqueue(context.Background(), queue, emailPayload{To: "a@example.com"},
+			database.WithRunAt(runAt), database.WithMaxAttempts(3))
+		require.NoError(t, err)
+		require.Equal(t, int64(42), id)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("should report missing job and wrong state", func(t *testing.T) {
+		queue, mock := newMockQueue(t)
+		mock.ExpectExec(`UPDATE jobs SET status = 'pending'`).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT .+ FROM jobs WHERE id = \$1`).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		require.ErrorIs(t, queue.Retry(context.Background(), 1), database.ErrJobNotFound)
+
+		mock.ExpectExec(`UPDATE jobs SET status = 'canceled'`).WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT .+ FROM jobs WHERE id = \$1`).WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(2, "done"))
+		require.ErrorIs(t, queue.Cancel(context.Background(), 2), database.ErrJobState)
+
+		mock.ExpectExec(`UPDATE jobs SET status = 'canceled'`).WithArgs(int64(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+		require.NoError(t, queue.Cancel(context.Background(), 3))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
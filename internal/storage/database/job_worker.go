@@ -0,0 +1,175 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go_project_template/internal/utils"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultJobPollInterval      = time.Second
+	DefaultJobVisibilityTimeout = 5 * time.Minute
+	DefaultJobRetryBase         = 10 * time.Second
+	DefaultJobRetryMax          = time.Hour
+)
+
+// JobHandler runs a job, returned error makes job retried or dead, see ErrJobPermanent.
+type JobHandler func(ctx context.Context, job Job) error
+
+// HandleJobs registers handler of jobs of type t with decoded payload.
+// Payload which can't be decoded makes job dead.
+func HandleJobs[T any](worker *JobWorker, t JobType[T], handler func(ctx context.Context, payload T) error) {
+	worker.Handle(t.Name(), func(ctx context.Context, job Job) error {
+		var payload T
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return fmt.Errorf("%w: decode %s payload: %w", ErrJobPermanent, t.Name(), err)
+		}
+		return handler(ctx, payload)
+	})
+}
+
+// JobWorker leases jobs of registered types from JobQueue and runs them with concurrency goroutines.
+// Job context is canceled when visibility timeout passes, after that the job may be leased by another worker.
+// Failed jobs are retried with exponential backoff. Handlers must be registered before Start.
+type JobWorker struct {
+	queue        *JobQueue
+	handlers     map[string]JobHandler
+	types        []string
+	concurrency  int
+	pollInterval time.Duration
+	visibility   time.Duration
+	retryBase    time.Duration
+	retryMax     time.Duration
+	onError      func(err error)
+
+	stopCh    chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func NewJobWorker(queue *JobQueue) *JobWorker {
+	return &JobWorker{
+		queue:        queue,
+		handlers:     make(map[string]JobHandler),
+		concurrency:  1,
+		pollInterval: DefaultJobPollInterval,
+		visibility:   DefaultJobVisibilityTimeout,
+		retryBase:    DefaultJobRetryBase,
+		retryMax:     DefaultJobRetryMax,
+		onError:      func(error) {},
+		stopCh:       make(chan struct{}),
+	}
+}
+
+// Handle registers handler of jobs of jobType, see HandleJobs for typed payloads.
+func (w *JobWorker) Handle(jobType string, handler JobHandler) *JobWorker {
+	if _, ok := w.handlers[jobType]; !ok {
+		w.types = append(w.types, jobType)
+	}
+	w.handlers[jobType] = handler
+	return w
+}
+
+// WithConcurrency sets number of jobs run at the same time.
+func (w *JobWorker) WithConcurrency(concurrency int) *JobWorker {
+	w.concurrency = max(1, concurrency)
+	return w
+}
+
+// WithPollInterval sets pause between polls when there are no due jobs.
+func (w *JobWorker) WithPollInterval(interval time.Duration) *JobWorker {
+	w.pollInterval = interval
+	return w
+}
+
+// WithVisibilityTimeout sets how long leased job is hidden from other workers, it limits job run time.
+func (w *JobWorker) WithVisibilityTimeout(timeout time.Duration) *JobWorker {
+	w.visibility = timeout
+	return w
+}
+
+// WithRetryBackoff sets delay before the first retry, doubled on every next attempt up to maxDelay.
+func (w *JobWorker) WithRetryBackoff(base, maxDelay time.Duration) *JobWorker {
+	w.retryBase, w.retryMax = base, maxDelay
+	return w
+}
+
+// WithOnError sets handler of job and queue errors, e.g. to log them. It is called from worker goroutines.
+func (w *JobWorker) WithOnError(onError func(err error)) *JobWorker {
+	w.onError = onError
+	return w
+}
+
+// Start runs workers until Stop, ctx is parent of job contexts. Worker without handlers doesn't poll.
+func (w *JobWorker) Start(ctx context.Context) *JobWorker {
+	if len(w.handlers) == 0 {
+		return w
+	}
+	w.wg.Add(w.concurrency)
+	for range w.concurrency {
+		go w.work(ctx)
+	}
+	return w
+}
+
+func (w *JobWorker) work(ctx context.Context) {
+	defer w.wg.Done()
+	utils.PollLoop(ctx, w.stopCh, w.pollInterval, func() bool {
+		ran, err := w.RunOnce(ctx)
+		if err != nil {
+			w.onError(err)
+			return false
+		}
+		return ran
+	})
+}
+
+// RunOnce leases and runs one due job, ran is false if there was none.
+func (w *JobWorker) RunOnce(ctx context.Context) (ran bool, err error) {
+	jobs, err := w.queue.lease(ctx, w.types, 1, w.visibility)
+	if err != nil || len(jobs) == 0 {
+		return false, err
+	}
+	job := jobs[0]
+	// job result is stored even if worker is shutting down
+	storeCtx := context.WithoutCancel(ctx)
+	if jobErr := w.run(ctx, job); jobErr != nil {
+		w.onError(fmt.Errorf("job %d %s attempt %d: %w", job.ID, job.Type, job.Attempts, jobErr))
+		return true, w.queue.fail(storeCtx, job, jobErr, utils.ExponentialBackoff(w.retryBase, w.retryMax, job.Attempts))
+	}
+	return true, w.queue.complete(storeCtx, job)
+}
+
+func (w *JobWorker) run(ctx context.Context, job Job) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, w.visibility)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return w.handlers[job.Type](ctx, job)
+}
+
+// Stop stops polling and waits for running jobs until ctx is done. Jobs which are not finished
+// by then are leased again after visibility timeout.
+func (w *JobWorker) Stop(ctx context.Context) error {
+	w.closeOnce.Do(func() {
+		close(w.stopCh)
+	})
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("stop job worker: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,229 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_project_template/internal/config"
+	"go_project_template/internal/utils"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	DefaultListenerMinReconnect = time.Second
+	DefaultListenerMaxReconnect = time.Minute
+	// NotifySubscriberBuffer is how many payloads a subscriber may lag behind before it misses them
+	NotifySubscriberBuffer = 64
+	// listenerPingInterval is how often idle connection is checked, notifications are lost while it is broken
+	listenerPingInterval = 90 * time.Second
+)
+
+var (
+	ErrChannelAlreadyListened = errors.New("channel is already listened")
+	ErrSubscribersMissed      = errors.New("subscribers missed notification")
+)
+
+// NotificationSource delivers notifications of LISTENed channels, it is implemented by pq.Listener
+// which reconnects and LISTENs again automatically, sending nil notification after reconnect.
+type NotificationSource interface {
+	Listen(channel string) error
+	Unlisten(channel string) error
+	NotificationChannel() <-chan *pq.Notification
+	Ping() error
+	Close() error
+}
+
+// NewPQListener creates pq.Listener connecting to cnf database, connection errors are passed to onError.
+func NewPQListener(cnf *config.DBConf, onError func(err error)) *pq.Listener {
+	return pq.NewListener(DSN(cnf), DefaultListenerMinReconnect, DefaultListenerMaxReconnect,
+		func(event pq.ListenerEventType, err error) {
+			if err != nil {
+				onError(fmt.Errorf("notify listener event %d: %w", event, err))
+			}
+		})
+}
+
+// RowChange is payload of notifications sent by notify_row_change trigger.
+type RowChange struct {
+	Table string `json:"table"`
+	Op    string `json:"op"`
+	ID    string `json:"id"`
+}
+
+// NotifyTriggerSQL returns statement creating trigger which notifies channel with RowChange on every
+// row change of table, key column is sent as id. It relies on notify_row_change function created by migrations.
+// Table may be schema qualified, names are quoted and arguments are escaped.
+func NotifyTriggerSQL(table, channel, keyColumn string) string {
+	trigger := table[strings.LastIndex(table, ".")+1:] + "_notify_change"
+	return fmt.Sprintf(`create trigger %s
+    after insert or update or delete
+    on %s
+    for each row
+execute function notify_row_change(%s, %s);`,
+		utils.DialectPostgres.QuoteIdentifier(trigger), utils.DialectPostgres.QuoteIdentifier(table),
+		pq.QuoteLiteral(channel), pq.QuoteLiteral(keyColumn))
+}
+
+// NotifyListener dispatches Postgres NOTIFY payloads of listened channels to NotifyChannel subscribers.
+// Notifications sent while connection is lost are missed, WithOnReconnect handler should resync state,
+// e.g. purge caches invalidated by notifications.
+type NotifyListener struct {
+	source      NotificationSource
+	onReconnect func()
+	onError     func(err error)
+
+	mu       sync.RWMutex
+	handlers map[string]func(payload string)
+
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+// NewNotifyListener creates listener of source, usually created with NewPQListener.
+func NewNotifyListener(source NotificationSource) *NotifyListener {
+	return &NotifyListener{
+		source:      source,
+		onReconnect: func() {},
+		onError:     func(error) {},
+		handlers:    make(map[string]func(payload string)),
+		cancel:      func() {},
+	}
+}
+
+// WithOnReconnect sets handler called after connection is restored and channels are listened again.
+func (l *NotifyListener) WithOnReconnect(onReconnect func()) *NotifyListener {
+	l.onReconnect = onReconnect
+	return l
+}
+
+// WithOnError sets handler of payload decoding and connection check errors, e.g. to log them.
+func (l *NotifyListener) WithOnError(onError func(err error)) *NotifyListener {
+	l.onError = onError
+	return l
+}
+
+// Start dispatches notifications until Close or ctx is done.
+func (l *NotifyListener) Start(ctx context.Context) *NotifyListener {
+	ctx, l.cancel = context.WithCancel(ctx)
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		ticker := time.NewTicker(listenerPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case n, ok := <-l.source.NotificationChannel():
+				if !ok {
+					return
+				}
+				l.dispatch(n)
+			case <-ticker.C:
+				if err := l.source.Ping(); err != nil {
+					l.onError(fmt.Errorf("ping notify listener: %w", err))
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return l
+}
+
+func (l *NotifyListener) dispatch(n *pq.Notification) {
+	if n == nil {
+		l.onReconnect()
+		return
+	}
+	l.mu.RLock()
+	handler, ok := l.handlers[n.Channel]
+	l.mu.RUnlock()
+	if ok {
+		handler(n.Extra)
+	}
+}
+
+// listen LISTENs channel and registers its payload handler.
+func (l *NotifyListener) listen(channel string, handler func(payload string)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.handlers[channel]; ok {
+		return fmt.Errorf("%w: %s", ErrChannelAlreadyListened, channel)
+	}
+	if err := l.source.Listen(channel); err != nil {
+		return fmt.Errorf("listen %s: %w", channel, err)
+	}
+	l.handlers[channel] = handler
+	return nil
+}
+
+func (l *NotifyListener) unlisten(channel string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.handlers, channel)
+	if err := l.source.Unlisten(channel); err != nil {
+		return fmt.Errorf("unlisten %s: %w", channel, err)
+	}
+	return nil
+}
+
+// Close stops dispatching and closes source connection.
+func (l *NotifyListener) Close() error {
+	l.cancel()
+	l.wg.Wait()
+	if err := l.source.Close(); err != nil {
+		return fmt.Errorf("close notify listener: %w", err)
+	}
+	return nil
+}
+
+// NotifyChannel fans out JSON payloads of one channel decoded to T to in-process subscribers.
+// Dispatching doesn't wait for subscribers, a subscriber lagging more than NotifySubscriberBuffer
+// payloads misses them and it is reported to onError of the listener.
+type NotifyChannel[T any] struct {
+	name        string
+	listener    *NotifyListener
+	broadcaster *utils.Broadcaster[T]
+}
+
+// ListenJSON LISTENs channel of listener, payloads which can't be decoded to T are reported to its onError.
+func ListenJSON[T any](listener *NotifyListener, channel string) (*NotifyChannel[T], error) {
+	c := &NotifyChannel[T]{
+		name:        channel,
+		listener:    listener,
+		broadcaster: utils.NewBroadcaster[T](),
+	}
+	err := listener.listen(channel, func(payload string) {
+		var value T
+		if err := json.Unmarshal([]byte(payload), &value); err != nil {
+			listener.onError(fmt.Errorf("decode %s notification: %w", channel, err))
+			return
+		}
+		if missed := c.broadcaster.TryBroadcast(value); len(missed) > 0 {
+			listener.onError(fmt.Errorf("%w of %s: %s", ErrSubscribersMissed, channel, strings.Join(missed, ", ")))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Subscribe returns channel of payloads for subscriber key, it is closed by Unsubscribe.
+func (c *NotifyChannel[T]) Subscribe(key string) <-chan T {
+	return c.broadcaster.RegisterBufferedListener(key, NotifySubscriberBuffer)
+}
+
+// Unsubscribe closes channel of subscriber key.
+func (c *NotifyChannel[T]) Unsubscribe(key string) {
+	c.broadcaster.UnregisterListener(key)
+}
+
+// Close UNLISTENs the channel, subscribers should unsubscribe before.
+func (c *NotifyChannel[T]) Close() error {
+	return c.listener.unlisten(c.name)
+}
@@ -0,0 +1,118 @@
+package database_test
+
+import (
+	"context"
+	"go_project_template/internal/repository"
+	"go_project_template/internal/storage/database"
+	testhelpers "go_project_template/internal/test_helpers"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSource struct {
+	mu       sync.Mutex
+	channels []string
+	ch       chan *pq.Notification
+}
+
+func (s *fakeSource) Listen(channel string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.channels = append(s.channels, channel)
+	return nil
+}
+
+func (s *fakeSource) Unlisten(string) error {
+	return nil
+}
+
+func (s *fakeSource) NotificationChannel() <-chan *pq.Notification {
+	return s.ch
+}
+
+func (s *fakeSource) Ping() error {
+	return nil
+}
+
+func (s *fakeSource) Close() error {
+	return nil
+}
+
+func TestNotifyListener(t *testing.T) {
+	source := &fakeSource{ch: make(chan *pq.Notification)}
+	reconnected := make(chan struct{}, 1)
+	errs := make(chan error, 1)
+	listener := database.NewNotifyListener(source).
+		WithOnReconnect(func() { reconnected <- struct{}{} }).
+		WithOnError(func(err error) { errs <- err }).
+		Start(context.Background())
+	defer func() { require.NoError(t, listener.Close()) }()
+
+	changes, err := database.ListenJSON[database.RowChange](listener, "users_changes")
+	require.NoError(t, err)
+	_, err = database.ListenJSON[database.RowChange](listener, "users_changes")
+	require.ErrorIs(t, err, database.ErrChannelAlreadyListened)
+	require.Equal(t, []string{"users_changes"}, source.channels)
+
+	first, second := changes.Subscribe("first"), changes.Subscribe("second")
+	source.ch <- &pq.Notification{Channel: "users_changes", Extra: `{"table":"users","op":"insert","id":"1"}`}
+	expected := database.RowChange{Table: "users", Op: "insert", ID: "1"}
+	require.Equal(t, expected, <-first)
+	require.Equal(t, expected, <-second)
+	changes.Unsubscribe("second")
+
+	// other channels and broken payloads are not delivered
+	source.ch <- &pq.Notification{Channel: "orders_changes", Extra: `{}`}
+	source.ch <- &pq.Notification{Channel: "users_changes", Extra: `not json`}
+	require.ErrorContains(t, <-errs, "decode users_changes notification")
+
+	source.ch <- nil
+	<-reconnected
+	require.Empty(t, first)
+
+	// slow subscriber misses payloads beyond its buffer without blocking dispatch
+	for i := 0; i <= database.NotifySubscriberBuffer; i++ {
+		source.ch <- &pq.Notification{Channel: "users_changes", Extra: `{"table":"users","op":"update","id":"1"}`}
+	}
+	require.ErrorIs(t, <-errs, database.ErrSubscribersMissed)
+	require.Len(t, first, database.NotifySubscriberBuffer)
+	changes.Unsubscribe("first")
+	require.NoError(t, changes.Close())
+}
+
+func TestNotifyTriggerSQL(t *testing.T) {
+	migration, err := os.ReadFile("../../../migrations/000004_notify_triggers.up.sql")
+	require.NoError(t, err)
+	trigger := database.NotifyTriggerSQL("users", repository.UsersChangesChannel, "u_id")
+	require.True(t, strings.Contains(string(migration), trigger), "migration doesn't match:\n%s", trigger)
+
+	trigger = database.NotifyTriggerSQL(`app.o"rders`, "it's", "id")
+	require.Contains(t, trigger, `create trigger "o""rders_notify_change"`)
+	require.Contains(t, trigger, `on "app"."o""rders"`)
+	require.Contains(t, trigger, `notify_row_change('it''s', 'id')`)
+}
+
+func TestNotifyListenerWithPostgres(t *testing.T) {
+	container := testhelpers.GetClean(t)
+	listener := database.NewNotifyListener(database.NewPQListener(&container.Cfg.ConfigDB, func(err error) {
+		t.Log(err)
+	})).Start(container.Ctx)
+	defer func() { require.NoError(t, listener.Close()) }()
+	changes, err := database.ListenJSON[database.RowChange](listener, repository.UsersChangesChannel)
+	require.NoError(t, err)
+	received := changes.Subscribe("test")
+	defer changes.Unsubscribe("test")
+
+	user := repository.User{ID: uuid.New(), CreatedAt: time.Now(), Name: "notify"}
+	require.NoError(t, container.Repo.CreateUser(container.Ctx, &user))
+	require.Equal(t, database.RowChange{Table: "users", Op: "insert", ID: user.ID.String()}, <-received)
+	require.NoError(t, container.Repo.DeleteUser(container.Ctx, user.ID))
+	require.Equal(t, database.RowChange{Table: "users", Op: "delete", ID: user.ID.String()}, <-received)
+}
@@ -0,0 +1,27 @@
+package testhelpers
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+// MockDB is database.DBConnector backed by sqlmock, for unit tests of SQL which don't need real Postgres.
+type MockDB struct {
+	db *sqlx.DB
+}
+
+func (m MockDB) Client() *sqlx.DB {
+	return m.db
+}
+
+// NewMockDB creates connector with postgres driver name, it is closed on test cleanup.
+func NewMockDB(t *testing.T) (MockDB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return MockDB{db: sqlx.NewDb(db, "postgres")}, mock
+}
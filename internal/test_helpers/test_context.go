@@ -23,6 +23,7 @@ type TestContainer struct {
 	Cfg    *config.AppConfig
 	Logger logger.AppLogger
 
+	DB   database.DBConnector
 	Repo *repository.Repo
 
 	ServiceSampler *samplerService.Service
@@ -51,6 +52,7 @@ func GetClean(t *testing.T) *TestContainer {
 		Ctx:            ctx,
 		Cfg:            conf,
 		Logger:         appLog,
+		DB:             dbConnect,
 		Repo:           repo,
 		ServiceSampler: serviceSampler,
 	}
@@ -28,7 +28,8 @@ func NewTestServer(t *testing.T, container *TestContainer) *TestServer {
 	}
 
 	appLog := logger.NewAppSLogger()
-	appHTTPServer := routes.InitAppRouter(appLog, container.ServiceSampler, fmt.Sprintf(":%d", srv.appPort), false)
+	appHTTPServer := routes.InitAppRouter(appLog, container.ServiceSampler, fmt.Sprintf(":%d", srv.appPort), false).
+		WithAdminToken(srv.CreateToken(t, ""))
 	t.Cleanup(func() {
 		require.NoError(t, appHTTPServer.Stop())
 	})
@@ -15,9 +15,14 @@ func NewBroadcaster[T any]() *Broadcaster[T] {
 }
 
 func (b *Broadcaster[T]) RegisterListener(key string) chan T {
+	return b.RegisterBufferedListener(key, 0)
+}
+
+// RegisterBufferedListener registers listener with channel buffering size messages, for use with TryBroadcast.
+func (b *Broadcaster[T]) RegisterBufferedListener(key string, size int) chan T {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	b.messages[key] = make(chan T)
+	b.messages[key] = make(chan T, size)
 	b.activeListeners++
 	return b.messages[key]
 }
@@ -37,3 +42,17 @@ func (b *Broadcaster[T]) Broadcast(msg T) {
 		b.messages[i] <- msg
 	}
 }
+
+// TryBroadcast sends msg without waiting, listeners with full channel miss it and their keys are returned.
+func (b *Broadcaster[T]) TryBroadcast(msg T) (missed []string) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for key, ch := range b.messages {
+		select {
+		case ch <- msg:
+		default:
+			missed = append(missed, key)
+		}
+	}
+	return missed
+}
@@ -95,4 +95,14 @@ func TestBroadcasterBroadcast(t *testing.T) {
 		br := utils.NewBroadcaster[int]()
 		br.Broadcast(100)
 	})
+	t.Run("check try broadcast", func(t *testing.T) {
+		br := utils.NewBroadcaster[int]()
+		fast := br.RegisterBufferedListener("fast", 2)
+		slow := br.RegisterBufferedListener("slow", 1)
+		require.Empty(t, br.TryBroadcast(1))
+		require.Equal(t, []string{"slow"}, br.TryBroadcast(2), "full listener misses message without blocking")
+		require.Equal(t, 1, <-fast)
+		require.Equal(t, 2, <-fast)
+		require.Equal(t, 1, <-slow)
+	})
 }
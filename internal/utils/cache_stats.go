@@ -0,0 +1,199 @@
+package utils
+
+import (
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	EvictReasonExpired = "expired"
+	EvictReasonManual  = "manual"
+	EvictReasonSize    = "size" // evicted to make room for other items
+)
+
+// CacheStats is a point in time view of cache counters.
+type CacheStats struct {
+	Hits       uint64
+	Misses     uint64
+	Evictions  map[string]uint64 // by reason
+	Items      int64
+	Bytes      int64 // sum of item weights, zero if cache doesn't weight items
+	Loads      uint64
+	LoadErrors uint64
+	LoadTime   time.Duration // total time spent loading values on miss
+}
+
+// HitRatio returns share of hits in all lookups, zero if there were no lookups.
+func (s CacheStats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// Add sums counters of two caches, e.g. shards of one cache.
+func (s CacheStats) Add(other CacheStats) CacheStats {
+	res := CacheStats{
+		Hits:       s.Hits + other.Hits,
+		Misses:     s.Misses + other.Misses,
+		Evictions:  maps.Clone(s.Evictions),
+		Items:      s.Items + other.Items,
+		Bytes:      s.Bytes + other.Bytes,
+		Loads:      s.Loads + other.Loads,
+		LoadErrors: s.LoadErrors + other.LoadErrors,
+		LoadTime:   s.LoadTime + other.LoadTime,
+	}
+	if res.Evictions == nil {
+		res.Evictions = make(map[string]uint64, len(other.Evictions))
+	}
+	for reason, num := range other.Evictions {
+		res.Evictions[reason] += num
+	}
+	return res
+}
+
+// CacheCounters collects cache statistics, zero value is ready to use.
+type CacheCounters struct {
+	hits       atomic.Uint64
+	misses     atomic.Uint64
+	loads      atomic.Uint64
+	loadErrors atomic.Uint64
+	loadNanos  atomic.Int64
+
+	mu        sync.Mutex
+	evictions map[string]uint64
+}
+
+func (c *CacheCounters) Hit() {
+	c.hits.Add(1)
+}
+
+func (c *CacheCounters) Miss() {
+	c.misses.Add(1)
+}
+
+func (c *CacheCounters) Evicted(reason string, num int) {
+	if num <= 0 {
+		return
+	}
+	c.mu.Lock()
+	if c.evictions == nil {
+		c.evictions = make(map[string]uint64)
+	}
+	c.evictions[reason] += uint64(num)
+	c.mu.Unlock()
+}
+
+// ObserveLoad records loading of a missing value which took duration.
+func (c *CacheCounters) ObserveLoad(duration time.Duration, err error) {
+	c.loads.Add(1)
+	if err != nil {
+		c.loadErrors.Add(1)
+	}
+	c.loadNanos.Add(duration.Nanoseconds())
+}
+
+// Stats returns counters with current items and bytes of cache.
+func (c *CacheCounters) Stats(items, bytes int64) CacheStats {
+	c.mu.Lock()
+	evictions := maps.Clone(c.evictions)
+	c.mu.Unlock()
+	if evictions == nil {
+		evictions = make(map[string]uint64)
+	}
+	return CacheStats{
+		Hits:       c.hits.Load(),
+		Misses:     c.misses.Load(),
+		Evictions:  evictions,
+		Items:      items,
+		Bytes:      bytes,
+		Loads:      c.loads.Load(),
+		LoadErrors: c.loadErrors.Load(),
+		LoadTime:   time.Duration(c.loadNanos.Load()),
+	}
+}
+
+// StatsProvider is a cache reporting its statistics.
+type StatsProvider interface {
+	Stats() CacheStats
+}
+
+// CacheCollector exports statistics of named caches to prometheus, stats are read on every scrape.
+type CacheCollector struct {
+	hits       *prometheus.Desc
+	misses     *prometheus.Desc
+	evictions  *prometheus.Desc
+	items      *prometheus.Desc
+	bytes      *prometheus.Desc
+	loads      *prometheus.Desc
+	loadErrors *prometheus.Desc
+	loadTime   *prometheus.Desc
+
+	mu     sync.RWMutex
+	caches map[string]StatsProvider
+}
+
+func NewCacheCollector(namespace string) *CacheCollector {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", name), help, append([]string{"cache"}, labels...), nil)
+	}
+	return &CacheCollector{
+		hits:       desc("hits_total", "Number of cache lookups which found the item."),
+		misses:     desc("misses_total", "Number of cache lookups which didn't find the item."),
+		evictions:  desc("evictions_total", "Number of items removed from cache by reason.", "reason"),
+		items:      desc("items", "Number of items in cache."),
+		bytes:      desc("bytes", "Sum of item weights in cache."),
+		loads:      desc("loads_total", "Number of values loaded on miss."),
+		loadErrors: desc("load_errors_total", "Number of failed loads."),
+		loadTime:   desc("load_duration_seconds_total", "Total time spent loading values."),
+		caches:     make(map[string]StatsProvider),
+	}
+}
+
+// Register adds cache under name, cache registered with the same name is replaced.
+func (c *CacheCollector) Register(name string, cache StatsProvider) *CacheCollector {
+	c.mu.Lock()
+	c.caches[name] = cache
+	c.mu.Unlock()
+	return c
+}
+
+func (c *CacheCollector) Unregister(name string) {
+	c.mu.Lock()
+	delete(c.caches, name)
+	c.mu.Unlock()
+}
+
+// Collectors returns collectors to be registered in prometheus registry.
+func (c *CacheCollector) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{c}
+}
+
+func (c *CacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{c.hits, c.misses, c.evictions, c.items, c.bytes, c.loads, c.loadErrors, c.loadTime} {
+		ch <- desc
+	}
+}
+
+func (c *CacheCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for name, cache := range c.caches {
+		stats := cache.Stats()
+		ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits), name)
+		ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses), name)
+		for reason, num := range stats.Evictions {
+			ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(num), name, reason)
+		}
+		ch <- prometheus.MustNewConstMetric(c.items, prometheus.GaugeValue, float64(stats.Items), name)
+		ch <- prometheus.MustNewConstMetric(c.bytes, prometheus.GaugeValue, float64(stats.Bytes), name)
+		ch <- prometheus.MustNewConstMetric(c.loads, prometheus.CounterValue, float64(stats.Loads), name)
+		ch <- prometheus.MustNewConstMetric(c.loadErrors, prometheus.CounterValue, float64(stats.LoadErrors), name)
+		ch <- prometheus.MustNewConstMetric(c.loadTime, prometheus.CounterValue, stats.LoadTime.Seconds(), name)
+	}
+}
@@ -0,0 +1,68 @@
+package utils_test
+
+import (
+	"errors"
+	"go_project_template/internal/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTTLMap_Stats(t *testing.T) {
+	m := utils.NewTTLMap[string, int](20*time.Millisecond, time.Hour)
+	defer m.Close()
+	m.Put("a", 1)
+	m.Put("b", 2)
+	m.Get("a")
+	m.Do("a", func(int) {})
+	m.Get("missing")
+	m.Delete("b")
+	m.Delete("missing")
+	time.Sleep(30 * time.Millisecond)
+	m.Get("a")
+	m.ObserveLoad(time.Second, nil)
+	m.ObserveLoad(time.Second, errors.New("boom"))
+
+	stats := m.Stats()
+	require.Equal(t, uint64(2), stats.Hits)
+	require.Equal(t, uint64(2), stats.Misses)
+	require.InDelta(t, 0.5, stats.HitRatio(), 0.001)
+	require.Equal(t, map[string]uint64{utils.EvictReasonManual: 1, utils.EvictReasonExpired: 1}, stats.Evictions)
+	require.Zero(t, stats.Items)
+	require.Equal(t, uint64(2), stats.Loads)
+	require.Equal(t, uint64(1), stats.LoadErrors)
+	require.Equal(t, 2*time.Second, stats.LoadTime)
+}
+
+func TestCacheCollector(t *testing.T) {
+	m := utils.NewTTLMap[string, int](time.Hour, time.Hour)
+	defer m.Close()
+	m.Put("a", 1)
+	m.Get("a")
+	m.Get("b")
+	m.Delete("a")
+
+	collector := utils.NewCacheCollector("app").Register("sessions", m)
+	expected := `
+# HELP app_cache_evictions_total Number of items removed from cache by reason.
+# TYPE app_cache_evictions_total counter
+app_cache_evictions_total{cache="sessions",reason="manual"} 1
+# HELP app_cache_hits_total Number of cache lookups which found the item.
+# TYPE app_cache_hits_total counter
+app_cache_hits_total{cache="sessions"} 1
+# HELP app_cache_items Number of items in cache.
+# TYPE app_cache_items gauge
+app_cache_items{cache="sessions"} 0
+# HELP app_cache_misses_total Number of cache lookups which didn't find the item.
+# TYPE app_cache_misses_total counter
+app_cache_misses_total{cache="sessions"} 1
+`
+	require.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"app_cache_evictions_total", "app_cache_hits_total", "app_cache_items", "app_cache_misses_total"))
+
+	collector.Unregister("sessions")
+	require.Zero(t, testutil.CollectAndCount(collector))
+}
@@ -0,0 +1,352 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultBreakerCooldown      = 30 * time.Second
+	DefaultBreakerWindow        = time.Minute
+	DefaultBreakerWindowBuckets = 10
+)
+
+var (
+	ErrCircuitOpen   = errors.New("circuit breaker is open")
+	ErrTooManyProbes = errors.New("circuit breaker is half-open, too many probe requests")
+)
+
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half_open"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+type OnStateChangeFunc func(name string, from, to BreakerState)
+
+type breakerTransition struct {
+	from, to BreakerState
+}
+
+// breakerBucket holds results for a single slice of the rolling window.
+type breakerBucket struct {
+	startNano int64
+	successes int
+	failures  int
+}
+
+// CircuitBreaker stops calling dependency when it keeps failing.
+//   - closed: requests pass, results are counted in rolling window; breaker opens when
+//     consecutive failures or failure rate (after minRequests in window) reach thresholds
+//   - open: requests are rejected with ErrCircuitOpen until cooldown passes
+//   - half-open: up to halfOpenProbes concurrent requests pass, halfOpenSuccesses successes close
+//     the breaker, any failure opens it again
+type CircuitBreaker struct {
+	name                string
+	consecutiveFailures int
+	failureRate         float64
+	minRequests         int
+	window              time.Duration
+	cooldown            time.Duration
+	halfOpenProbes      int
+	halfOpenSuccesses   int
+	isFailure           func(err error) bool
+	onStateChange       []OnStateChangeFunc
+	metrics             *CircuitBreakerMetrics
+
+	mu             sync.Mutex
+	state          BreakerState
+	generation     uint64
+	openedAt       time.Time
+	consecutive    int
+	probesInFlight int
+	probeSuccesses int
+	buckets        []breakerBucket
+	bucketDuration int64
+	transitions    []breakerTransition // made under mu, hooks are run for them by unlock
+}
+
+// NewCircuitBreaker creates breaker which opens after 5 consecutive failures.
+func NewCircuitBreaker(name string) *CircuitBreaker {
+	cb := &CircuitBreaker{
+		name:                name,
+		consecutiveFailures: 5,
+		window:              DefaultBreakerWindow,
+		cooldown:            DefaultBreakerCooldown,
+		halfOpenProbes:      1,
+		halfOpenSuccesses:   1,
+		isFailure: func(err error) bool {
+			return err != nil
+		},
+	}
+	cb.resetWindow(DefaultBreakerWindowBuckets)
+	return cb
+}
+
+// WithConsecutiveFailures opens breaker after num failures in a row, 0 disables the check.
+func (cb *CircuitBreaker) WithConsecutiveFailures(num int) *CircuitBreaker {
+	cb.consecutiveFailures = num
+	return cb
+}
+
+// WithFailureRate opens breaker when failures/total in rolling window reaches rate (0..1),
+// but only when window holds at least minRequests results. rate 0 disables the check.
+func (cb *CircuitBreaker) WithFailureRate(rate float64, minRequests int) *CircuitBreaker {
+	cb.failureRate = rate
+	cb.minRequests = minRequests
+	return cb
+}
+
+// WithWindow sets rolling window size and amount of buckets it is split to.
+func (cb *CircuitBreaker) WithWindow(window time.Duration, buckets int) *CircuitBreaker {
+	cb.window = window
+	cb.resetWindow(buckets)
+	return cb
+}
+
+// WithCooldown sets how long breaker stays open before switching to half-open.
+func (cb *CircuitBreaker) WithCooldown(cooldown time.Duration) *CircuitBreaker {
+	cb.cooldown = cooldown
+	return cb
+}
+
+// WithHalfOpenProbes sets max concurrent requests in half-open state and successes required to close.
+func (cb *CircuitBreaker) WithHalfOpenProbes(maxProbes, successesToClose int) *CircuitBreaker {
+	cb.halfOpenProbes = maxProbes
+	cb.halfOpenSuccesses = successesToClose
+	return cb
+}
+
+// WithIsFailure defines which errors count as dependency failure, e.g. to ignore validation errors.
+func (cb *CircuitBreaker) WithIsFailure(isFailure func(err error) bool) *CircuitBreaker {
+	cb.isFailure = isFailure
+	return cb
+}
+
+// WithOnStateChange adds hooks called on every state switch. Hooks are called after breaker is unlocked,
+// so they may use the breaker, but hooks of concurrent switches may run concurrently.
+func (cb *CircuitBreaker) WithOnStateChange(hooks ...OnStateChangeFunc) *CircuitBreaker {
+	cb.onStateChange = append(cb.onStateChange, hooks...)
+	return cb
+}
+
+func (cb *CircuitBreaker) WithMetrics(metrics *CircuitBreakerMetrics) *CircuitBreaker {
+	cb.metrics = metrics
+	cb.metrics.state(cb.name, BreakerClosed)
+	return cb
+}
+
+func (cb *CircuitBreaker) Name() string {
+	return cb.name
+}
+
+// State returns current state, switching open breaker to half-open if cooldown passed.
+func (cb *CircuitBreaker) State() BreakerState {
+	cb.mu.Lock()
+	defer cb.unlock()
+	cb.refreshState(time.Now())
+	return cb.state
+}
+
+// Allow checks if request can be executed. If so, done must be called exactly once with request result.
+func (cb *CircuitBreaker) Allow() (done func(err error), err error) {
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	cb.refreshState(time.Now())
+	switch cb.state {
+	case BreakerOpen:
+		cb.metrics.request(cb.name, breakerResultRejected)
+		return nil, ErrCircuitOpen
+	case BreakerHalfOpen:
+		if cb.probesInFlight >= cb.halfOpenProbes {
+			cb.metrics.request(cb.name, breakerResultRejected)
+			return nil, ErrTooManyProbes
+		}
+		cb.probesInFlight++
+	}
+
+	generation := cb.generation
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() {
+			cb.onResult(generation, cb.isFailure(err))
+		})
+	}, nil
+}
+
+// Execute runs fn if breaker allows it and records its result.
+func (cb *CircuitBreaker) Execute(fn func() error) error {
+	done, err := cb.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	done(err)
+	return err
+}
+
+// ExecuteWithBreaker is a generic version of CircuitBreaker.Execute.
+func ExecuteWithBreaker[T any](cb *CircuitBreaker, fn func() (T, error)) (res T, err error) {
+	done, err := cb.Allow()
+	if err != nil {
+		return res, err
+	}
+	res, err = fn()
+	done(err)
+	return res, err
+}
+
+// Reset forces breaker into closed state and clears collected results.
+func (cb *CircuitBreaker) Reset() {
+	cb.mu.Lock()
+	defer cb.unlock()
+	cb.setState(BreakerClosed, time.Now())
+}
+
+func (cb *CircuitBreaker) onResult(generation uint64, failed bool) {
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	now := time.Now()
+	if generation != cb.generation {
+		// result belongs to previous state, e.g. slow request finished after breaker opened
+		return
+	}
+	if failed {
+		cb.metrics.request(cb.name, breakerResultFailure)
+	} else {
+		cb.metrics.request(cb.name, breakerResultSuccess)
+	}
+
+	switch cb.state {
+	case BreakerHalfOpen:
+		cb.probesInFlight--
+		if failed {
+			cb.setState(BreakerOpen, now)
+			return
+		}
+		cb.probeSuccesses++
+		if cb.probeSuccesses >= cb.halfOpenSuccesses {
+			cb.setState(BreakerClosed, now)
+		}
+	case BreakerClosed:
+		bucket := cb.currentBucket(now)
+		if !failed {
+			bucket.successes++
+			cb.consecutive = 0
+			return
+		}
+		bucket.failures++
+		cb.consecutive++
+		if cb.shouldOpen(now) {
+			cb.setState(BreakerOpen, now)
+		}
+	}
+}
+
+func (cb *CircuitBreaker) shouldOpen(now time.Time) bool {
+	if cb.consecutiveFailures > 0 && cb.consecutive >= cb.consecutiveFailures {
+		return true
+	}
+	if cb.failureRate <= 0 {
+		return false
+	}
+	successes, failures := cb.windowCounts(now)
+	total := successes + failures
+	if total == 0 || total < cb.minRequests {
+		return false
+	}
+	return float64(failures)/float64(total) >= cb.failureRate
+}
+
+func (cb *CircuitBreaker) refreshState(now time.Time) {
+	if cb.state == BreakerOpen && now.Sub(cb.openedAt) >= cb.cooldown {
+		cb.setState(BreakerHalfOpen, now)
+	}
+}
+
+// setState switches state and resets counters, listeners are notified by unlock. Must be called under lock.
+func (cb *CircuitBreaker) setState(state BreakerState, now time.Time) {
+	prev := cb.state
+	cb.state = state
+	cb.generation++
+	cb.consecutive = 0
+	cb.probesInFlight = 0
+	cb.probeSuccesses = 0
+	for i := range cb.buckets {
+		cb.buckets[i] = breakerBucket{}
+	}
+	if state == BreakerOpen {
+		cb.openedAt = now
+	}
+	if prev == state {
+		return
+	}
+	cb.metrics.transition(cb.name, prev, state)
+	if len(cb.onStateChange) > 0 {
+		cb.transitions = append(cb.transitions, breakerTransition{from: prev, to: state})
+	}
+}
+
+// unlock releases mu and then notifies listeners about transitions made under it.
+func (cb *CircuitBreaker) unlock() {
+	transitions := cb.transitions
+	cb.transitions = nil
+	cb.mu.Unlock()
+	for _, tr := range transitions {
+		for _, hook := range cb.onStateChange {
+			hook(cb.name, tr.from, tr.to)
+		}
+	}
+}
+
+func (cb *CircuitBreaker) resetWindow(buckets int) {
+	if buckets <= 0 {
+		buckets = 1
+	}
+	cb.buckets = make([]breakerBucket, buckets)
+	cb.bucketDuration = cb.window.Nanoseconds() / int64(buckets)
+	if cb.bucketDuration <= 0 {
+		cb.bucketDuration = 1
+	}
+}
+
+// currentBucket returns bucket for now, clearing it if it holds outdated results.
+func (cb *CircuitBreaker) currentBucket(now time.Time) *breakerBucket {
+	start := now.UnixNano() - now.UnixNano()%cb.bucketDuration
+	idx := int((start / cb.bucketDuration) % int64(len(cb.buckets)))
+	bucket := &cb.buckets[idx]
+	if bucket.startNano != start {
+		*bucket = breakerBucket{startNano: start}
+	}
+	return bucket
+}
+
+func (cb *CircuitBreaker) windowCounts(now time.Time) (successes, failures int) {
+	from := now.UnixNano() - cb.window.Nanoseconds()
+	for i := range cb.buckets {
+		if cb.buckets[i].startNano > from {
+			successes += cb.buckets[i].successes
+			failures += cb.buckets[i].failures
+		}
+	}
+	return successes, failures
+}
@@ -0,0 +1,67 @@
+package utils
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const (
+	breakerResultSuccess  = "success"
+	breakerResultFailure  = "failure"
+	breakerResultRejected = "rejected"
+)
+
+// CircuitBreakerMetrics holds prometheus collectors for CircuitBreaker.
+// One instance can be shared by many breakers, they are distinguished by name label.
+type CircuitBreakerMetrics struct {
+	states      *prometheus.GaugeVec
+	requests    *prometheus.CounterVec
+	transitions *prometheus.CounterVec
+}
+
+func NewCircuitBreakerMetrics(namespace string) *CircuitBreakerMetrics {
+	return &CircuitBreakerMetrics{
+		states: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "circuit_breaker",
+			Name:      "state",
+			Help:      "Current breaker state: 0 - closed, 1 - open, 2 - half-open.",
+		}, []string{"name"}),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "circuit_breaker",
+			Name:      "requests_total",
+			Help:      "Number of requests passed through breaker by result.",
+		}, []string{"name", "result"}),
+		transitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "circuit_breaker",
+			Name:      "transitions_total",
+			Help:      "Number of breaker state changes.",
+		}, []string{"name", "from", "to"}),
+	}
+}
+
+// Collectors returns collectors to be registered in prometheus registry.
+func (m *CircuitBreakerMetrics) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.states, m.requests, m.transitions}
+}
+
+func (m *CircuitBreakerMetrics) state(name string, state BreakerState) {
+	if m == nil {
+		return
+	}
+	m.states.WithLabelValues(name).Set(float64(state))
+}
+
+func (m *CircuitBreakerMetrics) request(name, result string) {
+	if m == nil {
+		return
+	}
+	m.requests.WithLabelValues(name, result).Inc()
+}
+
+func (m *CircuitBreakerMetrics) transition(name string, from, to BreakerState) {
+	if m == nil {
+		return
+	}
+	m.states.WithLabelValues(name).Set(float64(to))
+	m.transitions.WithLabelValues(name, from.String(), to.String()).Inc()
+}
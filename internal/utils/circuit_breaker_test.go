@@ -0,0 +1,170 @@
+package utils_test
+
+import (
+	"context"
+	"errors"
+	"go_project_template/internal/utils"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	errTest := errors.New("test error")
+	failing := func() error { return errTest }
+	passing := func() error { return nil }
+
+	t.Run("should open after consecutive failures", func(t *testing.T) {
+		cb := utils.NewCircuitBreaker("test").WithConsecutiveFailures(3).WithCooldown(time.Hour)
+		for i := 0; i < 2; i++ {
+			require.ErrorIs(t, cb.Execute(failing), errTest)
+		}
+		require.NoError(t, cb.Execute(passing), "success should reset consecutive counter")
+		for i := 0; i < 3; i++ {
+			require.ErrorIs(t, cb.Execute(failing), errTest)
+		}
+		require.Equal(t, utils.BreakerOpen, cb.State())
+		require.ErrorIs(t, cb.Execute(passing), utils.ErrCircuitOpen)
+	})
+	t.Run("should open by failure rate after min requests", func(t *testing.T) {
+		cb := utils.NewCircuitBreaker("test").
+			WithConsecutiveFailures(0).
+			WithFailureRate(0.5, 4).
+			WithCooldown(time.Hour)
+		require.ErrorIs(t, cb.Execute(failing), errTest)
+		require.NoError(t, cb.Execute(passing))
+		require.ErrorIs(t, cb.Execute(failing), errTest)
+		require.Equal(t, utils.BreakerClosed, cb.State(), "not enough requests in window")
+		require.NoError(t, cb.Execute(passing))
+		require.Equal(t, utils.BreakerClosed, cb.State(), "should be evaluated on failure only")
+		require.ErrorIs(t, cb.Execute(failing), errTest)
+		require.Equal(t, utils.BreakerOpen, cb.State())
+	})
+	t.Run("should forget failures outside of window", func(t *testing.T) {
+		cb := utils.NewCircuitBreaker("test").
+			WithConsecutiveFailures(0).
+			WithFailureRate(0.5, 2).
+			WithWindow(50*time.Millisecond, 5)
+		require.ErrorIs(t, cb.Execute(failing), errTest)
+		time.Sleep(60 * time.Millisecond)
+		require.NoError(t, cb.Execute(passing))
+		require.NoError(t, cb.Execute(passing))
+		require.ErrorIs(t, cb.Execute(failing), errTest)
+		require.Equal(t, utils.BreakerClosed, cb.State())
+	})
+	t.Run("should switch to half-open after cooldown and limit probes", func(t *testing.T) {
+		transitions := make([]string, 0, 3)
+		cb := utils.NewCircuitBreaker("test").
+			WithConsecutiveFailures(1).
+			WithCooldown(20*time.Millisecond).
+			WithHalfOpenProbes(1, 2).
+			WithOnStateChange(func(name string, from, to utils.BreakerState) {
+				require.Equal(t, "test", name)
+				transitions = append(transitions, from.String()+"->"+to.String())
+			})
+		require.ErrorIs(t, cb.Execute(failing), errTest)
+		require.ErrorIs(t, cb.Execute(passing), utils.ErrCircuitOpen)
+
+		time.Sleep(30 * time.Millisecond)
+		require.Equal(t, utils.BreakerHalfOpen, cb.State())
+		done, err := cb.Allow()
+		require.NoError(t, err)
+		_, err = cb.Allow()
+		require.ErrorIs(t, err, utils.ErrTooManyProbes)
+		done(nil)
+		require.Equal(t, utils.BreakerHalfOpen, cb.State(), "2 successes required to close")
+		require.NoError(t, cb.Execute(passing))
+		require.Equal(t, utils.BreakerClosed, cb.State())
+		require.Equal(t, []string{"closed->open", "open->half_open", "half_open->closed"}, transitions)
+	})
+	t.Run("should allow hooks to use breaker", func(t *testing.T) {
+		var states []utils.BreakerState
+		var cb *utils.CircuitBreaker
+		cb = utils.NewCircuitBreaker("test").
+			WithConsecutiveFailures(1).
+			WithCooldown(time.Hour).
+			WithOnStateChange(func(_ string, _, _ utils.BreakerState) {
+				states = append(states, cb.State())
+			})
+		require.ErrorIs(t, cb.Execute(failing), errTest)
+		cb.Reset()
+		require.Equal(t, []utils.BreakerState{utils.BreakerOpen, utils.BreakerClosed}, states)
+	})
+	t.Run("should reopen when probe fails", func(t *testing.T) {
+		cb := utils.NewCircuitBreaker("test").WithConsecutiveFailures(1).WithCooldown(20 * time.Millisecond)
+		require.ErrorIs(t, cb.Execute(failing), errTest)
+		time.Sleep(30 * time.Millisecond)
+		require.ErrorIs(t, cb.Execute(failing), errTest)
+		require.Equal(t, utils.BreakerOpen, cb.State())
+	})
+	t.Run("should ignore errors which are not failures", func(t *testing.T) {
+		cb := utils.NewCircuitBreaker("test").WithConsecutiveFailures(1).WithIsFailure(func(err error) bool {
+			return err != nil && !errors.Is(err, errTest)
+		})
+		require.ErrorIs(t, cb.Execute(failing), errTest)
+		require.Equal(t, utils.BreakerClosed, cb.State())
+	})
+	t.Run("should be safe for concurrent usage", func(t *testing.T) {
+		cb := utils.NewCircuitBreaker("test").WithFailureRate(0.9, 10).WithCooldown(time.Millisecond)
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					if (i+j)%2 == 0 {
+						_ = cb.Execute(failing)
+					} else {
+						_ = cb.Execute(passing)
+					}
+				}
+			}(i)
+		}
+		wg.Wait()
+	})
+}
+
+func TestCircuitBreaker_Compose(t *testing.T) {
+	errTest := errors.New("test error")
+	t.Run("repeater should stop when breaker opens", func(t *testing.T) {
+		cnt := 0
+		cb := utils.NewCircuitBreaker("test").WithConsecutiveFailures(3).WithCooldown(time.Hour)
+		_, err := utils.NewFuncRepeater(func() (int, error) {
+			cnt++
+			return 0, errTest
+		}).WithCircuitBreaker(cb).WithMaxTries(10).Run()
+		require.ErrorIs(t, err, utils.ErrCircuitOpen)
+		require.Equal(t, 3, cnt)
+	})
+	t.Run("curl should not send requests when breaker is open", func(t *testing.T) {
+		cnt := 0
+		srv := newTestServer(t, func(w http.ResponseWriter, _ *http.Request) {
+			cnt++
+			w.WriteHeader(http.StatusBadGateway)
+		})
+		cb := utils.NewCircuitBreaker("test").WithConsecutiveFailures(2).WithCooldown(time.Hour)
+		for i := 0; i < 2; i++ {
+			_, code, err := utils.GetCurl(context.Background(), srv.URL, nil, utils.WithCircuitBreaker[TestResponse](cb))
+			require.NoError(t, err)
+			require.Equal(t, http.StatusBadGateway, code)
+		}
+		_, _, err := utils.PostCurl(context.Background(), srv.URL, TestRequest{}, nil, utils.WithCircuitBreaker[TestResponse](cb))
+		require.ErrorIs(t, err, utils.ErrCircuitOpen)
+		require.Equal(t, 2, cnt)
+	})
+	t.Run("curl should not open breaker on too many requests", func(t *testing.T) {
+		srv := newTestServer(t, func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+		cb := utils.NewCircuitBreaker("test").WithConsecutiveFailures(2).WithCooldown(time.Hour)
+		for i := 0; i < 3; i++ {
+			_, code, err := utils.GetCurl(context.Background(), srv.URL, nil, utils.WithCircuitBreaker[TestResponse](cb))
+			require.NoError(t, err)
+			require.Equal(t, http.StatusTooManyRequests, code)
+		}
+		require.Equal(t, utils.BreakerClosed, cb.State())
+	})
+}
@@ -0,0 +1,208 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	httpCacheHit         = "hit"
+	httpCacheMiss        = "miss"
+	httpCacheRevalidated = "revalidated"
+	httpCacheCoalesced   = "coalesced"
+)
+
+type cachedHTTPResponse struct {
+	resp         *HTTPResponse
+	expiresAt    time.Time
+	etag         string
+	lastModified string
+}
+
+// HTTPResponseCache caches successful GET responses in TTLMap honouring Cache-Control, Expires and ETag headers.
+// Stale responses with ETag or Last-Modified are kept up to maxTTL and revalidated with conditional requests.
+type HTTPResponseCache struct {
+	name       string
+	storage    *TTLMap[string, cachedHTTPResponse]
+	defaultTTL time.Duration
+	metrics    *prometheus.CounterVec
+}
+
+// NewHTTPResponseCache creates cache, maxTTL limits how long any response is kept, including stale ones.
+// Call Close() when done to stop TTLMap cleanup goroutine.
+func NewHTTPResponseCache(name string, maxTTL, cleanupInterval time.Duration) *HTTPResponseCache {
+	return &HTTPResponseCache{
+		name:    name,
+		storage: NewTTLMap[string, cachedHTTPResponse](maxTTL, cleanupInterval),
+	}
+}
+
+// WithDefaultTTL sets freshness for responses without Cache-Control max-age and Expires headers.
+// By default such responses are cached only for revalidation.
+func (c *HTTPResponseCache) WithDefaultTTL(ttl time.Duration) *HTTPResponseCache {
+	c.defaultTTL = ttl
+	return c
+}
+
+// WithMetrics counts cache lookups by result: hit, miss, revalidated, coalesced.
+func (c *HTTPResponseCache) WithMetrics(namespace string) *HTTPResponseCache {
+	c.metrics = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http_cache",
+		Name:      "lookups_total",
+		Help:      "Number of http cache lookups by result.",
+	}, []string{"name", "result"})
+	return c
+}
+
+// Collectors returns collectors to be registered in prometheus registry, empty if metrics are disabled.
+func (c *HTTPResponseCache) Collectors() []prometheus.Collector {
+	if c.metrics == nil {
+		return nil
+	}
+	return []prometheus.Collector{c.metrics}
+}
+
+func (c *HTTPResponseCache) Close() {
+	c.storage.Close()
+}
+
+// Purge removes all cached responses.
+func (c *HTTPResponseCache) Purge() {
+	for key := range c.storage.LoadAll() {
+		c.storage.Delete(key)
+	}
+}
+
+func (c *HTTPResponseCache) do(ctx context.Context, client *HTTPClient, req *HTTPRequest, breaker *CircuitBreaker) (*HTTPResponse, error) {
+	key := httpRequestKey(client, req)
+	cached, ok := c.storage.Get(key)
+	now := time.Now()
+	if ok && now.Before(cached.expiresAt) {
+		c.observe(httpCacheHit)
+		return cached.resp, nil
+	}
+
+	fetchReq := req
+	if ok && (cached.etag != "" || cached.lastModified != "") {
+		fetchReq = &HTTPRequest{Method: req.Method, URL: req.URL, Header: req.Header.Clone(), Body: req.Body}
+		if fetchReq.Header == nil {
+			fetchReq.Header = make(http.Header, 2)
+		}
+		if cached.etag != "" {
+			fetchReq.Header.Set("If-None-Match", cached.etag)
+		}
+		if cached.lastModified != "" {
+			fetchReq.Header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+	// separate flight from plain WithSingleFlight callers, they must not receive 304 of revalidation
+	resp, shared, err := client.doShared(ctx, "cache\n"+key, fetchReq, breaker)
+	if err != nil {
+		return nil, err
+	}
+	if shared {
+		c.observe(httpCacheCoalesced)
+	}
+
+	if ok && resp.StatusCode == http.StatusNotModified {
+		c.observe(httpCacheRevalidated)
+		cached.expiresAt = now.Add(c.freshness(resp.Header, now))
+		c.storage.Put(key, cached)
+		return cached.resp, nil
+	}
+	c.observe(httpCacheMiss)
+	if resp.StatusCode == http.StatusOK {
+		c.store(key, resp, now)
+	}
+	return resp, nil
+}
+
+func (c *HTTPResponseCache) store(key string, resp *HTTPResponse, now time.Time) {
+	directives := parseCacheControl(resp.Header.Get("Cache-Control"))
+	if _, ok := directives["no-store"]; ok {
+		return
+	}
+	entry := cachedHTTPResponse{
+		resp:         resp,
+		expiresAt:    now.Add(c.freshness(resp.Header, now)),
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}
+	if !entry.expiresAt.After(now) && entry.etag == "" && entry.lastModified == "" {
+		// nothing to serve from cache and nothing to revalidate with
+		return
+	}
+	c.storage.Put(key, entry)
+}
+
+// freshness calculates how long response can be served without revalidation.
+func (c *HTTPResponseCache) freshness(header http.Header, now time.Time) time.Duration {
+	directives := parseCacheControl(header.Get("Cache-Control"))
+	if _, ok := directives["no-cache"]; ok {
+		return 0
+	}
+	for _, directive := range []string{"s-maxage", "max-age"} {
+		if value, ok := directives[directive]; ok {
+			seconds, err := strconv.Atoi(value)
+			if err != nil || seconds < 0 {
+				return 0
+			}
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	if expires := header.Get("Expires"); expires != "" {
+		date, err := http.ParseTime(expires)
+		if err != nil || !date.After(now) {
+			return 0
+		}
+		return date.Sub(now)
+	}
+	return c.defaultTTL
+}
+
+func (c *HTTPResponseCache) observe(result string) {
+	if c.metrics == nil {
+		return
+	}
+	c.metrics.WithLabelValues(c.name, result).Inc()
+}
+
+func parseCacheControl(value string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, arg, _ := strings.Cut(part, "=")
+		directives[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(arg), `"`)
+	}
+	return directives
+}
+
+// httpRequestKey identifies request for coalescing and caching, headers are included as responses may vary by them.
+func httpRequestKey(client *HTTPClient, req *HTTPRequest) string {
+	var sb strings.Builder
+	sb.WriteString(req.Method)
+	sb.WriteByte(' ')
+	sb.WriteString(client.resolveURL(req.URL))
+	keys := make([]string, 0, len(req.Header))
+	for k := range req.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		sb.WriteByte('\n')
+		sb.WriteString(k)
+		sb.WriteByte(':')
+		sb.WriteString(strings.Join(req.Header[k], ","))
+	}
+	return sb.String()
+}
@@ -0,0 +1,157 @@
+package utils_test
+
+import (
+	"context"
+	"go_project_template/internal/utils"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCurl_SingleFlight(t *testing.T) {
+	var cnt atomic.Int32
+	release := make(chan struct{})
+	srv := newTestServer(t, func(w http.ResponseWriter, _ *http.Request) {
+		cnt.Add(1)
+		<-release
+		_, _ = w.Write([]byte(`{"id":"00000000-0000-0000-0000-000000000001"}`))
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, code, err := utils.GetCurl(context.Background(), srv.URL, nil, utils.WithSingleFlight[TestResponse]())
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, code)
+			require.Equal(t, "00000000-0000-0000-0000-000000000001", res.ID1.String())
+		}()
+	}
+	require.Eventually(t, func() bool { return cnt.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(50 * time.Millisecond) // let other goroutines join the flight
+	close(release)
+	wg.Wait()
+	require.Equal(t, int32(1), cnt.Load())
+
+	t.Run("caller should stop waiting when its ctx is done", func(t *testing.T) {
+		block := make(chan struct{})
+		slow := newTestServer(t, func(w http.ResponseWriter, _ *http.Request) {
+			<-block
+		})
+		defer close(block)
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, _, err := utils.GetCurl(ctx, slow.URL, nil, utils.WithSingleFlight[TestResponse]())
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestGetCurl_ResponseCache(t *testing.T) {
+	ctx := context.Background()
+	newCache := func(t *testing.T) *utils.HTTPResponseCache {
+		cache := utils.NewHTTPResponseCache("test", time.Minute, time.Minute)
+		t.Cleanup(cache.Close)
+		return cache
+	}
+
+	t.Run("should serve fresh response from cache", func(t *testing.T) {
+		var cnt atomic.Int32
+		srv := newTestServer(t, func(w http.ResponseWriter, _ *http.Request) {
+			cnt.Add(1)
+			w.Header().Set("Cache-Control", "public, max-age=60")
+			_, _ = w.Write([]byte(`{"id":"00000000-0000-0000-0000-000000000001"}`))
+		})
+		cache := newCache(t).WithMetrics("test")
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(cache.Collectors()...)
+		for i := 0; i < 3; i++ {
+			res, code, err := utils.GetCurl(ctx, srv.URL, nil, utils.WithResponseCache[TestResponse](cache))
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, code)
+			require.Equal(t, "00000000-0000-0000-0000-000000000001", res.ID1.String())
+		}
+		require.Equal(t, int32(1), cnt.Load())
+
+		_, _, err := utils.GetCurl(ctx, srv.URL, map[string]string{"X-Other": "1"}, utils.WithResponseCache[TestResponse](cache))
+		require.NoError(t, err)
+		require.Equal(t, int32(2), cnt.Load(), "different headers should not share cache entry")
+
+		expected := `
+# HELP test_http_cache_lookups_total Number of http cache lookups by result.
+# TYPE test_http_cache_lookups_total counter
+test_http_cache_lookups_total{name="test",result="hit"} 2
+test_http_cache_lookups_total{name="test",result="miss"} 2
+`
+		require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected)))
+	})
+	t.Run("should revalidate stale response with etag", func(t *testing.T) {
+		var cnt, notModified atomic.Int32
+		srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			cnt.Add(1)
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Cache-Control", "no-cache")
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				notModified.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"00000000-0000-0000-0000-000000000002"}`))
+		})
+		cache := newCache(t)
+		for i := 0; i < 3; i++ {
+			res, code, err := utils.GetCurl(ctx, srv.URL, nil, utils.WithResponseCache[TestResponse](cache))
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, code)
+			require.Equal(t, "00000000-0000-0000-0000-000000000002", res.ID1.String())
+		}
+		require.Equal(t, int32(3), cnt.Load())
+		require.Equal(t, int32(2), notModified.Load())
+	})
+	t.Run("should not cache no-store and error responses", func(t *testing.T) {
+		var cnt atomic.Int32
+		srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			cnt.Add(1)
+			if r.URL.Path == "/error" {
+				w.Header().Set("Cache-Control", "max-age=60")
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.Header().Set("Cache-Control", "no-store, max-age=60")
+			_, _ = w.Write([]byte(`{}`))
+		})
+		cache := newCache(t).WithDefaultTTL(time.Minute)
+		for i := 0; i < 2; i++ {
+			_, _, err := utils.GetCurl(ctx, srv.URL, nil, utils.WithResponseCache[TestResponse](cache))
+			require.NoError(t, err)
+			_, code, err := utils.GetCurl(ctx, srv.URL+"/error", nil, utils.WithResponseCache[TestResponse](cache))
+			require.NoError(t, err)
+			require.Equal(t, http.StatusBadGateway, code)
+		}
+		require.Equal(t, int32(4), cnt.Load())
+	})
+	t.Run("should use default ttl when response has no freshness headers", func(t *testing.T) {
+		var cnt atomic.Int32
+		srv := newTestServer(t, func(w http.ResponseWriter, _ *http.Request) {
+			cnt.Add(1)
+			_, _ = w.Write([]byte(`{}`))
+		})
+		cache := newCache(t).WithDefaultTTL(time.Minute)
+		for i := 0; i < 2; i++ {
+			_, _, err := utils.GetCurl(ctx, srv.URL, nil, utils.WithResponseCache[TestResponse](cache))
+			require.NoError(t, err)
+		}
+		require.Equal(t, int32(1), cnt.Load())
+		cache.Purge()
+		_, _, err := utils.GetCurl(ctx, srv.URL, nil, utils.WithResponseCache[TestResponse](cache))
+		require.NoError(t, err)
+		require.Equal(t, int32(2), cnt.Load())
+	})
+}
@@ -0,0 +1,399 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	DefaultHTTPTimeout      = 30 * time.Second
+	DefaultHTTPMaxBodySize  = 32 * 1024 * 1024
+	DefaultHTTPRetryTimeout = 100 * time.Millisecond
+	DefaultHTTPMaxRetryWait = 5 * time.Second
+)
+
+const errMsgRequestCanceled = "request canceled"
+
+var (
+	ErrResponseTooLarge      = errors.New("response body is too large")
+	ErrTooManyRequestsStatus = errors.New("too many requests status")
+)
+
+// DefaultHTTPClient is used by Curl helpers unless WithClient option is passed.
+var DefaultHTTPClient = NewHTTPClient()
+
+var _ http.RoundTripper = (*hookTransport)(nil)
+
+// HTTPRoundTrip executes request, it is the next element of hook chain.
+type HTTPRoundTrip func(req *http.Request) (*http.Response, error)
+
+// HTTPHook wraps request execution, e.g. for logging or metrics. Hook must call next to proceed.
+type HTTPHook func(req *http.Request, next HTTPRoundTrip) (*http.Response, error)
+
+// HTTPTransportConf configures connection pooling of HTTPClient, zero values keep http.DefaultTransport settings.
+type HTTPTransportConf struct {
+	MaxIdleConns          int
+	MaxIdleConnsPerHost   int
+	MaxConnsPerHost       int
+	IdleConnTimeout       time.Duration
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+}
+
+// HTTPRequest is a transport independent request description, body is kept as bytes so request can be retried.
+type HTTPRequest struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// HTTPResponse is a fully read response.
+type HTTPResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// HTTPClient is a reusable client used by Curl helpers.
+type HTTPClient struct {
+	baseURL      string
+	client       *http.Client
+	transport    http.RoundTripper
+	headers      http.Header
+	auth         func(req *http.Request)
+	maxBodySize  int64
+	gzip         bool
+	maxTries     int
+	retryTimeout time.Duration
+	maxRetryWait time.Duration
+	breaker      *CircuitBreaker
+	hooks        []HTTPHook
+	flights      singleflight.Group
+}
+
+// NewHTTPClient creates client with DefaultHTTPTimeout, DefaultHTTPMaxBodySize and no retries.
+func NewHTTPClient() *HTTPClient {
+	c := &HTTPClient{
+		headers:      make(http.Header),
+		maxBodySize:  DefaultHTTPMaxBodySize,
+		maxTries:     1,
+		retryTimeout: DefaultHTTPRetryTimeout,
+		maxRetryWait: DefaultHTTPMaxRetryWait,
+		transport:    http.DefaultTransport,
+	}
+	c.client = &http.Client{
+		Timeout:   DefaultHTTPTimeout,
+		Transport: &hookTransport{client: c},
+	}
+	return c
+}
+
+// WithBaseURL makes relative URLs to be resolved against baseURL.
+func (c *HTTPClient) WithBaseURL(baseURL string) *HTTPClient {
+	c.baseURL = strings.TrimRight(baseURL, "/")
+	return c
+}
+
+// WithTimeout limits whole request including reading response body, 0 means no timeout.
+func (c *HTTPClient) WithTimeout(timeout time.Duration) *HTTPClient {
+	c.client.Timeout = timeout
+	return c
+}
+
+// WithTransport creates dedicated connection pool for client.
+func (c *HTTPClient) WithTransport(conf HTTPTransportConf) *HTTPClient {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if conf.MaxIdleConns > 0 {
+		transport.MaxIdleConns = conf.MaxIdleConns
+	}
+	if conf.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = conf.MaxIdleConnsPerHost
+	}
+	if conf.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = conf.MaxConnsPerHost
+	}
+	if conf.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = conf.IdleConnTimeout
+	}
+	if conf.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = conf.TLSHandshakeTimeout
+	}
+	if conf.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = conf.ResponseHeaderTimeout
+	}
+	if conf.DialTimeout > 0 {
+		transport.DialContext = (&net.Dialer{Timeout: conf.DialTimeout, KeepAlive: 30 * time.Second}).DialContext
+	}
+	c.transport = transport
+	return c
+}
+
+// WithRoundTripper replaces underlying transport, hooks are still applied.
+func (c *HTTPClient) WithRoundTripper(transport http.RoundTripper) *HTTPClient {
+	c.transport = transport
+	return c
+}
+
+// WithRetries retries transport errors, 5xx and 429 responses with exponential backoff starting from retryTimeout.
+// maxTries includes the first attempt.
+func (c *HTTPClient) WithRetries(maxTries int, retryTimeout time.Duration) *HTTPClient {
+	c.maxTries = maxTries
+	c.retryTimeout = retryTimeout
+	return c
+}
+
+// WithMaxRetryWait caps pause between retries.
+func (c *HTTPClient) WithMaxRetryWait(maxWait time.Duration) *HTTPClient {
+	c.maxRetryWait = maxWait
+	return c
+}
+
+// WithMaxBodySize limits size of response body, ErrResponseTooLarge is returned if exceeded. 0 means no limit.
+func (c *HTTPClient) WithMaxBodySize(size int64) *HTTPClient {
+	c.maxBodySize = size
+	return c
+}
+
+// WithGzip compresses request bodies and asks server for gzipped responses.
+func (c *HTTPClient) WithGzip(enabled bool) *HTTPClient {
+	c.gzip = enabled
+	return c
+}
+
+// WithHeader adds header sent with every request.
+func (c *HTTPClient) WithHeader(key, value string) *HTTPClient {
+	c.headers.Add(key, value)
+	return c
+}
+
+// WithAuth sets function which authorizes every request.
+func (c *HTTPClient) WithAuth(auth func(req *http.Request)) *HTTPClient {
+	c.auth = auth
+	return c
+}
+
+func (c *HTTPClient) WithBearerToken(token string) *HTTPClient {
+	return c.WithAuth(func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+token)
+	})
+}
+
+func (c *HTTPClient) WithBasicAuth(user, pass string) *HTTPClient {
+	return c.WithAuth(func(req *http.Request) {
+		req.SetBasicAuth(user, pass)
+	})
+}
+
+// WithCircuitBreaker runs every attempt through breaker, transport errors and 5xx responses are counted as failures,
+// 429 responses are not.
+func (c *HTTPClient) WithCircuitBreaker(breaker *CircuitBreaker) *HTTPClient {
+	c.breaker = breaker
+	return c
+}
+
+// WithHooks appends hooks to chain, the first hook is the outermost one.
+func (c *HTTPClient) WithHooks(hooks ...HTTPHook) *HTTPClient {
+	c.hooks = append(c.hooks, hooks...)
+	return c
+}
+
+// Do executes request with retries and returns fully read response.
+// Responses with any status are returned without error, retryable statuses are returned after last attempt.
+// breaker overrides client breaker if set.
+func (c *HTTPClient) Do(ctx context.Context, req *HTTPRequest, breaker *CircuitBreaker) (*HTTPResponse, error) {
+	if breaker == nil {
+		breaker = c.breaker
+	}
+	attempt := func(ctx context.Context) (*HTTPResponse, error) {
+		if breaker == nil {
+			return c.attempt(ctx, req)
+		}
+		return c.attemptWithBreaker(ctx, req, breaker)
+	}
+	if c.maxTries <= 1 {
+		return statusErrToResponse(attempt(ctx))
+	}
+
+	repeater := NewCtxFuncRepeater(attempt).
+		WithCtx(ctx).
+		WithMaxTries(c.maxTries).
+		WithFallbackTries(0).
+		WithExponentialBackoff(c.retryTimeout).
+		WithMaxDelay(c.maxRetryWait).
+		WithJitter(JitterEqual).
+		WithRetryIf(isRetryableHTTPErr).
+		WithErrMsg(errMsgRequestCanceled)
+	return statusErrToResponse(repeater.Run())
+}
+
+// attemptWithBreaker runs attempt through breaker. 429 is reported as success, upstream is alive
+// and only throttles us, so rate limiting alone must not open the breaker.
+func (c *HTTPClient) attemptWithBreaker(ctx context.Context, req *HTTPRequest, breaker *CircuitBreaker) (*HTTPResponse, error) {
+	done, err := breaker.Allow()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.attempt(ctx, req)
+	if errors.Is(err, ErrTooManyRequestsStatus) {
+		done(nil)
+	} else {
+		done(err)
+	}
+	return resp, err
+}
+
+// doShared executes request once for all concurrent callers with the same key.
+// Shared request is detached from caller ctx, so the first caller leaving does not fail others,
+// each caller still stops waiting when its own ctx is done. shared reports if response was given to several callers.
+func (c *HTTPClient) doShared(ctx context.Context, key string, req *HTTPRequest, breaker *CircuitBreaker) (resp *HTTPResponse, shared bool, err error) {
+	ch := c.flights.DoChan(key, func() (any, error) {
+		return c.Do(context.WithoutCancel(ctx), req, breaker)
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Shared, res.Err
+		}
+		return res.Val.(*HTTPResponse), res.Shared, nil
+	case <-ctx.Done():
+		return nil, false, fmt.Errorf("%s: %w", errMsgRequestCanceled, ctx.Err())
+	}
+}
+
+func (c *HTTPClient) attempt(ctx context.Context, r *HTTPRequest) (*HTTPResponse, error) {
+	body := r.Body
+	if c.gzip && len(body) > 0 {
+		compressed, err := gzipBytes(body)
+		if err != nil {
+			return nil, fmt.Errorf("unable to compress request body: %w", err)
+		}
+		body = compressed
+	}
+	var bodyReader io.Reader = http.NoBody
+	if len(body) > 0 {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.Method, c.resolveURL(r.URL), bodyReader)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request: %w", err)
+	}
+	for k, v := range c.headers {
+		req.Header[k] = append(req.Header[k], v...)
+	}
+	for k, v := range r.Header {
+		req.Header[k] = append(req.Header[k], v...)
+	}
+	if c.gzip {
+		req.Header.Set("Accept-Encoding", "gzip")
+		if len(body) > 0 {
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+	}
+	if c.auth != nil {
+		c.auth(req)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := c.readBody(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	res := &HTTPResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       respBody,
+	}
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return res, fmt.Errorf("%w: %d", ErrServerErrorStatus, resp.StatusCode)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return res, fmt.Errorf("%w: %d", ErrTooManyRequestsStatus, resp.StatusCode)
+	}
+	return res, nil
+}
+
+func (c *HTTPClient) readBody(resp *http.Response) ([]byte, error) {
+	var reader io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gzReader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create gzip reader: %w", err)
+		}
+		defer gzReader.Close()
+		reader = gzReader
+	}
+	if c.maxBodySize <= 0 {
+		return io.ReadAll(reader)
+	}
+	b, err := io.ReadAll(io.LimitReader(reader, c.maxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > c.maxBodySize {
+		return nil, fmt.Errorf("%w: limit %d bytes", ErrResponseTooLarge, c.maxBodySize)
+	}
+	return b, nil
+}
+
+func (c *HTTPClient) resolveURL(target string) string {
+	if c.baseURL == "" || strings.Contains(target, "://") {
+		return target
+	}
+	return c.baseURL + "/" + strings.TrimLeft(target, "/")
+}
+
+// hookTransport applies client hooks around the underlying transport.
+type hookTransport struct {
+	client *HTTPClient
+}
+
+func (t *hookTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.client.transport.RoundTrip
+	for i := len(t.client.hooks) - 1; i >= 0; i-- {
+		hook, inner := t.client.hooks[i], next
+		next = func(req *http.Request) (*http.Response, error) {
+			return hook(req, inner)
+		}
+	}
+	return next(req)
+}
+
+func isRetryableHTTPErr(err error) bool {
+	return !errors.Is(err, ErrResponseTooLarge)
+}
+
+// statusErrToResponse drops errors produced for retryable statuses, caller gets response with status as is.
+func statusErrToResponse(resp *HTTPResponse, err error) (*HTTPResponse, error) {
+	if resp != nil && (errors.Is(err, ErrServerErrorStatus) || errors.Is(err, ErrTooManyRequestsStatus)) {
+		return resp, nil
+	}
+	return resp, err
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// HTTPClientMetrics holds prometheus collectors for HTTPClient, use Hook to attach it to client.
+type HTTPClientMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+func NewHTTPClientMetrics(namespace string) *HTTPClientMetrics {
+	return &HTTPClientMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "requests_total",
+			Help:      "Number of outgoing http requests by status code, code is 0 for transport errors.",
+		}, []string{"host", "method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "request_duration_seconds",
+			Help:      "Time until response headers are received.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"host", "method"}),
+	}
+}
+
+// Collectors returns collectors to be registered in prometheus registry.
+func (m *HTTPClientMetrics) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.requests, m.duration}
+}
+
+// Hook returns HTTPHook recording every attempt, retries included.
+func (m *HTTPClientMetrics) Hook() HTTPHook {
+	return func(req *http.Request, next HTTPRoundTrip) (*http.Response, error) {
+		started := time.Now()
+		resp, err := next(req)
+		m.duration.WithLabelValues(req.URL.Host, req.Method).Observe(time.Since(started).Seconds())
+		code := 0
+		if err == nil {
+			code = resp.StatusCode
+		}
+		m.requests.WithLabelValues(req.URL.Host, req.Method, strconv.Itoa(code)).Inc()
+		return resp, err
+	}
+}
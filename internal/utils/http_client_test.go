@@ -0,0 +1,173 @@
+package utils_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"go_project_template/internal/utils"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPClient(t *testing.T) {
+	ctx := context.Background()
+	sampleResult := TestResponse{
+		ID1: uuid.New(),
+		ID2: uuid.New(),
+		ID3: uuid.New(),
+	}
+
+	t.Run("should resolve base url and send default headers and auth", func(t *testing.T) {
+		srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/api/v1/test", r.URL.Path)
+			require.Equal(t, "default", r.Header.Get("X-Default"))
+			require.Equal(t, "custom", r.Header.Get("X-Custom"))
+			require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+			require.NoError(t, json.NewEncoder(w).Encode(sampleResult))
+		})
+		client := utils.NewHTTPClient().
+			WithBaseURL(srv.URL+"/api/v1/").
+			WithHeader("X-Default", "default").
+			WithBearerToken("token")
+		res, code, err := utils.GetCurl(ctx, "/test", map[string]string{"X-Custom": "custom"}, utils.WithClient[TestResponse](client))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, &sampleResult, res)
+	})
+	t.Run("should retry server errors and keep request body", func(t *testing.T) {
+		var cnt atomic.Int32
+		sampleRequest := TestRequest{ID1: uuid.New()}
+		srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			var req TestRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			require.Equal(t, sampleRequest, req)
+			if cnt.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			require.NoError(t, json.NewEncoder(w).Encode(sampleResult))
+		})
+		client := utils.NewHTTPClient().WithRetries(3, time.Millisecond)
+		res, code, err := utils.PostCurl(ctx, srv.URL, sampleRequest, nil, utils.WithClient[TestResponse](client))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, &sampleResult, res)
+		require.Equal(t, int32(3), cnt.Load())
+	})
+	t.Run("should return last status when retries are exhausted", func(t *testing.T) {
+		var cnt atomic.Int32
+		srv := newTestServer(t, func(w http.ResponseWriter, _ *http.Request) {
+			cnt.Add(1)
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+		client := utils.NewHTTPClient().WithRetries(2, time.Millisecond)
+		_, code, err := utils.GetCurl(ctx, srv.URL, nil, utils.WithClient[TestResponse](client))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusTooManyRequests, code)
+		require.Equal(t, int32(2), cnt.Load())
+	})
+	t.Run("should not retry client errors", func(t *testing.T) {
+		var cnt atomic.Int32
+		srv := newTestServer(t, func(w http.ResponseWriter, _ *http.Request) {
+			cnt.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+		})
+		client := utils.NewHTTPClient().WithRetries(3, time.Millisecond)
+		_, code, err := utils.GetCurl(ctx, srv.URL, nil, utils.WithClient[TestResponse](client))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Equal(t, int32(1), cnt.Load())
+	})
+	t.Run("should limit response body size", func(t *testing.T) {
+		srv := newTestServer(t, func(w http.ResponseWriter, _ *http.Request) {
+			_, err := w.Write([]byte(strings.Repeat("a", 100)))
+			require.NoError(t, err)
+		})
+		client := utils.NewHTTPClient().WithMaxBodySize(99).WithRetries(3, time.Millisecond)
+		_, code, err := utils.GetCurl(ctx, srv.URL, nil, utils.WithClient[TestResponse](client))
+		require.ErrorIs(t, err, utils.ErrResponseTooLarge)
+		require.Zero(t, code)
+	})
+	t.Run("should timeout", func(t *testing.T) {
+		srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+		})
+		client := utils.NewHTTPClient().WithTimeout(10 * time.Millisecond)
+		_, _, err := utils.GetCurl(ctx, srv.URL, nil, utils.WithClient[TestResponse](client))
+		require.ErrorContains(t, err, "Client.Timeout exceeded")
+	})
+	t.Run("should compress request and decompress response", func(t *testing.T) {
+		srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+			require.Equal(t, "gzip", r.Header.Get("Accept-Encoding"))
+			gzReader, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			body, err := io.ReadAll(gzReader)
+			require.NoError(t, err)
+			require.Contains(t, string(body), `"id"`)
+
+			w.Header().Set("Content-Encoding", "gzip")
+			gzWriter := gzip.NewWriter(w)
+			require.NoError(t, json.NewEncoder(gzWriter).Encode(sampleResult))
+			require.NoError(t, gzWriter.Close())
+		})
+		client := utils.NewHTTPClient().WithGzip(true)
+		res, _, err := utils.PatchCurl(ctx, srv.URL, TestRequest{}, nil, utils.WithClient[TestResponse](client))
+		require.NoError(t, err)
+		require.Equal(t, &sampleResult, res)
+	})
+	t.Run("should treat blank and empty string bodies as empty", func(t *testing.T) {
+		for _, body := range []string{"", " \n", `""`} {
+			srv := newTestServer(t, func(w http.ResponseWriter, _ *http.Request) {
+				_, err := w.Write([]byte(body))
+				require.NoError(t, err)
+			})
+			res, _, err := utils.GetCurl[TestResponse](ctx, srv.URL, nil)
+			require.NoError(t, err)
+			require.Equal(t, &TestResponse{}, res)
+		}
+		srv := newTestServer(t, func(w http.ResponseWriter, _ *http.Request) {
+			_, err := w.Write([]byte(`"a\"b"`))
+			require.NoError(t, err)
+		})
+		res, _, err := utils.GetCurl[string](ctx, srv.URL, nil)
+		require.NoError(t, err)
+		require.Equal(t, `a"b`, *res)
+	})
+	t.Run("should run hooks in order and record metrics", func(t *testing.T) {
+		srv := newTestServer(t, func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+		var calls bytes.Buffer
+		hook := func(name string) utils.HTTPHook {
+			return func(req *http.Request, next utils.HTTPRoundTrip) (*http.Response, error) {
+				calls.WriteString(name + ">")
+				resp, err := next(req)
+				calls.WriteString("<" + name)
+				return resp, err
+			}
+		}
+		metrics := utils.NewHTTPClientMetrics("test")
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(metrics.Collectors()...)
+		client := utils.NewHTTPClient().WithHooks(hook("a"), hook("b"), metrics.Hook())
+		_, code, err := utils.GetCurl(ctx, srv.URL, nil, utils.WithClient[TestResponse](client))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNoContent, code)
+		require.Equal(t, "a>b><b<a", calls.String())
+		require.Equal(t, 1, testutil.CollectAndCount(metrics.Collectors()[0]))
+	})
+}
@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const httpErrorBodyPreview = 256
+
+// HTTPError is returned by Curl helpers for non-2xx responses when WithHTTPErrors or WithTypedHTTPErrors is used.
+type HTTPError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// Decoded holds body decoded to error type passed to WithTypedHTTPErrors, nil if decoding failed.
+	Decoded any
+}
+
+func (e *HTTPError) Error() string {
+	body := e.Body
+	if len(body) > httpErrorBodyPreview {
+		body = body[:httpErrorBodyPreview]
+	}
+	return fmt.Sprintf("%s %s: unexpected status code: %d, body: %s", e.Method, e.URL, e.StatusCode, body)
+}
+
+// RetryAfter parses Retry-After header, which can contain either seconds or http date.
+func (e *HTTPError) RetryAfter() (time.Duration, bool) {
+	return ParseRetryAfter(e.Header.Get("Retry-After"), time.Now())
+}
+
+// ParseRetryAfter parses Retry-After header value relative to now.
+// Dates in the past result in zero duration.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if delay := date.Sub(now); delay > 0 {
+		return delay, true
+	}
+	return 0, true
+}
+
+// WithHTTPErrors makes Curl helpers return *HTTPError for non-2xx responses instead of decoding them to T.
+func WithHTTPErrors[T any]() func(*CurlConf[T]) {
+	return func(c *CurlConf[T]) {
+		c.HTTPErrors = true
+	}
+}
+
+// WithTypedHTTPErrors is WithHTTPErrors which also decodes error body to E, see HTTPErrorBody.
+func WithTypedHTTPErrors[T, E any]() func(*CurlConf[T]) {
+	return func(c *CurlConf[T]) {
+		c.HTTPErrors = true
+		c.ErrorDecoder = func(body []byte) (any, error) {
+			var decoded E
+			if err := json.Unmarshal(body, &decoded); err != nil {
+				return nil, err
+			}
+			return decoded, nil
+		}
+	}
+}
+
+// AsHTTPError finds *HTTPError in err chain.
+func AsHTTPError(err error) (*HTTPError, bool) {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr, true
+	}
+	return nil, false
+}
+
+// HTTPErrorBody returns error body decoded with WithTypedHTTPErrors[T, E].
+func HTTPErrorBody[E any](err error) (res E, ok bool) {
+	httpErr, ok := AsHTTPError(err)
+	if !ok {
+		return res, false
+	}
+	res, ok = httpErr.Decoded.(E)
+	return res, ok
+}
+
+// HTTPErrorStatus returns status code of *HTTPError in err chain or 0.
+func HTTPErrorStatus(err error) int {
+	if httpErr, ok := AsHTTPError(err); ok {
+		return httpErr.StatusCode
+	}
+	return 0
+}
+
+func IsBadRequest(err error) bool {
+	return HTTPErrorStatus(err) == http.StatusBadRequest
+}
+
+func IsUnauthorized(err error) bool {
+	return HTTPErrorStatus(err) == http.StatusUnauthorized
+}
+
+func IsForbidden(err error) bool {
+	return HTTPErrorStatus(err) == http.StatusForbidden
+}
+
+func IsNotFound(err error) bool {
+	return HTTPErrorStatus(err) == http.StatusNotFound
+}
+
+func IsConflict(err error) bool {
+	return HTTPErrorStatus(err) == http.StatusConflict
+}
+
+func IsRateLimited(err error) bool {
+	return HTTPErrorStatus(err) == http.StatusTooManyRequests
+}
+
+func IsServerError(err error) bool {
+	return HTTPErrorStatus(err) >= http.StatusInternalServerError
+}
+
+// IsRetryable reports whether request may succeed if repeated later:
+// timeouts, rate limiting and server errors except 501 Not Implemented.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	switch status := HTTPErrorStatus(err); status {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests:
+		return true
+	case http.StatusNotImplemented:
+		return false
+	default:
+		return status >= http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,106 @@
+package utils_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_project_template/internal/utils"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPErrors(t *testing.T) {
+	type apiError struct {
+		Error       string `json:"error"`
+		Description string `json:"description"`
+	}
+	ctx := context.Background()
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/not_found":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"not found","description":"no such token"}`))
+		case "/rate_limited":
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/broken":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`<html>bad request</html>`))
+		default:
+			_, _ = w.Write([]byte(`{"id":"00000000-0000-0000-0000-000000000000"}`))
+		}
+	})
+
+	t.Run("should return typed error for non-2xx response", func(t *testing.T) {
+		res, code, err := utils.GetCurl(ctx, srv.URL+"/not_found", nil, utils.WithTypedHTTPErrors[TestResponse, apiError]())
+		require.Nil(t, res)
+		require.Equal(t, http.StatusNotFound, code)
+		require.True(t, utils.IsNotFound(err))
+		require.False(t, utils.IsRetryable(err))
+
+		httpErr, ok := utils.AsHTTPError(fmt.Errorf("wrapped: %w", err))
+		require.True(t, ok)
+		require.Equal(t, http.MethodGet, httpErr.Method)
+		require.JSONEq(t, `{"error":"not found","description":"no such token"}`, string(httpErr.Body))
+
+		decoded, ok := utils.HTTPErrorBody[apiError](err)
+		require.True(t, ok)
+		require.Equal(t, apiError{Error: "not found", Description: "no such token"}, decoded)
+	})
+	t.Run("should keep raw body when error type cannot be decoded", func(t *testing.T) {
+		_, code, err := utils.PostCurl(ctx, srv.URL+"/broken", TestRequest{}, nil, utils.WithTypedHTTPErrors[TestResponse, apiError]())
+		require.Equal(t, http.StatusBadRequest, code)
+		require.True(t, utils.IsBadRequest(err))
+		_, ok := utils.HTTPErrorBody[apiError](err)
+		require.False(t, ok)
+		require.ErrorContains(t, err, "<html>bad request</html>")
+	})
+	t.Run("should parse retry after", func(t *testing.T) {
+		_, _, err := utils.GetCurl(ctx, srv.URL+"/rate_limited", nil, utils.WithHTTPErrors[TestResponse]())
+		require.True(t, utils.IsRateLimited(err))
+		require.True(t, utils.IsRetryable(err))
+		httpErr, ok := utils.AsHTTPError(err)
+		require.True(t, ok)
+		delay, ok := httpErr.RetryAfter()
+		require.True(t, ok)
+		require.Equal(t, 7*time.Second, delay)
+	})
+	t.Run("should decode successful response as usual", func(t *testing.T) {
+		res, code, err := utils.GetCurl(ctx, srv.URL+"/ok", nil, utils.WithHTTPErrors[TestResponse]())
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, code)
+		require.NotNil(t, res)
+	})
+	t.Run("should keep legacy behaviour without option", func(t *testing.T) {
+		res, code, err := utils.GetCurl[apiError](ctx, srv.URL+"/not_found", nil)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, code)
+		require.Equal(t, "not found", res.Error)
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{value: "", ok: false},
+		{value: "120", expected: 2 * time.Minute, ok: true},
+		{value: "-1", ok: false},
+		{value: "Mon, 01 Jan 2024 00:00:30 GMT", expected: 30 * time.Second, ok: true},
+		{value: "Sun, 31 Dec 2023 00:00:00 GMT", expected: 0, ok: true},
+		{value: "tomorrow", ok: false},
+	}
+	for _, tc := range testCases {
+		delay, ok := utils.ParseRetryAfter(tc.value, now)
+		require.Equal(t, tc.ok, ok, tc.value)
+		require.Equal(t, tc.expected, delay, tc.value)
+	}
+	require.False(t, utils.IsRetryable(nil))
+	require.False(t, utils.IsRetryable(errors.New("some error")))
+}
@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+var ErrLoadPanicked = errors.New("load panicked")
+
+// LoadFunc loads value of a key missing in cache, e.g. from database.
+type LoadFunc[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+// LoaderConf configures read-through loading of a cache, zero value disables both features.
+type LoaderConf struct {
+	// ErrorTTL is how long loader error is returned for its key without calling loader again.
+	ErrorTTL time.Duration
+	// StaleWhileRevalidate is the last part of item lifetime when item is returned as is
+	// and reloaded in background. Items without TTL are never stale.
+	StaleWhileRevalidate time.Duration
+}
+
+type loadCall[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+type loadError struct {
+	err        error
+	expiryNano int64
+}
+
+// Loader deduplicates concurrent loads of the same key and stores loaded values to cache.
+type Loader[K comparable, V any] struct {
+	conf    LoaderConf
+	store   func(key K, value V)
+	observe func(duration time.Duration, err error)
+
+	mu        sync.Mutex
+	calls     map[K]*loadCall[V]
+	errs      map[K]loadError
+	pruneSize int // errs size which triggers removal of expired errors
+}
+
+// NewLoader creates loader which puts loaded values to cache with store and reports every load to observe.
+func NewLoader[K comparable, V any](conf LoaderConf, store func(key K, value V), observe func(duration time.Duration, err error)) *Loader[K, V] {
+	return &Loader[K, V]{
+		conf:      conf,
+		store:     store,
+		observe:   observe,
+		calls:     make(map[K]*loadCall[V]),
+		errs:      make(map[K]loadError),
+		pruneSize: 64,
+	}
+}
+
+// Load calls load once for all concurrent callers of the same key, every caller waits for result within its ctx.
+// Load itself is not canceled when callers give up, so its result is still cached for later callers.
+func (l *Loader[K, V]) Load(ctx context.Context, key K, load LoadFunc[K, V]) (value V, err error) {
+	l.mu.Lock()
+	if cached, ok := l.errs[key]; ok {
+		if time.Now().UnixNano() <= cached.expiryNano {
+			l.mu.Unlock()
+			return value, cached.err
+		}
+		delete(l.errs, key)
+	}
+	call := l.startLocked(ctx, key, load)
+	l.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		return value, ctx.Err()
+	}
+}
+
+// Revalidate reloads key in background if item with remaining ttl is stale, zero ttl means item never expires.
+// Returns true if item is stale.
+func (l *Loader[K, V]) Revalidate(ctx context.Context, key K, ttl time.Duration, load LoadFunc[K, V]) bool {
+	if ttl <= 0 || ttl > l.conf.StaleWhileRevalidate {
+		return false
+	}
+	l.mu.Lock()
+	l.startLocked(ctx, key, load)
+	l.mu.Unlock()
+	return true
+}
+
+// startLocked joins running load of key or starts a new one, must be called under l.mu.
+func (l *Loader[K, V]) startLocked(ctx context.Context, key K, load LoadFunc[K, V]) *loadCall[V] {
+	if call, ok := l.calls[key]; ok {
+		return call
+	}
+	call := &loadCall[V]{done: make(chan struct{})}
+	l.calls[key] = call
+	go l.run(context.WithoutCancel(ctx), key, load, call)
+	return call
+}
+
+func (l *Loader[K, V]) run(ctx context.Context, key K, load LoadFunc[K, V], call *loadCall[V]) {
+	defer func() {
+		l.mu.Lock()
+		delete(l.calls, key)
+		if call.err != nil && l.conf.ErrorTTL > 0 {
+			l.errs[key] = loadError{err: call.err, expiryNano: time.Now().UnixNano() + l.conf.ErrorTTL.Nanoseconds()}
+			l.pruneErrorsLocked()
+		}
+		l.mu.Unlock()
+		close(call.done)
+	}()
+	start := time.Now()
+	call.value, call.err = safeLoad(ctx, key, load)
+	l.observe(time.Since(start), call.err)
+	if call.err == nil {
+		l.store(key, call.value)
+	}
+}
+
+// safeLoad turns panic of load into error, load runs in its own goroutine and would crash the process otherwise.
+func safeLoad[K comparable, V any](ctx context.Context, key K, load LoadFunc[K, V]) (value V, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v\n%s", ErrLoadPanicked, r, debug.Stack())
+		}
+	}()
+	return load(ctx, key)
+}
+
+// pruneErrorsLocked removes expired errors once their number doubles, so failing unique keys can't grow it forever.
+func (l *Loader[K, V]) pruneErrorsLocked() {
+	if len(l.errs) < l.pruneSize {
+		return
+	}
+	now := time.Now().UnixNano()
+	for key, cached := range l.errs {
+		if now > cached.expiryNano {
+			delete(l.errs, key)
+		}
+	}
+	l.pruneSize = max(64, 2*len(l.errs))
+}
@@ -0,0 +1,136 @@
+package utils_test
+
+import (
+	"context"
+	"errors"
+	"go_project_template/internal/utils"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTTLMap_GetOrLoad(t *testing.T) {
+	t.Run("should load missing key once for concurrent callers", func(t *testing.T) {
+		m := utils.NewTTLMap[string, int](time.Minute, time.Minute)
+		defer m.Close()
+		var calls atomic.Int32
+		release := make(chan struct{})
+		loader := func(_ context.Context, key string) (int, error) {
+			calls.Add(1)
+			<-release
+			return len(key), nil
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				val, err := m.GetOrLoad(context.Background(), "key", loader)
+				require.NoError(t, err)
+				require.Equal(t, 3, val)
+			}()
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		require.Equal(t, int32(1), calls.Load())
+		val, ok := m.Get("key")
+		require.True(t, ok)
+		require.Equal(t, 3, val)
+		stats := m.Stats()
+		require.Equal(t, uint64(1), stats.Loads)
+	})
+	t.Run("should cache loader error", func(t *testing.T) {
+		m := utils.NewTTLMap[string, int](time.Minute, time.Minute).
+			WithLoader(utils.LoaderConf{ErrorTTL: 100 * time.Millisecond})
+		defer m.Close()
+		errLoad := errors.New("db is down")
+		var calls atomic.Int32
+		loader := func(context.Context, string) (int, error) {
+			if calls.Add(1) == 1 {
+				return 0, errLoad
+			}
+			return 1, nil
+		}
+
+		for i := 0; i < 3; i++ {
+			_, err := m.GetOrLoad(context.Background(), "key", loader)
+			require.ErrorIs(t, err, errLoad)
+		}
+		require.Equal(t, int32(1), calls.Load())
+		require.Equal(t, uint64(1), m.Stats().LoadErrors)
+
+		time.Sleep(150 * time.Millisecond)
+		val, err := m.GetOrLoad(context.Background(), "key", loader)
+		require.NoError(t, err)
+		require.Equal(t, 1, val)
+	})
+	t.Run("should return stale value and refresh it in background", func(t *testing.T) {
+		m := utils.NewTTLMap[string, int](200*time.Millisecond, time.Minute).
+			WithLoader(utils.LoaderConf{StaleWhileRevalidate: 150 * time.Millisecond})
+		defer m.Close()
+		var version atomic.Int32
+		loader := func(context.Context, string) (int, error) {
+			return int(version.Add(1)), nil
+		}
+
+		val, err := m.GetOrLoad(context.Background(), "key", loader)
+		require.NoError(t, err)
+		require.Equal(t, 1, val)
+		val, err = m.GetOrLoad(context.Background(), "key", loader)
+		require.NoError(t, err)
+		require.Equal(t, 1, val, "fresh value is not reloaded")
+
+		time.Sleep(100 * time.Millisecond)
+		val, err = m.GetOrLoad(context.Background(), "key", loader)
+		require.NoError(t, err)
+		require.Equal(t, 1, val, "stale value is returned")
+		require.Eventually(t, func() bool {
+			val, ok := m.Get("key")
+			return ok && val == 2
+		}, time.Second, 10*time.Millisecond)
+	})
+	t.Run("should return loader panic as error", func(t *testing.T) {
+		m := utils.NewTTLMap[string, int](time.Minute, time.Minute)
+		defer m.Close()
+		loader := func(context.Context, string) (int, error) {
+			panic("boom")
+		}
+
+		_, err := m.GetOrLoad(context.Background(), "key", loader)
+		require.ErrorIs(t, err, utils.ErrLoadPanicked)
+		require.ErrorContains(t, err, "boom")
+		// next caller starts a new load instead of waiting for the panicked one
+		val, err := m.GetOrLoad(context.Background(), "key", func(context.Context, string) (int, error) {
+			return 1, nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, val)
+	})
+	t.Run("should stop waiting on context cancel", func(t *testing.T) {
+		m := utils.NewTTLMap[string, int](time.Minute, time.Minute)
+		defer m.Close()
+		release := make(chan struct{})
+		loader := func(ctx context.Context, _ string) (int, error) {
+			<-release
+			return 7, ctx.Err()
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err := m.GetOrLoad(ctx, "key", loader)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		// load goes on without the canceled caller and its result is cached
+		close(release)
+		require.Eventually(t, func() bool {
+			val, ok := m.Get("key")
+			return ok && val == 7
+		}, time.Second, 10*time.Millisecond)
+	})
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"context"
+	"time"
+)
+
+// PollLoop calls poll until ctx is done or stop is closed. When poll reports more work is probably due
+// it is called again at once, otherwise after interval.
+func PollLoop(ctx context.Context, stop <-chan struct{}, interval time.Duration, poll func() (more bool)) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+		case <-stop:
+			return
+		case <-ctx.Done():
+			return
+		}
+		wait := interval
+		if poll() {
+			wait = 0
+		}
+		timer.Reset(wait)
+	}
+}
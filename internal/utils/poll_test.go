@@ -0,0 +1,37 @@
+package utils_test
+
+import (
+	"context"
+	"go_project_template/internal/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollLoop(t *testing.T) {
+	t.Run("should poll again at once while more work is due", func(t *testing.T) {
+		stop := make(chan struct{})
+		polls := 0
+		started := time.Now()
+		utils.PollLoop(context.Background(), stop, time.Hour, func() bool {
+			polls++
+			if polls == 5 {
+				close(stop)
+			}
+			return polls < 5
+		})
+		require.Equal(t, 5, polls)
+		require.Less(t, time.Since(started), time.Second)
+	})
+	t.Run("should wait interval when there is no work", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		polls := 0
+		utils.PollLoop(ctx, nil, 20*time.Millisecond, func() bool {
+			polls++
+			return false
+		})
+		require.InDelta(t, 3, polls, 1)
+	})
+}
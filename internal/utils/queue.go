@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueFull   = errors.New("queue is full")
+	ErrQueueClosed = errors.New("queue is closed")
+)
+
+// Queue is a bounded FIFO queue safe for concurrent use.
+// After Close pushes fail with ErrQueueClosed, while items left in the queue can still be popped.
+type Queue[T any] struct {
+	items chan T
+	done  chan struct{}
+
+	// mu is held for read by pushers, so Close returns only when no push is in flight
+	mu     sync.RWMutex
+	closed bool
+	once   sync.Once
+}
+
+// NewQueue creates queue holding up to capacity items, capacity below 1 is raised to 1.
+func NewQueue[T any](capacity int) *Queue[T] {
+	return &Queue[T]{
+		items: make(chan T, max(1, capacity)),
+		done:  make(chan struct{}),
+	}
+}
+
+// Push adds item waiting for free space until ctx is done or queue is closed.
+func (q *Queue[T]) Push(ctx context.Context, item T) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	select {
+	case q.items <- item:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-q.done:
+		return ErrQueueClosed
+	}
+}
+
+// TryPush adds item without waiting, returns ErrQueueFull if there is no free space.
+func (q *Queue[T]) TryPush(item T) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	select {
+	case q.items <- item:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Pop removes the oldest item waiting until it appears, ctx is done or queue is closed and empty.
+func (q *Queue[T]) Pop(ctx context.Context) (item T, err error) {
+	select {
+	case item = <-q.items:
+		return item, nil
+	case <-ctx.Done():
+		return item, ctx.Err()
+	case <-q.done:
+		// items pushed before Close are still returned
+		if item, ok := q.TryPop(); ok {
+			return item, nil
+		}
+		return item, ErrQueueClosed
+	}
+}
+
+// TryPop removes the oldest item without waiting, ok is false if queue is empty.
+func (q *Queue[T]) TryPop() (item T, ok bool) {
+	select {
+	case item = <-q.items:
+		return item, true
+	default:
+		return item, false
+	}
+}
+
+// PopBatch waits for the first item like Pop, then collects up to maxSize items
+// for at most maxWait, e.g. to write them in one query. Returns ErrQueueClosed only if no items are left.
+func (q *Queue[T]) PopBatch(ctx context.Context, maxSize int, maxWait time.Duration) ([]T, error) {
+	first, err := q.Pop(ctx)
+	if err != nil {
+		return nil, err
+	}
+	batch := make([]T, 1, max(1, maxSize))
+	batch[0] = first
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+	for len(batch) < maxSize {
+		select {
+		case item := <-q.items:
+			batch = append(batch, item)
+		case <-timer.C:
+			return batch, nil
+		case <-ctx.Done():
+			return batch, nil
+		case <-q.done:
+			// nothing can be pushed anymore, take what is left without waiting
+			for len(batch) < maxSize {
+				item, ok := q.TryPop()
+				if !ok {
+					break
+				}
+				batch = append(batch, item)
+			}
+			return batch, nil
+		}
+	}
+	return batch, nil
+}
+
+// Close rejects further pushes and wakes up blocked callers, safe to call multiple times.
+func (q *Queue[T]) Close() {
+	q.once.Do(func() {
+		close(q.done)
+		q.mu.Lock()
+		q.closed = true
+		q.mu.Unlock()
+	})
+}
+
+// Len returns number of items in the queue.
+func (q *Queue[T]) Len() int {
+	return len(q.items)
+}
+
+func (q *Queue[T]) Cap() int {
+	return cap(q.items)
+}
@@ -0,0 +1,96 @@
+package utils_test
+
+import (
+	"context"
+	"go_project_template/internal/utils"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueue(t *testing.T) {
+	t.Run("should keep order and bound", func(t *testing.T) {
+		q := utils.NewQueue[int](2)
+		require.NoError(t, q.TryPush(1))
+		require.NoError(t, q.Push(context.Background(), 2))
+		require.ErrorIs(t, q.TryPush(3), utils.ErrQueueFull)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, q.Push(ctx, 3), context.DeadlineExceeded)
+
+		item, ok := q.TryPop()
+		require.True(t, ok)
+		require.Equal(t, 1, item)
+		item, err := q.Pop(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 2, item)
+		_, ok = q.TryPop()
+		require.False(t, ok)
+	})
+	t.Run("should unblock pusher when item is popped", func(t *testing.T) {
+		q := utils.NewQueue[int](1)
+		require.NoError(t, q.TryPush(1))
+		pushed := make(chan error)
+		go func() {
+			pushed <- q.Push(context.Background(), 2)
+		}()
+		item, err := q.Pop(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 1, item)
+		require.NoError(t, <-pushed)
+		require.Equal(t, 1, q.Len())
+	})
+	t.Run("should drain items after close", func(t *testing.T) {
+		q := utils.NewQueue[int](3)
+		require.NoError(t, q.TryPush(1))
+		q.Close()
+		q.Close()
+		require.ErrorIs(t, q.TryPush(2), utils.ErrQueueClosed)
+		require.ErrorIs(t, q.Push(context.Background(), 2), utils.ErrQueueClosed)
+
+		item, err := q.Pop(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 1, item)
+		_, err = q.Pop(context.Background())
+		require.ErrorIs(t, err, utils.ErrQueueClosed)
+	})
+	t.Run("should wake up blocked callers on close", func(t *testing.T) {
+		q := utils.NewQueue[int](1)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := q.Pop(context.Background())
+			require.ErrorIs(t, err, utils.ErrQueueClosed)
+		}()
+		time.Sleep(10 * time.Millisecond)
+		q.Close()
+		wg.Wait()
+	})
+	t.Run("should pop batches by size and wait", func(t *testing.T) {
+		q := utils.NewQueue[int](10)
+		for i := 0; i < 5; i++ {
+			require.NoError(t, q.TryPush(i))
+		}
+		batch, err := q.PopBatch(context.Background(), 3, time.Second)
+		require.NoError(t, err)
+		require.Equal(t, []int{0, 1, 2}, batch)
+
+		start := time.Now()
+		batch, err = q.PopBatch(context.Background(), 3, 50*time.Millisecond)
+		require.NoError(t, err)
+		require.Equal(t, []int{3, 4}, batch)
+		require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+		require.NoError(t, q.TryPush(5))
+		q.Close()
+		batch, err = q.PopBatch(context.Background(), 3, time.Hour)
+		require.NoError(t, err)
+		require.Equal(t, []int{5}, batch)
+		_, err = q.PopBatch(context.Background(), 3, time.Hour)
+		require.ErrorIs(t, err, utils.ErrQueueClosed)
+	})
+}
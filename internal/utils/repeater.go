@@ -4,37 +4,87 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"math/rand/v2"
 	"reflect"
 	"time"
 )
 
-const DefaultFallbackTries = 5
+const (
+	DefaultFallbackTries     = 5
+	DefaultBackoffMultiplier = 2.0
+)
 
 var ErrorRepeatableFuncResNil = errors.New("RepeatableFunc result is nil")
 
 type RepeatableFunc[T any] func() (T, error)
 
+// RepeatableCtxFunc is a RepeatableFunc which receives the attempt context,
+// required for WithAttemptTimeout to be able to interrupt a single attempt.
+type RepeatableCtxFunc[T any] func(ctx context.Context) (T, error)
+
+// JitterMode defines how random noise is applied to the calculated backoff delay.
+// See https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+type JitterMode int
+
+const (
+	JitterNone         JitterMode = iota
+	JitterFull                    // random between 0 and delay
+	JitterEqual                   // delay/2 + random between 0 and delay/2
+	JitterDecorrelated            // random between base and previous delay * 3
+)
+
+type backoffMode int
+
+const (
+	backoffFixed backoffMode = iota
+	backoffLinear
+	backoffExponential
+)
+
+// OnRetryFunc is called before waiting for the next attempt.
+// attempt is the number of the failed attempt starting from 1.
+type OnRetryFunc func(attempt int, err error, delay time.Duration)
+
 type FuncRepeater[T any] struct {
-	ctx           context.Context
-	fn            RepeatableFunc[T]
-	fallback      *RepeatableFunc[T]
-	maxTries      int
-	fallbackTries int
-	triesTimeout  *time.Duration
-	linearTimeout bool
-	errMsg        string
-	exitErrors    []error
+	ctx            context.Context
+	fn             RepeatableCtxFunc[T]
+	fallback       *RepeatableCtxFunc[T]
+	maxTries       int
+	fallbackTries  int
+	triesTimeout   *time.Duration
+	backoff        backoffMode
+	multiplier     float64
+	maxDelay       time.Duration
+	jitter         JitterMode
+	attemptTimeout time.Duration
+	retryIf        func(err error) bool
+	onRetry        []OnRetryFunc
+	breaker        *CircuitBreaker
+	metrics        *RepeaterMetrics
+	metricsName    string
+	errMsg         string
+	exitErrors     []error
 }
 
 // Run repeats RepeatableFunc until any of these happens:
 // - maxTries reached if it is set and fallback is not
 // - fallback is set and maxTries + fallbackTries reached
-// - ctx.Done()
-// - exitErrors encountered
+// - ctx.Done(), including while waiting between attempts
+// - exitErrors encountered or retryIf predicate returned false
+// - circuit breaker rejected attempt
 // - non-nil result returned
-// it also records metrics to hist
+// it also records metrics if WithMetrics is set
 func (r *FuncRepeater[T]) Run() (res T, err error) {
+	started := time.Now()
+	res, outcome, err := r.run()
+	r.metrics.observe(r.metricsName, outcome, time.Since(started))
+	return res, err
+}
+
+func (r *FuncRepeater[T]) run() (res T, outcome string, err error) {
 	triesCount := 0
+	var prevDelay time.Duration
 
 	function := r.fn
 	for {
@@ -42,38 +92,45 @@ func (r *FuncRepeater[T]) Run() (res T, err error) {
 		// if fallback enabled we switch to fallback function and keep trying fallbackTries more
 		if r.maxTries > 0 && triesCount >= r.maxTries {
 			if r.fallback == nil || triesCount >= r.maxTries+r.fallbackTries {
-				return res, fmt.Errorf("maximum number of retries reached: %w", err)
+				return res, OutcomeExhausted, fmt.Errorf("maximum number of retries reached: %w", err)
 			}
 
 			function = *r.fallback
 		}
 
-		if r.triesTimeout != nil {
-			timeout := *r.triesTimeout
-			if r.linearTimeout {
-				timeout *= time.Duration(triesCount)
+		if triesCount > 0 {
+			delay := r.nextDelay(triesCount, prevDelay)
+			prevDelay = delay
+			for _, hook := range r.onRetry {
+				hook(triesCount, err, delay)
+			}
+			if !r.wait(delay) {
+				return res, OutcomeCanceled, r.ctxErr(err)
 			}
-			time.Sleep(timeout)
 		}
 
 		triesCount++
 
 		select {
 		case <-r.ctx.Done():
-			if err != nil {
-				return res, fmt.Errorf("%s: %w", r.errMsg, err)
-			}
-
-			return res, errors.New(r.errMsg)
+			return res, OutcomeCanceled, r.ctxErr(err)
 		default:
 			// run function
-			res, err = function()
+			r.metrics.attempt(r.metricsName)
+			res, err = r.callAttempt(function)
 			if err != nil {
+				if errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrTooManyProbes) {
+					// dependency is down, no reason to keep trying
+					return res, OutcomeCircuitOpen, err
+				}
 				for _, e := range r.exitErrors {
 					if errors.Is(err, e) {
-						return res, err
+						return res, OutcomeExitError, err
 					}
 				}
+				if r.retryIf != nil && !r.retryIf(err) {
+					return res, OutcomeNotRetryable, err
+				}
 
 				continue
 			}
@@ -87,17 +144,121 @@ func (r *FuncRepeater[T]) Run() (res T, err error) {
 				err = ErrorRepeatableFuncResNil
 				continue
 			}
-			return //nolint:nakedret // it's ok to return here
+			return res, OutcomeSuccess, nil
 		}
 	}
 }
 
+func (r *FuncRepeater[T]) callAttempt(function RepeatableCtxFunc[T]) (res T, err error) {
+	ctx := r.ctx
+	if r.attemptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(r.ctx, r.attemptTimeout)
+		defer cancel()
+	}
+	if r.breaker == nil {
+		return function(ctx)
+	}
+	return ExecuteWithBreaker(r.breaker, func() (T, error) {
+		return function(ctx)
+	})
+}
+
+// ctxErr wraps both ctx error and error of the last attempt, so callers can check either of them.
+func (r *FuncRepeater[T]) ctxErr(err error) error {
+	if err != nil {
+		return fmt.Errorf("%s: %w: %w", r.errMsg, r.ctx.Err(), err)
+	}
+	return fmt.Errorf("%s: %w", r.errMsg, r.ctx.Err())
+}
+
+// wait blocks for delay, returns false if ctx is done earlier.
+func (r *FuncRepeater[T]) wait(delay time.Duration) bool {
+	if delay <= 0 {
+		return true
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-r.ctx.Done():
+		return false
+	}
+}
+
+// nextDelay calculates pause before attempt number triesCount+1.
+func (r *FuncRepeater[T]) nextDelay(triesCount int, prevDelay time.Duration) time.Duration {
+	if r.triesTimeout == nil {
+		return 0
+	}
+	base := *r.triesTimeout
+	delay := base
+	switch r.backoff {
+	case backoffLinear:
+		delay = base * time.Duration(triesCount)
+	case backoffExponential:
+		delay = exponentialDelay(base, r.multiplier, triesCount)
+	}
+	delay = r.capDelay(delay)
+
+	switch r.jitter {
+	case JitterFull:
+		delay = randDuration(0, delay)
+	case JitterEqual:
+		delay = delay/2 + randDuration(0, delay/2)
+	case JitterDecorrelated:
+		// prevDelay*3 must not overflow when there is no maxDelay
+		prevDelay = min(max(prevDelay, base), math.MaxInt64/3)
+		delay = randDuration(base, prevDelay*3)
+	}
+	return r.capDelay(delay)
+}
+
+func (r *FuncRepeater[T]) capDelay(delay time.Duration) time.Duration {
+	if r.maxDelay > 0 && delay > r.maxDelay {
+		return r.maxDelay
+	}
+	return delay
+}
+
+// ExponentialBackoff returns pause after attempts failed tries in schedule of WithExponentialBackoff
+// without jitter, capped by maxDelay. It is meant for retries scheduled outside of FuncRepeater, e.g. stored in database.
+func ExponentialBackoff(base, maxDelay time.Duration, attempts int) time.Duration {
+	return min(exponentialDelay(base, DefaultBackoffMultiplier, max(1, attempts)), maxDelay)
+}
+
+// exponentialDelay returns base * multiplier^(triesCount-1) without overflow.
+func exponentialDelay(base time.Duration, multiplier float64, triesCount int) time.Duration {
+	exp := float64(base) * math.Pow(multiplier, float64(triesCount-1))
+	if exp >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(exp)
+}
+
+// randDuration returns random duration in [from, to).
+func randDuration(from, to time.Duration) time.Duration {
+	if to <= from {
+		return from
+	}
+	return from + rand.N(to-from) //nolint:gosec // jitter does not need crypto rand
+}
+
 func NewFuncRepeater[T any](fn RepeatableFunc[T]) *FuncRepeater[T] {
+	return NewCtxFuncRepeater(func(context.Context) (T, error) {
+		return fn()
+	})
+}
+
+// NewCtxFuncRepeater creates repeater for function which respects attempt context.
+func NewCtxFuncRepeater[T any](fn RepeatableCtxFunc[T]) *FuncRepeater[T] {
 	return &FuncRepeater[T]{
 		fn:            fn,
 		ctx:           context.Background(),
 		fallbackTries: DefaultFallbackTries,
-		linearTimeout: false,
+		backoff:       backoffFixed,
+		multiplier:    DefaultBackoffMultiplier,
 	}
 }
 
@@ -107,6 +268,12 @@ func (r *FuncRepeater[T]) WithCtx(ctx context.Context) *FuncRepeater[T] {
 }
 
 func (r *FuncRepeater[T]) WithFallback(fallback RepeatableFunc[T]) *FuncRepeater[T] {
+	return r.WithCtxFallback(func(context.Context) (T, error) {
+		return fallback()
+	})
+}
+
+func (r *FuncRepeater[T]) WithCtxFallback(fallback RepeatableCtxFunc[T]) *FuncRepeater[T] {
 	r.fallback = &fallback
 	return r
 }
@@ -121,13 +288,75 @@ func (r *FuncRepeater[T]) WithFallbackTries(num int) *FuncRepeater[T] {
 	return r
 }
 
+// WithTriesTimeout sets pause between attempts. It is the base delay for linear and exponential backoff.
 func (r *FuncRepeater[T]) WithTriesTimeout(timeout time.Duration) *FuncRepeater[T] {
 	r.triesTimeout = &timeout
 	return r
 }
 
+// WithLinearTimeout makes pause grow linearly: timeout * number of failed attempts.
 func (r *FuncRepeater[T]) WithLinearTimeout(linear bool) *FuncRepeater[T] {
-	r.linearTimeout = linear
+	if linear {
+		r.backoff = backoffLinear
+	} else if r.backoff == backoffLinear {
+		r.backoff = backoffFixed
+	}
+	return r
+}
+
+// WithExponentialBackoff makes pause grow as base * multiplier^(attempt-1), multiplier is 2 by default.
+func (r *FuncRepeater[T]) WithExponentialBackoff(base time.Duration) *FuncRepeater[T] {
+	r.triesTimeout = &base
+	r.backoff = backoffExponential
+	return r
+}
+
+func (r *FuncRepeater[T]) WithBackoffMultiplier(multiplier float64) *FuncRepeater[T] {
+	r.multiplier = multiplier
+	return r
+}
+
+// WithMaxDelay caps pause between attempts, jitter included.
+func (r *FuncRepeater[T]) WithMaxDelay(maxDelay time.Duration) *FuncRepeater[T] {
+	r.maxDelay = maxDelay
+	return r
+}
+
+func (r *FuncRepeater[T]) WithJitter(jitter JitterMode) *FuncRepeater[T] {
+	r.jitter = jitter
+	return r
+}
+
+// WithAttemptTimeout limits duration of every single attempt.
+// Only functions created with NewCtxFuncRepeater / WithCtxFallback can be interrupted.
+func (r *FuncRepeater[T]) WithAttemptTimeout(timeout time.Duration) *FuncRepeater[T] {
+	r.attemptTimeout = timeout
+	return r
+}
+
+// WithRetryIf stops repeating and returns error as is if retryIf returns false for it.
+func (r *FuncRepeater[T]) WithRetryIf(retryIf func(err error) bool) *FuncRepeater[T] {
+	r.retryIf = retryIf
+	return r
+}
+
+// WithOnRetry registers hook called after every failed attempt which will be retried.
+func (r *FuncRepeater[T]) WithOnRetry(hooks ...OnRetryFunc) *FuncRepeater[T] {
+	r.onRetry = append(r.onRetry, hooks...)
+	return r
+}
+
+// WithCircuitBreaker runs every attempt through breaker.
+// Repeater stops immediately with ErrCircuitOpen when breaker rejects attempt.
+func (r *FuncRepeater[T]) WithCircuitBreaker(breaker *CircuitBreaker) *FuncRepeater[T] {
+	r.breaker = breaker
+	return r
+}
+
+// WithMetrics records attempts and outcomes to metrics, name is used as label value.
+func (r *FuncRepeater[T]) WithMetrics(metrics *RepeaterMetrics, name string) *FuncRepeater[T] {
+	r.metrics = metrics
+	r.metricsName = name
 	return r
 }
 
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	OutcomeSuccess      = "success"
+	OutcomeExhausted    = "exhausted"
+	OutcomeCanceled     = "canceled"
+	OutcomeExitError    = "exit_error"
+	OutcomeNotRetryable = "not_retryable"
+	OutcomeCircuitOpen  = "circuit_open"
+)
+
+// RepeaterMetrics holds prometheus collectors for FuncRepeater.
+// One instance can be shared by many repeaters, they are distinguished by name label.
+type RepeaterMetrics struct {
+	attempts *prometheus.CounterVec
+	outcomes *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+func NewRepeaterMetrics(namespace string) *RepeaterMetrics {
+	return &RepeaterMetrics{
+		attempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "repeater",
+			Name:      "attempts_total",
+			Help:      "Number of attempts made by repeater.",
+		}, []string{"name"}),
+		outcomes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "repeater",
+			Name:      "runs_total",
+			Help:      "Number of repeater runs by outcome.",
+		}, []string{"name", "outcome"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "repeater",
+			Name:      "run_duration_seconds",
+			Help:      "Duration of repeater run including all attempts and pauses.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"name", "outcome"}),
+	}
+}
+
+// Collectors returns collectors to be registered in prometheus registry.
+func (m *RepeaterMetrics) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.attempts, m.outcomes, m.duration}
+}
+
+func (m *RepeaterMetrics) attempt(name string) {
+	if m == nil {
+		return
+	}
+	m.attempts.WithLabelValues(name).Inc()
+}
+
+func (m *RepeaterMetrics) observe(name, outcome string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.outcomes.WithLabelValues(name, outcome).Inc()
+	m.duration.WithLabelValues(name, outcome).Observe(duration.Seconds())
+}
@@ -2,14 +2,18 @@ package utils_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"go_project_template/internal/utils"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 )
 
@@ -181,3 +185,116 @@ func Test_FuncRepeater(t *testing.T) {
 		require.Equal(t, uint32(1), res)
 	})
 }
+
+func Test_FuncRepeater_Backoff(t *testing.T) {
+	errTest := errors.New("test error")
+	failing := func() (int, error) {
+		return 0, errTest
+	}
+	t.Run("should grow delay exponentially up to max delay", func(t *testing.T) {
+		delays := make([]time.Duration, 0, 5)
+		_, err := utils.NewFuncRepeater(failing).
+			WithExponentialBackoff(time.Millisecond).
+			WithMaxDelay(4 * time.Millisecond).
+			WithOnRetry(func(_ int, err error, delay time.Duration) {
+				require.ErrorIs(t, err, errTest)
+				delays = append(delays, delay)
+			}).
+			WithMaxTries(6).Run()
+		require.ErrorIs(t, err, errTest)
+		require.Equal(t, []time.Duration{
+			time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond,
+		}, delays)
+	})
+	t.Run("should keep jitter in bounds", func(t *testing.T) {
+		testCases := map[utils.JitterMode][2]time.Duration{
+			utils.JitterFull:         {0, 8 * time.Millisecond},
+			utils.JitterEqual:        {time.Millisecond / 2, 8 * time.Millisecond},
+			utils.JitterDecorrelated: {time.Millisecond, 8 * time.Millisecond},
+		}
+		for mode, bounds := range testCases {
+			_, err := utils.NewFuncRepeater(failing).
+				WithExponentialBackoff(time.Millisecond).
+				WithMaxDelay(8 * time.Millisecond).
+				WithJitter(mode).
+				WithOnRetry(func(_ int, _ error, delay time.Duration) {
+					require.GreaterOrEqual(t, delay, bounds[0])
+					require.LessOrEqual(t, delay, bounds[1])
+				}).
+				WithMaxTries(6).Run()
+			require.ErrorIs(t, err, errTest)
+		}
+	})
+	t.Run("should stop waiting when context is done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		started := time.Now()
+		_, err := utils.NewFuncRepeater(failing).
+			WithCtx(ctx).
+			WithErrMsg("sample").
+			WithTriesTimeout(time.Hour).Run()
+		require.ErrorIs(t, err, errTest)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Less(t, time.Since(started), time.Second)
+
+		ctx, cancel = context.WithCancel(context.Background())
+		cancel()
+		_, err = utils.NewFuncRepeater(failing).WithCtx(ctx).Run()
+		require.ErrorIs(t, err, context.Canceled)
+	})
+	t.Run("should stop when retryIf returns false", func(t *testing.T) {
+		cnt := 0
+		_, err := utils.NewFuncRepeater(func() (int, error) {
+			cnt++
+			return 0, errTest
+		}).WithRetryIf(func(err error) bool {
+			return cnt < 3
+		}).Run()
+		require.ErrorIs(t, err, errTest)
+		require.Equal(t, 3, cnt)
+	})
+	t.Run("should interrupt attempt after attempt timeout", func(t *testing.T) {
+		cnt := 0
+		res, err := utils.NewCtxFuncRepeater(func(ctx context.Context) (int, error) {
+			cnt++
+			if cnt == 1 {
+				<-ctx.Done()
+				return 0, ctx.Err()
+			}
+			return cnt, nil
+		}).WithAttemptTimeout(10 * time.Millisecond).WithMaxTries(3).Run()
+		require.NoError(t, err)
+		require.Equal(t, 2, res)
+	})
+	t.Run("should record metrics", func(t *testing.T) {
+		metrics := utils.NewRepeaterMetrics("test")
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(metrics.Collectors()...)
+		_, err := utils.NewFuncRepeater(failing).WithMetrics(metrics, "failing").WithMaxTries(3).Run()
+		require.Error(t, err)
+		_, err = utils.NewFuncRepeater(func() (int, error) { return 1, nil }).WithMetrics(metrics, "ok").Run()
+		require.NoError(t, err)
+
+		expected := `
+# HELP test_repeater_runs_total Number of repeater runs by outcome.
+# TYPE test_repeater_runs_total counter
+test_repeater_runs_total{name="failing",outcome="exhausted"} 1
+test_repeater_runs_total{name="ok",outcome="success"} 1
+# HELP test_repeater_attempts_total Number of attempts made by repeater.
+# TYPE test_repeater_attempts_total counter
+test_repeater_attempts_total{name="failing"} 3
+test_repeater_attempts_total{name="ok"} 1
+`
+		require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected),
+			"test_repeater_runs_total", "test_repeater_attempts_total"))
+	})
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	delays := make([]time.Duration, 0, 5)
+	for attempts := 1; attempts <= 5; attempts++ {
+		delays = append(delays, utils.ExponentialBackoff(time.Second, 5*time.Second, attempts))
+	}
+	require.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}, delays)
+	require.Equal(t, time.Hour, utils.ExponentialBackoff(time.Second, time.Hour, 1000))
+}
@@ -4,14 +4,23 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
 )
 
+// ErrServerErrorStatus is reported to circuit breaker when upstream responds with 5xx status.
+var ErrServerErrorStatus = errors.New("server error status")
+
 type CurlConf[T any] struct {
-	Decoder func(io.Reader) error
+	Decoder      func(io.Reader) error
+	Breaker      *CircuitBreaker
+	Client       *HTTPClient
+	HTTPErrors   bool
+	ErrorDecoder func(body []byte) (any, error)
+	SingleFlight bool
+	Cache        *HTTPResponseCache
 }
 
 type CurlOpts[T any] func(*CurlConf[T])
@@ -22,34 +31,70 @@ func WithDecoder[T any](decoder func(io.Reader) error) func(*CurlConf[T]) {
 	}
 }
 
-func PatchCurl[T any](ctx context.Context, targetURL string, payload any, headers map[string]string) (res *T, statusCode int, err error) {
+// WithCircuitBreaker runs request through breaker, transport errors and 5xx responses are counted as failures,
+// 429 responses are not.
+// When breaker is open request is not sent and ErrCircuitOpen is returned.
+func WithCircuitBreaker[T any](breaker *CircuitBreaker) func(*CurlConf[T]) {
+	return func(c *CurlConf[T]) {
+		c.Breaker = breaker
+	}
+}
+
+// WithClient sends request using client instead of DefaultHTTPClient.
+func WithClient[T any](client *HTTPClient) func(*CurlConf[T]) {
+	return func(c *CurlConf[T]) {
+		c.Client = client
+	}
+}
+
+// WithSingleFlight coalesces concurrent identical GET requests (same url and headers) into one upstream call.
+func WithSingleFlight[T any]() func(*CurlConf[T]) {
+	return func(c *CurlConf[T]) {
+		c.SingleFlight = true
+	}
+}
+
+// WithResponseCache serves GET requests from cache, see HTTPResponseCache. Cache misses are always coalesced.
+func WithResponseCache[T any](cache *HTTPResponseCache) func(*CurlConf[T]) {
+	return func(c *CurlConf[T]) {
+		c.Cache = cache
+	}
+}
+
+func PatchCurl[T any](ctx context.Context, targetURL string, payload any, headers map[string]string, opts ...CurlOpts[T]) (res *T, statusCode int, err error) {
 	payloadJSON, err := json.Marshal(payload)
 	if err != nil {
 		return res, 0, fmt.Errorf("unable to marshal payload: %w", err)
 	}
-	return CurlWithBody[T](ctx, http.MethodPatch, targetURL, payloadJSON, headers)
+	return CurlWithBody[T](ctx, http.MethodPatch, targetURL, payloadJSON, headers, opts...)
 }
 
-func PostCurl[T any](ctx context.Context, targetURL string, payload any, headers map[string]string) (res *T, statusCode int, err error) {
+func PostCurl[T any](ctx context.Context, targetURL string, payload any, headers map[string]string, opts ...CurlOpts[T]) (res *T, statusCode int, err error) {
 	payloadJSON, err := json.Marshal(payload)
 	if err != nil {
 		return res, 0, fmt.Errorf("unable to marshal payload: %w", err)
 	}
-	return CurlWithBody[T](ctx, http.MethodPost, targetURL, payloadJSON, headers)
+	return CurlWithBody[T](ctx, http.MethodPost, targetURL, payloadJSON, headers, opts...)
 }
 
-func CurlWithBody[T any](ctx context.Context, method, targetURL string, payloadJSON []byte, headers map[string]string) (res *T, statusCode int, err error) {
+func CurlWithBody[T any](ctx context.Context, method, targetURL string, payloadJSON []byte, headers map[string]string, opts ...CurlOpts[T]) (res *T, statusCode int, err error) {
+	config := &CurlConf[T]{}
+	for _, opt := range opts {
+		opt(config)
+	}
 	// todo inject tracer
-	req, err := http.NewRequestWithContext(ctx, method, targetURL, bytes.NewBuffer(payloadJSON))
-	if err != nil {
-		return res, 0, fmt.Errorf("unable to create request: %w", err)
+	req := &HTTPRequest{
+		Method: method,
+		URL:    targetURL,
+		Header: make(http.Header, len(headers)+2),
+		Body:   payloadJSON,
 	}
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Content-Type", "application/json")
 	for k, v := range headers {
 		req.Header.Add(k, v)
 	}
-	return executeWithDefaultClient[T](req, nil)
+	return executeWithClient(ctx, req, config)
 }
 
 // GetCurl is a generic function to send GET request with headers and return response
@@ -57,41 +102,74 @@ func CurlWithBody[T any](ctx context.Context, method, targetURL string, payloadJ
 // T is a type of response, automatically unmarshalled from json
 // check status code first. in some cases response can be different, so unmarsharlling will fail
 // status code return as is even in unmarshalling error
+// with WithHTTPErrors option non-2xx responses are returned as *HTTPError instead
 func GetCurl[T any](ctx context.Context, targetURL string, headers map[string]string, opts ...CurlOpts[T]) (res *T, statusCode int, err error) {
 	config := &CurlConf[T]{}
 	for _, opt := range opts {
 		opt(config)
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetURL, http.NoBody)
-	if err != nil {
-		return res, 0, fmt.Errorf("unable to create request: %w", err)
+	req := &HTTPRequest{
+		Method: http.MethodGet,
+		URL:    targetURL,
+		Header: make(http.Header, len(headers)),
 	}
 	for k, v := range headers {
 		req.Header.Add(k, v)
 	}
-	return executeWithDefaultClient[T](req, config.Decoder)
+	return executeWithClient(ctx, req, config)
 }
 
-func executeWithDefaultClient[T any](req *http.Request, decoder func(io.Reader) error) (res *T, statusCode int, err error) {
-	client := http.DefaultClient
-	resp, err := client.Do(req)
-	if err != nil {
-		return res, 0, fmt.Errorf("failed to send request: %w", err)
+func executeWithClient[T any](ctx context.Context, req *HTTPRequest, config *CurlConf[T]) (res *T, statusCode int, err error) {
+	client := config.Client
+	if client == nil {
+		client = DefaultHTTPClient
 	}
-	defer resp.Body.Close()
-	if decoder != nil {
-		return nil, resp.StatusCode, decoder(resp.Body)
+	var resp *HTTPResponse
+	switch {
+	case req.Method == http.MethodGet && config.Cache != nil:
+		resp, err = config.Cache.do(ctx, client, req, config.Breaker)
+	case req.Method == http.MethodGet && config.SingleFlight:
+		resp, _, err = client.doShared(ctx, httpRequestKey(client, req), req, config.Breaker)
+	default:
+		resp, err = client.Do(ctx, req, config.Breaker)
 	}
-	b, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return res, 0, fmt.Errorf("failed to read response body: %w", err)
+		return res, 0, err
+	}
+	if config.HTTPErrors && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+		return res, resp.StatusCode, newHTTPError(req, resp, config.ErrorDecoder)
+	}
+	if config.Decoder != nil {
+		return nil, resp.StatusCode, config.Decoder(bytes.NewReader(resp.Body))
 	}
 	var result T
-	if strings.TrimSpace(strings.ReplaceAll(string(b), "\"", "")) != "" {
-		if err = json.Unmarshal(b, &result); err != nil {
+	if !isEmptyBody(resp.Body) {
+		if err = json.Unmarshal(resp.Body, &result); err != nil {
 			return res, resp.StatusCode, fmt.Errorf("failed to unmarshal response: %w", err)
 		}
 	}
 
 	return &result, resp.StatusCode, nil
 }
+
+// isEmptyBody reports whether body has nothing to unmarshal: only whitespaces or empty json string.
+func isEmptyBody(body []byte) bool {
+	trimmed := bytes.TrimSpace(body)
+	return len(trimmed) == 0 || bytes.Equal(trimmed, []byte(`""`))
+}
+
+func newHTTPError(req *HTTPRequest, resp *HTTPResponse, decoder func(body []byte) (any, error)) *HTTPError {
+	httpErr := &HTTPError{
+		Method:     req.Method,
+		URL:        req.URL,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       resp.Body,
+	}
+	if decoder != nil && !isEmptyBody(resp.Body) {
+		if decoded, err := decoder(resp.Body); err == nil {
+			httpErr.Decoded = decoded
+		}
+	}
+	return httpErr
+}
@@ -1,6 +1,9 @@
 package utils
 
-import "sync"
+import (
+	"iter"
+	"sync"
+)
 
 // RWMap provides a thread-safe map implementation using read-write locks.
 type RWMap[K comparable, V any] struct {
@@ -73,6 +76,79 @@ func (rm *RWMap[K, V]) Store(key K, value V) {
 	rm.mu.Unlock()
 }
 
+// LoadOrStore returns the existing value of key if present, otherwise it stores and returns value.
+// loaded is true if value was loaded.
+func (rm *RWMap[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	if actual, loaded = rm.Internal[key]; loaded {
+		return actual, true
+	}
+	rm.Internal[key] = value
+	return value, false
+}
+
+// LoadAndDelete deletes key returning its previous value, loaded reports if key was present.
+func (rm *RWMap[K, V]) LoadAndDelete(key K) (value V, loaded bool) {
+	rm.mu.Lock()
+	value, loaded = rm.Internal[key]
+	delete(rm.Internal, key)
+	rm.mu.Unlock()
+	return value, loaded
+}
+
+// CompareAndSwapFunc stores new value if key holds value equal to old value by equal function.
+func (rm *RWMap[K, V]) CompareAndSwapFunc(key K, oldValue, newValue V, equal func(a, b V) bool) bool {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	current, ok := rm.Internal[key]
+	if !ok || !equal(current, oldValue) {
+		return false
+	}
+	rm.Internal[key] = newValue
+	return true
+}
+
+// Compute sets value of key to the result of fn called with current value, loaded is false if key is missing.
+// Key is deleted if fn returns keep false. Returns the resulting value and if key is present.
+// fn is called under write lock, so it must not use the map.
+func (rm *RWMap[K, V]) Compute(key K, fn func(value V, loaded bool) (newValue V, keep bool)) (value V, ok bool) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	current, loaded := rm.Internal[key]
+	value, ok = fn(current, loaded)
+	if !ok {
+		delete(rm.Internal, key)
+		var zero V
+		return zero, false
+	}
+	rm.Internal[key] = value
+	return value, true
+}
+
+// Update replaces value of existing key with the result of fn, returns false if key is missing.
+// fn is called under write lock, so it must not use the map.
+func (rm *RWMap[K, V]) Update(key K, fn func(value V) V) bool {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	current, ok := rm.Internal[key]
+	if ok {
+		rm.Internal[key] = fn(current)
+	}
+	return ok
+}
+
+// All returns iterator over a copy of the map taken when iteration starts, so loop body may use the map.
+func (rm *RWMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for key, value := range rm.LoadAll() {
+			if !yield(key, value) {
+				return
+			}
+		}
+	}
+}
+
 // Keys returns a slice of all keys in the map.
 func (rm *RWMap[K, V]) Keys() []K {
 	rm.mu.Lock()
@@ -84,19 +160,45 @@ func (rm *RWMap[K, V]) Keys() []K {
 	return result
 }
 
-// Range calls the provided function for each key/value pair in the map.
+// KeysSeq returns iterator over keys of the map copy, see All.
+func (rm *RWMap[K, V]) KeysSeq() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for key := range rm.All() {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns iterator over values of the map copy, see All.
+func (rm *RWMap[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, value := range rm.All() {
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
+
+// Range calls the provided function for each key/value pair of the map copy, so f may use the map.
 // If the function returns false, iteration stops.
 func (rm *RWMap[K, V]) Range(f func(key K, value V) bool) {
-	rm.mu.RLock()
-	defer rm.mu.RUnlock()
-
-	for k, v := range rm.Internal {
+	for k, v := range rm.All() {
 		if !f(k, v) {
 			break
 		}
 	}
 }
 
+// Len returns number of entries in the map.
+func (rm *RWMap[K, V]) Len() int {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	return len(rm.Internal)
+}
+
 // Do executes a function on a value under read lock. Returns false if the key is not found.
 func (rm *RWMap[K, V]) Do(key K, fn func(value V)) bool {
 	rm.mu.RLock()
@@ -2,6 +2,7 @@ package utils_test
 
 import (
 	"go_project_template/internal/utils"
+	"slices"
 	"sync"
 	"testing"
 
@@ -84,3 +85,75 @@ func testRWMap(t *testing.T, rwMap *utils.RWMap[int, string]) {
 		})
 	})
 }
+
+func TestRWMap_AtomicUpdates(t *testing.T) {
+	t.Run("should load or store", func(t *testing.T) {
+		rwMap := utils.NewRWMap[string, int]()
+		actual, loaded := rwMap.LoadOrStore("a", 1)
+		require.False(t, loaded)
+		require.Equal(t, 1, actual)
+		actual, loaded = rwMap.LoadOrStore("a", 2)
+		require.True(t, loaded)
+		require.Equal(t, 1, actual)
+
+		value, loaded := rwMap.LoadAndDelete("a")
+		require.True(t, loaded)
+		require.Equal(t, 1, value)
+		_, loaded = rwMap.LoadAndDelete("a")
+		require.False(t, loaded)
+	})
+	t.Run("should compare and swap", func(t *testing.T) {
+		rwMap := utils.NewRWMap[string, []int]()
+		require.False(t, rwMap.CompareAndSwapFunc("a", nil, []int{1}, slices.Equal), "missing key is not swapped")
+		rwMap.Store("a", []int{1})
+		require.False(t, rwMap.CompareAndSwapFunc("a", []int{2}, []int{3}, slices.Equal))
+		require.True(t, rwMap.CompareAndSwapFunc("a", []int{1}, []int{3}, slices.Equal), "works for not comparable values")
+		value, _ := rwMap.Load("a")
+		require.Equal(t, []int{3}, value)
+	})
+	t.Run("should compute concurrently without lost updates", func(t *testing.T) {
+		rwMap := utils.NewRWMap[string, int]()
+		var wg sync.WaitGroup
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rwMap.Compute("counter", func(value int, _ bool) (int, bool) {
+					return value + 1, true
+				})
+			}()
+		}
+		wg.Wait()
+		value, _ := rwMap.Load("counter")
+		require.Equal(t, 100, value)
+
+		require.True(t, rwMap.Update("counter", func(value int) int { return value * 2 }))
+		require.False(t, rwMap.Update("missing", func(value int) int { return value }))
+		value, ok := rwMap.Compute("counter", func(value int, loaded bool) (int, bool) {
+			require.True(t, loaded)
+			require.Equal(t, 200, value)
+			return 0, false
+		})
+		require.False(t, ok)
+		require.Zero(t, value)
+		require.Equal(t, 0, rwMap.Len())
+	})
+	t.Run("should iterate without holding lock", func(t *testing.T) {
+		rwMap := utils.NewRWMapFromStdMap(map[int]string{1: "a", 2: "b"})
+		seen := make(map[int]string)
+		for key, value := range rwMap.All() {
+			seen[key] = value
+			rwMap.Store(key+10, value) // would deadlock under read lock
+		}
+		require.Equal(t, map[int]string{1: "a", 2: "b"}, seen)
+		require.ElementsMatch(t, []int{1, 2, 11, 12}, rwMap.Keys())
+		require.ElementsMatch(t, []int{1, 2, 11, 12}, slices.Collect(rwMap.KeysSeq()))
+		require.ElementsMatch(t, []string{"a", "b", "a", "b"}, slices.Collect(rwMap.Values()))
+
+		rwMap.Range(func(key int, _ string) bool {
+			rwMap.Delete(key)
+			return true
+		})
+		require.Equal(t, 0, rwMap.Len())
+	})
+}
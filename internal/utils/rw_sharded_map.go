@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"hash/maphash"
+	"iter"
+	"slices"
+)
+
+type rwMapShard[K comparable, V any] struct {
+	RWMap[K, V]
+	_ [32]byte // pads to 64 B (1 cache line) so adjacent shards don't cause false sharing
+}
+
+// ShardedRWMap is RWMap split into shards like TTLMap, so writers of different keys rarely contend.
+// Operations on a single key are atomic, All and LoadAll see shards at different moments.
+type ShardedRWMap[K comparable, V any] struct {
+	shards [numShards]rwMapShard[K, V]
+	seed   maphash.Seed
+}
+
+func NewShardedRWMap[K comparable, V any]() *ShardedRWMap[K, V] {
+	m := &ShardedRWMap[K, V]{seed: maphash.MakeSeed()}
+	for i := range m.shards {
+		m.shards[i].Internal = make(map[K]V)
+	}
+	return m
+}
+
+// shardOf returns the shard responsible for key.
+func (m *ShardedRWMap[K, V]) shardOf(key K) *RWMap[K, V] {
+	return &m.shards[maphash.Comparable(m.seed, key)&shardMask].RWMap
+}
+
+func (m *ShardedRWMap[K, V]) Load(key K) (value V, ok bool) {
+	return m.shardOf(key).Load(key)
+}
+
+func (m *ShardedRWMap[K, V]) Store(key K, value V) {
+	m.shardOf(key).Store(key, value)
+}
+
+func (m *ShardedRWMap[K, V]) Delete(key K) {
+	m.shardOf(key).Delete(key)
+}
+
+// LoadOrStore returns the existing value of key if present, otherwise it stores and returns value.
+func (m *ShardedRWMap[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+	return m.shardOf(key).LoadOrStore(key, value)
+}
+
+// LoadAndDelete deletes key returning its previous value, loaded reports if key was present.
+func (m *ShardedRWMap[K, V]) LoadAndDelete(key K) (value V, loaded bool) {
+	return m.shardOf(key).LoadAndDelete(key)
+}
+
+// CompareAndSwapFunc stores new value if key holds value equal to old value by equal function.
+func (m *ShardedRWMap[K, V]) CompareAndSwapFunc(key K, oldValue, newValue V, equal func(a, b V) bool) bool {
+	return m.shardOf(key).CompareAndSwapFunc(key, oldValue, newValue, equal)
+}
+
+// Compute works like RWMap.Compute, fn is called under lock of key shard.
+func (m *ShardedRWMap[K, V]) Compute(key K, fn func(value V, loaded bool) (newValue V, keep bool)) (value V, ok bool) {
+	return m.shardOf(key).Compute(key, fn)
+}
+
+// Update works like RWMap.Update, fn is called under lock of key shard.
+func (m *ShardedRWMap[K, V]) Update(key K, fn func(value V) V) bool {
+	return m.shardOf(key).Update(key, fn)
+}
+
+// Len returns the total number of entries across all shards.
+func (m *ShardedRWMap[K, V]) Len() int {
+	total := 0
+	for i := range m.shards {
+		total += m.shards[i].Len()
+	}
+	return total
+}
+
+// LoadAll returns a copy of all entries.
+func (m *ShardedRWMap[K, V]) LoadAll() map[K]V {
+	result := make(map[K]V)
+	for key, value := range m.All() {
+		result[key] = value
+	}
+	return result
+}
+
+// All returns iterator copying every shard when it is reached, so loop body may use the map.
+func (m *ShardedRWMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for i := range m.shards {
+			for key, value := range m.shards[i].All() {
+				if !yield(key, value) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Keys returns a slice of all keys, see All.
+func (m *ShardedRWMap[K, V]) Keys() []K {
+	return slices.Collect(m.KeysSeq())
+}
+
+// KeysSeq returns iterator over keys, see All.
+func (m *ShardedRWMap[K, V]) KeysSeq() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for key := range m.All() {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns iterator over values, see All.
+func (m *ShardedRWMap[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, value := range m.All() {
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package utils_test
+
+import (
+	"go_project_template/internal/utils"
+	"maps"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedRWMap(t *testing.T) {
+	m := utils.NewShardedRWMap[string, int]()
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := strconv.Itoa(i)
+			_, loaded := m.LoadOrStore(key, i)
+			require.False(t, loaded)
+			require.True(t, m.CompareAndSwapFunc(key, i, i*2, func(a, b int) bool { return a == b }))
+			require.True(t, m.Update(key, func(value int) int { return value + 1 }))
+			m.Compute("total", func(value int, _ bool) (int, bool) {
+				return value + 1, true
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	require.Equal(t, 101, m.Len())
+	total, ok := m.Load("total")
+	require.True(t, ok)
+	require.Equal(t, 100, total)
+	value, ok := m.Load("10")
+	require.True(t, ok)
+	require.Equal(t, 21, value)
+
+	all := m.LoadAll()
+	require.Len(t, all, 101)
+	require.Equal(t, all, maps.Collect(m.All()))
+	require.Len(t, m.Keys(), 101)
+	for key := range m.KeysSeq() {
+		if key != "total" {
+			m.Delete(key)
+		}
+	}
+	value, loaded := m.LoadAndDelete("total")
+	require.True(t, loaded)
+	require.Equal(t, 100, value)
+	require.Equal(t, 0, m.Len())
+}
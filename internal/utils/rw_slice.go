@@ -1,6 +1,9 @@
 package utils
 
-import "sync"
+import (
+	"iter"
+	"sync"
+)
 
 type RWSlice[V any] struct {
 	mu       sync.RWMutex
@@ -51,3 +54,26 @@ func (rw *RWSlice[V]) Erase() {
 	rw.Internal = make([]V, 0, len(rw.Internal))
 	rw.mu.Unlock()
 }
+
+// All returns iterator over elements present when iteration starts, added elements are not visited.
+func (rw *RWSlice[V]) All() iter.Seq2[int, V] {
+	return func(yield func(int, V) bool) {
+		// elements are only appended, so snapshot of the header is never changed under us
+		for i, value := range rw.LoadAll() {
+			if !yield(i, value) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns iterator over element values, see All.
+func (rw *RWSlice[V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, value := range rw.All() {
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
@@ -94,3 +94,22 @@ func equalSlices[V comparable](a, b []V) bool {
 	}
 	return true
 }
+
+func TestRWSlice_Iterators(t *testing.T) {
+	rwSlice := utils.NewRWSlice[int]()
+	rwSlice.AddBulk([]int{1, 2, 3})
+
+	var values []int
+	for i, value := range rwSlice.All() {
+		require.Equal(t, i+1, value)
+		rwSlice.Add(value) // appended elements are not visited
+	}
+	for value := range rwSlice.Values() {
+		values = append(values, value)
+		if len(values) == 2 {
+			break
+		}
+	}
+	require.Equal(t, []int{1, 2}, values)
+	require.Equal(t, 6, rwSlice.Len())
+}
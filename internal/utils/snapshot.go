@@ -0,0 +1,303 @@
+package utils
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// snapshot layout: magic(4) | version(2) | created nano(8) | entries count(8) | entries | crc32(4).
+// Entry is expiry nano(8) | key len(uvarint) | key | value len(uvarint) | value, expiry 0 means never.
+// Checksum covers everything before itself.
+const (
+	SnapshotVersion = 1
+
+	snapshotHeaderSize = 22
+)
+
+var snapshotMagic = []byte("CSNP")
+
+var (
+	ErrSnapshotCorrupted     = errors.New("snapshot is corrupted")
+	ErrSnapshotVersion       = errors.New("unsupported snapshot version")
+	ErrSnapshotNotConfigured = errors.New("snapshot is not configured")
+)
+
+var snapshotCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Codec converts keys and values of a cache to bytes and back.
+type Codec[T any] struct {
+	Encode func(value T) ([]byte, error)
+	Decode func(data []byte) (T, error)
+}
+
+func StringCodec() Codec[string] {
+	return Codec[string]{
+		Encode: func(value string) ([]byte, error) { return []byte(value), nil },
+		Decode: func(data []byte) (string, error) { return string(data), nil },
+	}
+}
+
+func BytesCodec() Codec[[]byte] {
+	return Codec[[]byte]{
+		Encode: func(value []byte) ([]byte, error) { return value, nil },
+		Decode: func(data []byte) ([]byte, error) { return data, nil },
+	}
+}
+
+func JSONCodec[T any]() Codec[T] {
+	return Codec[T]{
+		Encode: func(value T) ([]byte, error) { return json.Marshal(value) },
+		Decode: func(data []byte) (res T, err error) {
+			err = json.Unmarshal(data, &res)
+			return res, err
+		},
+	}
+}
+
+// SnapshotEntry is a single cache item, zero ExpiresAt means item never expires.
+type SnapshotEntry[K any, V any] struct {
+	Key       K
+	Value     V
+	ExpiresAt time.Time
+}
+
+// SnapshotConf describes where and how cache is saved.
+type SnapshotConf[K any, V any] struct {
+	Path   string
+	Keys   Codec[K]
+	Values Codec[V]
+}
+
+// WriteSnapshot writes entries in given order to temporary file and atomically renames it to path.
+func WriteSnapshot[K any, V any](conf SnapshotConf[K, V], entries []SnapshotEntry[K, V]) error {
+	dir := filepath.Dir(conf.Path)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("create snapshot dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(conf.Path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("create snapshot file: %w", err)
+	}
+	defer func() {
+		// no-op after successful rename
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	crc := crc32.New(snapshotCRCTable)
+	w := bufio.NewWriter(io.MultiWriter(tmp, crc))
+	if err := writeSnapshotEntries(w, conf, entries); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	if err := binary.Write(tmp, binary.LittleEndian, crc.Sum32()); err != nil {
+		return fmt.Errorf("write snapshot checksum: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("sync snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), conf.Path); err != nil {
+		return fmt.Errorf("replace snapshot: %w", err)
+	}
+	return nil
+}
+
+func writeSnapshotEntries[K any, V any](w *bufio.Writer, conf SnapshotConf[K, V], entries []SnapshotEntry[K, V]) error {
+	header := make([]byte, snapshotHeaderSize)
+	copy(header, snapshotMagic)
+	binary.LittleEndian.PutUint16(header[4:], SnapshotVersion)
+	binary.LittleEndian.PutUint64(header[6:], uint64(time.Now().UnixNano()))
+	binary.LittleEndian.PutUint64(header[14:], uint64(len(entries)))
+	if _, err := w.Write(header); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	buf := make([]byte, 0, 64)
+	for _, entry := range entries {
+		key, err := conf.Keys.Encode(entry.Key)
+		if err != nil {
+			return fmt.Errorf("encode snapshot key: %w", err)
+		}
+		value, err := conf.Values.Encode(entry.Value)
+		if err != nil {
+			return fmt.Errorf("encode snapshot value: %w", err)
+		}
+		expiryNano := int64(0)
+		if !entry.ExpiresAt.IsZero() {
+			expiryNano = entry.ExpiresAt.UnixNano()
+		}
+		buf = binary.LittleEndian.AppendUint64(buf[:0], uint64(expiryNano))
+		buf = binary.AppendUvarint(buf, uint64(len(key)))
+		buf = append(buf, key...)
+		buf = binary.AppendUvarint(buf, uint64(len(value)))
+		buf = append(buf, value...)
+		if _, err := w.Write(buf); err != nil {
+			return fmt.Errorf("write snapshot: %w", err)
+		}
+	}
+	return nil
+}
+
+// ReadSnapshot reads entries in order they were written, expired entries are skipped.
+// Missing file is not an error, it results in no entries.
+func ReadSnapshot[K any, V any](conf SnapshotConf[K, V]) ([]SnapshotEntry[K, V], error) {
+	data, err := os.ReadFile(conf.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot: %w", err)
+	}
+	if len(data) < snapshotHeaderSize+4 || string(data[:4]) != string(snapshotMagic) {
+		return nil, ErrSnapshotCorrupted
+	}
+	body := data[:len(data)-4]
+	if crc32.Checksum(body, snapshotCRCTable) != binary.LittleEndian.Uint32(data[len(body):]) {
+		return nil, ErrSnapshotCorrupted
+	}
+	if version := binary.LittleEndian.Uint16(body[4:]); version != SnapshotVersion {
+		return nil, fmt.Errorf("%w: %d", ErrSnapshotVersion, version)
+	}
+	count := binary.LittleEndian.Uint64(body[14:])
+	return readSnapshotEntries(conf, body[snapshotHeaderSize:], count)
+}
+
+func readSnapshotEntries[K any, V any](conf SnapshotConf[K, V], data []byte, count uint64) ([]SnapshotEntry[K, V], error) {
+	now := time.Now().UnixNano()
+	// every entry takes at least 10 bytes, so count can't make us allocate more than data allows
+	entries := make([]SnapshotEntry[K, V], 0, min(count, uint64(len(data)/10)))
+	for i := uint64(0); i < count; i++ {
+		if len(data) < 8 {
+			return nil, ErrSnapshotCorrupted
+		}
+		expiryNano := int64(binary.LittleEndian.Uint64(data)) //nolint:gosec // written from int64
+		data = data[8:]
+		rawKey, rest, ok := readSnapshotField(data)
+		if !ok {
+			return nil, ErrSnapshotCorrupted
+		}
+		rawValue, rest, ok := readSnapshotField(rest)
+		if !ok {
+			return nil, ErrSnapshotCorrupted
+		}
+		data = rest
+		if expiryNano != 0 && expiryNano <= now {
+			continue
+		}
+		key, err := conf.Keys.Decode(rawKey)
+		if err != nil {
+			return nil, fmt.Errorf("decode snapshot key: %w", err)
+		}
+		value, err := conf.Values.Decode(rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("decode snapshot value: %w", err)
+		}
+		entry := SnapshotEntry[K, V]{Key: key, Value: value}
+		if expiryNano != 0 {
+			entry.ExpiresAt = time.Unix(0, expiryNano)
+		}
+		entries = append(entries, entry)
+	}
+	if len(data) != 0 {
+		return nil, ErrSnapshotCorrupted
+	}
+	return entries, nil
+}
+
+func readSnapshotField(data []byte) (field, rest []byte, ok bool) {
+	size, n := binary.Uvarint(data)
+	if n <= 0 || size > uint64(len(data)-n) {
+		return nil, nil, false
+	}
+	return data[n : n+int(size)], data[n+int(size):], true //nolint:gosec // checked against data length
+}
+
+// SnapshotSaver is a cache which saves itself to configured snapshot file.
+type SnapshotSaver interface {
+	SaveSnapshot() error
+}
+
+// Snapshotter saves caches every interval and once more on Stop, e.g. on graceful shutdown.
+type Snapshotter struct {
+	savers   []SnapshotSaver
+	interval time.Duration
+	onError  func(err error)
+
+	wg        sync.WaitGroup
+	stopCh    chan struct{}
+	closeOnce sync.Once
+}
+
+func NewSnapshotter(interval time.Duration, savers ...SnapshotSaver) *Snapshotter {
+	return &Snapshotter{
+		savers:   savers,
+		interval: interval,
+		onError:  func(error) {},
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// WithOnError sets handler of periodic save errors, e.g. to log them.
+func (s *Snapshotter) WithOnError(onError func(err error)) *Snapshotter {
+	s.onError = onError
+	return s
+}
+
+// Start runs periodic saving until ctx is done or Stop is called.
+func (s *Snapshotter) Start(ctx context.Context) *Snapshotter {
+	ticker := time.NewTicker(s.interval)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Save(); err != nil {
+					s.onError(err)
+				}
+			case <-s.stopCh:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return s
+}
+
+// Save saves all caches, errors of every cache are joined.
+func (s *Snapshotter) Save() error {
+	var errs []error
+	for _, saver := range s.savers {
+		if err := saver.SaveSnapshot(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Stop stops periodic saving and takes the final snapshot, safe to call multiple times.
+func (s *Snapshotter) Stop() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.stopCh)
+		s.wg.Wait()
+		err = s.Save()
+	})
+	return err
+}
@@ -0,0 +1,116 @@
+package utils_test
+
+import (
+	"errors"
+	"go_project_template/internal/utils"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type snapshotValue struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func snapshotConf(t *testing.T) utils.SnapshotConf[string, snapshotValue] {
+	t.Helper()
+	return utils.SnapshotConf[string, snapshotValue]{
+		Path:   filepath.Join(t.TempDir(), "cache.snapshot"),
+		Keys:   utils.StringCodec(),
+		Values: utils.JSONCodec[snapshotValue](),
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	t.Run("should keep order and skip expired entries", func(t *testing.T) {
+		conf := snapshotConf(t)
+		expiresAt := time.Now().Add(time.Hour)
+		require.NoError(t, utils.WriteSnapshot(conf, []utils.SnapshotEntry[string, snapshotValue]{
+			{Key: "b", Value: snapshotValue{Name: "b", Count: 2}},
+			{Key: "expired", Value: snapshotValue{Name: "expired"}, ExpiresAt: time.Now().Add(-time.Second)},
+			{Key: "a", Value: snapshotValue{Name: "a", Count: 1}, ExpiresAt: expiresAt},
+		}))
+
+		entries, err := utils.ReadSnapshot(conf)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		require.Equal(t, "b", entries[0].Key)
+		require.True(t, entries[0].ExpiresAt.IsZero())
+		require.Equal(t, snapshotValue{Name: "a", Count: 1}, entries[1].Value)
+		require.Equal(t, expiresAt.UnixNano(), entries[1].ExpiresAt.UnixNano())
+	})
+	t.Run("should load nothing without file", func(t *testing.T) {
+		entries, err := utils.ReadSnapshot(snapshotConf(t))
+		require.NoError(t, err)
+		require.Empty(t, entries)
+	})
+	t.Run("should detect damaged file", func(t *testing.T) {
+		conf := snapshotConf(t)
+		require.NoError(t, utils.WriteSnapshot(conf, []utils.SnapshotEntry[string, snapshotValue]{
+			{Key: "a", Value: snapshotValue{Name: "a"}},
+		}))
+		data, err := os.ReadFile(conf.Path)
+		require.NoError(t, err)
+
+		for name, damaged := range map[string][]byte{
+			"flipped byte": append(append([]byte{}, data[:25]...), append([]byte{data[25] ^ 0xff}, data[26:]...)...),
+			"truncated":    data[:len(data)-5],
+			"empty":        {},
+		} {
+			require.NoError(t, os.WriteFile(conf.Path, damaged, 0o600))
+			_, err = utils.ReadSnapshot(conf)
+			require.ErrorIs(t, err, utils.ErrSnapshotCorrupted, name)
+		}
+	})
+	t.Run("should restore ttl map", func(t *testing.T) {
+		conf := snapshotConf(t)
+		m := utils.NewTTLMap[string, snapshotValue](time.Hour, time.Minute).WithSnapshot(conf)
+		defer m.Close()
+		m.Put("a", snapshotValue{Name: "a"})
+		m.Put("b", snapshotValue{Name: "b"})
+		require.NoError(t, m.SaveSnapshot())
+
+		// shorter max TTL of restored map caps remaining TTL
+		restored := utils.NewTTLMap[string, snapshotValue](30*time.Millisecond, time.Minute).WithSnapshot(conf)
+		defer restored.Close()
+		loaded, err := restored.LoadSnapshot()
+		require.NoError(t, err)
+		require.Equal(t, 2, loaded)
+		require.Equal(t, m.LoadAll(), restored.LoadAll())
+		time.Sleep(40 * time.Millisecond)
+		_, ok := restored.Get("a")
+		require.False(t, ok)
+
+		_, err = utils.NewTTLMap[string, int](time.Hour, time.Minute).LoadSnapshot()
+		require.ErrorIs(t, err, utils.ErrSnapshotNotConfigured)
+	})
+	t.Run("should save on stop", func(t *testing.T) {
+		conf := snapshotConf(t)
+		m := utils.NewTTLMap[string, snapshotValue](time.Hour, time.Minute).WithSnapshot(conf)
+		defer m.Close()
+		failing := saverFunc(func() error { return errors.New("disk is full") })
+
+		var periodicErrs []error
+		snapshotter := utils.NewSnapshotter(time.Hour, m, failing).
+			WithOnError(func(err error) { periodicErrs = append(periodicErrs, err) }).
+			Start(t.Context())
+		m.Put("a", snapshotValue{Name: "a"})
+		require.Error(t, snapshotter.Stop(), "error of failing saver should be returned")
+		require.NoError(t, snapshotter.Stop())
+		require.Empty(t, periodicErrs)
+
+		entries, err := utils.ReadSnapshot(conf)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+	})
+}
+
+type saverFunc func() error
+
+func (f saverFunc) SaveSnapshot() error {
+	return f()
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 )
 
@@ -10,34 +11,32 @@ const (
 	MSParamPlaceholder = "@p"
 )
 
+// GenerateInsertSQL generates insert SQL statement, columns are sorted so the same mapping
+// keys always produce the same query.
 func GenerateInsertSQL(tableName string, fieldsValuesMapping map[string]any) (sqlI string, params []any) {
-	fields := make([]string, 0, len(fieldsValuesMapping))
-	placeholders := make([]string, 0, len(fieldsValuesMapping))
-	params = make([]any, 0, len(fieldsValuesMapping))
-	counter := 1
-	for k, v := range fieldsValuesMapping {
-		params = append(params, v)
-		fields = append(fields, k)
-		placeholders = append(placeholders, fmt.Sprintf("$%d", counter))
-		counter++
+	fields := sortedColumns(fieldsValuesMapping)
+	placeholders := make([]string, 0, len(fields))
+	params = make([]any, 0, len(fields))
+	for i, field := range fields {
+		params = append(params, fieldsValuesMapping[field])
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
 	}
 	sqlI = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", tableName, strings.Join(fields, ", "), strings.Join(placeholders, ", "))
 	return sqlI, params
 }
 
-// GenerateBulkInsertSQL This method generates a bulk insert SQL statement based on entity mapping
-// will panic if entityList is empty
+// GenerateBulkInsertSQL This method generates a bulk insert SQL statement based on entity mapping,
+// columns are taken from the first entity in sorted order. Returns empty query if entityList is empty.
 func GenerateBulkInsertSQL[T any](
 	tableName string,
 	paramPlaceholder string,
 	entityList []T,
 	entityProcessor func(entity T) map[string]any,
 ) (sqlI string, params []any) {
-	// processor is based on map, so it random iteration. generate columns first
-	columns := make([]string, 0, 10)
-	for k := range entityProcessor(entityList[0]) {
-		columns = append(columns, k)
+	if len(entityList) == 0 {
+		return "", nil
 	}
+	columns := sortedColumns(entityProcessor(entityList[0]))
 
 	// generate values
 	counter := 1
@@ -57,3 +56,13 @@ func GenerateBulkInsertSQL[T any](
 	sqlI = fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", tableName, strings.Join(columns, ","), strings.Join(placeholders, ","))
 	return sqlI, params
 }
+
+// sortedColumns returns mapping keys in sorted order, map iteration order is random.
+func sortedColumns(mapping map[string]any) []string {
+	columns := make([]string, 0, len(mapping))
+	for column := range mapping {
+		columns = append(columns, column)
+	}
+	slices.Sort(columns)
+	return columns
+}
@@ -0,0 +1,243 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrEmptyInsert      = errors.New("no rows to insert")
+	ErrNoConflictTarget = errors.New("conflict columns are required")
+	ErrUnknownColumn    = errors.New("column is not inserted")
+)
+
+// SQLDialect defines placeholders, identifier quoting and upsert syntax of a database.
+type SQLDialect int
+
+const (
+	DialectPostgres SQLDialect = iota
+	DialectSQLite
+	DialectMSSQL
+)
+
+func (d SQLDialect) String() string {
+	switch d {
+	case DialectPostgres:
+		return "postgres"
+	case DialectSQLite:
+		return "sqlite"
+	case DialectMSSQL:
+		return "mssql"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(d))
+	}
+}
+
+// Placeholder returns placeholder of n-th bind parameter, counting from 1.
+func (d SQLDialect) Placeholder(n int) string {
+	switch d {
+	case DialectSQLite:
+		return "?"
+	case DialectMSSQL:
+		return MSParamPlaceholder + strconv.Itoa(n)
+	default:
+		return PQParamPlaceholder + strconv.Itoa(n)
+	}
+}
+
+// QuoteIdentifier quotes every part of possibly schema qualified name, e.g. public.users.
+func (d SQLDialect) QuoteIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if d == DialectMSSQL {
+			parts[i] = "[" + strings.ReplaceAll(part, "]", "]]") + "]"
+		} else {
+			parts[i] = `"` + strings.ReplaceAll(part, `"`, `""`) + `"`
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func (d SQLDialect) quoteAll(names []string, prefix string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = prefix + d.QuoteIdentifier(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// InsertBuilder builds insert and upsert statements of entities.
+// Columns are taken from the first entity in sorted order, so the same entity type always
+// produces the same query text. Postgres and SQLite use ON CONFLICT, MSSQL uses MERGE.
+// Build is safe for concurrent use, With* methods are not.
+type InsertBuilder[T any] struct {
+	dialect   SQLDialect
+	table     string
+	mapper    func(entity T) map[string]any
+	conflict  []string
+	update    []string
+	upsert    bool
+	doNothing bool
+	returning []string
+}
+
+// NewInsertBuilder creates builder of inserts into table, mapper returns column values of an entity.
+func NewInsertBuilder[T any](dialect SQLDialect, table string, mapper func(entity T) map[string]any) *InsertBuilder[T] {
+	return &InsertBuilder[T]{
+		dialect: dialect,
+		table:   table,
+		mapper:  mapper,
+	}
+}
+
+// WithUpsert updates existing rows conflicting on conflictColumns. Without updateColumns
+// all inserted columns except conflict ones are updated, if none are left conflicts are ignored.
+func (b *InsertBuilder[T]) WithUpsert(conflictColumns []string, updateColumns ...string) *InsertBuilder[T] {
+	b.conflict = conflictColumns
+	b.update = updateColumns
+	b.upsert, b.doNothing = true, false
+	return b
+}
+
+// WithOnConflictDoNothing skips rows conflicting on conflictColumns, Postgres and SQLite
+// allow to omit them to skip any conflict.
+func (b *InsertBuilder[T]) WithOnConflictDoNothing(conflictColumns ...string) *InsertBuilder[T] {
+	b.conflict = conflictColumns
+	b.update = nil
+	b.upsert, b.doNothing = false, true
+	return b
+}
+
+// WithReturning makes statement return columns of inserted or updated rows.
+func (b *InsertBuilder[T]) WithReturning(columns ...string) *InsertBuilder[T] {
+	b.returning = columns
+	return b
+}
+
+// Build returns statement inserting entities and its parameters.
+func (b *InsertBuilder[T]) Build(entities []T) (query string, params []any, err error) {
+	if len(entities) == 0 {
+		return "", nil, ErrEmptyInsert
+	}
+	columns := sortedColumns(b.mapper(entities[0]))
+	for _, column := range b.conflict {
+		if !slices.Contains(columns, column) {
+			return "", nil, fmt.Errorf("%w: %s", ErrUnknownColumn, column)
+		}
+	}
+	update, err := b.updateColumns(columns)
+	if err != nil {
+		return "", nil, err
+	}
+
+	params = make([]any, 0, len(entities)*len(columns))
+	rows := make([]string, 0, len(entities))
+	placeholders := make([]string, len(columns))
+	for _, entity := range entities {
+		mapping := b.mapper(entity)
+		for i, column := range columns {
+			params = append(params, mapping[column])
+			placeholders[i] = b.dialect.Placeholder(len(params))
+		}
+		rows = append(rows, "("+strings.Join(placeholders, ", ")+")")
+	}
+
+	if b.dialect == DialectMSSQL {
+		query, err = b.buildMSSQL(columns, update, strings.Join(rows, ", "))
+	} else {
+		query = b.buildOnConflict(columns, update, strings.Join(rows, ", "))
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return query, params, nil
+}
+
+// updateColumns returns columns updated on conflict, nil means conflicts are ignored.
+func (b *InsertBuilder[T]) updateColumns(columns []string) ([]string, error) {
+	if !b.upsert {
+		return nil, nil
+	}
+	if len(b.conflict) == 0 {
+		return nil, ErrNoConflictTarget
+	}
+	if len(b.update) > 0 {
+		for _, column := range b.update {
+			if !slices.Contains(columns, column) {
+				return nil, fmt.Errorf("%w: %s", ErrUnknownColumn, column)
+			}
+		}
+		return b.update, nil
+	}
+	update := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if !slices.Contains(b.conflict, column) {
+			update = append(update, column)
+		}
+	}
+	return update, nil
+}
+
+func (b *InsertBuilder[T]) buildOnConflict(columns, update []string, values string) string {
+	d := b.dialect
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "INSERT INTO %s (%s) VALUES %s", d.QuoteIdentifier(b.table), d.quoteAll(columns, ""), values)
+	if b.upsert || b.doNothing {
+		sb.WriteString(" ON CONFLICT")
+		if len(b.conflict) > 0 {
+			fmt.Fprintf(&sb, " (%s)", d.quoteAll(b.conflict, ""))
+		}
+		if len(update) == 0 {
+			sb.WriteString(" DO NOTHING")
+		} else {
+			sets := make([]string, len(update))
+			for i, column := range update {
+				sets[i] = fmt.Sprintf("%s = EXCLUDED.%s", d.QuoteIdentifier(column), d.QuoteIdentifier(column))
+			}
+			fmt.Fprintf(&sb, " DO UPDATE SET %s", strings.Join(sets, ", "))
+		}
+	}
+	if len(b.returning) > 0 {
+		fmt.Fprintf(&sb, " RETURNING %s", d.quoteAll(b.returning, ""))
+	}
+	return sb.String()
+}
+
+// buildMSSQL uses plain INSERT with OUTPUT clause or MERGE for upserts,
+// MERGE matches rows on conflict columns, so they can't be omitted.
+func (b *InsertBuilder[T]) buildMSSQL(columns, update []string, values string) (string, error) {
+	d := b.dialect
+	output := ""
+	if len(b.returning) > 0 {
+		output = " OUTPUT " + d.quoteAll(b.returning, "INSERTED.")
+	}
+	if !b.upsert && !b.doNothing {
+		return fmt.Sprintf("INSERT INTO %s (%s)%s VALUES %s", d.QuoteIdentifier(b.table), d.quoteAll(columns, ""), output, values), nil
+	}
+	if len(b.conflict) == 0 {
+		return "", ErrNoConflictTarget
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "MERGE INTO %s AS target USING (VALUES %s) AS source (%s) ON ",
+		d.QuoteIdentifier(b.table), values, d.quoteAll(columns, ""))
+	for i, column := range b.conflict {
+		if i > 0 {
+			sb.WriteString(" AND ")
+		}
+		fmt.Fprintf(&sb, "target.%s = source.%s", d.QuoteIdentifier(column), d.QuoteIdentifier(column))
+	}
+	if len(update) > 0 {
+		sets := make([]string, len(update))
+		for i, column := range update {
+			sets[i] = fmt.Sprintf("target.%s = source.%s", d.QuoteIdentifier(column), d.QuoteIdentifier(column))
+		}
+		fmt.Fprintf(&sb, " WHEN MATCHED THEN UPDATE SET %s", strings.Join(sets, ", "))
+	}
+	fmt.Fprintf(&sb, " WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)%s;",
+		d.quoteAll(columns, ""), d.quoteAll(columns, "source."), output)
+	return sb.String(), nil
+}
@@ -0,0 +1,104 @@
+package utils_test
+
+import (
+	"flag"
+	"go_project_template/internal/utils"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+type account struct {
+	ID      int
+	Email   string
+	Balance int
+}
+
+func accountColumns(a account) map[string]any {
+	return map[string]any{"id": a.ID, "email": a.Email, "balance": a.Balance}
+}
+
+// requireGolden compares actual with testdata/sql/name.golden, run tests with -update to rewrite it.
+func requireGolden(t *testing.T, name, actual string) {
+	t.Helper()
+	path := filepath.Join("testdata", "sql", name+".golden")
+	if *updateGolden {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+		require.NoError(t, os.WriteFile(path, []byte(actual+"\n"), 0o600))
+	}
+	expected, err := os.ReadFile(path) //nolint:gosec // test data path
+	require.NoError(t, err)
+	require.Equal(t, string(expected), actual+"\n")
+}
+
+func TestInsertBuilder(t *testing.T) {
+	accounts := []account{{1, "a@example.com", 10}, {2, "b@example.com", 20}}
+	cases := []struct {
+		name  string
+		build func(b *utils.InsertBuilder[account]) *utils.InsertBuilder[account]
+	}{
+		{"insert", func(b *utils.InsertBuilder[account]) *utils.InsertBuilder[account] {
+			return b
+		}},
+		{"returning", func(b *utils.InsertBuilder[account]) *utils.InsertBuilder[account] {
+			return b.WithReturning("id")
+		}},
+		{"upsert", func(b *utils.InsertBuilder[account]) *utils.InsertBuilder[account] {
+			return b.WithUpsert([]string{"id"}).WithReturning("id", "balance")
+		}},
+		{"upsert_columns", func(b *utils.InsertBuilder[account]) *utils.InsertBuilder[account] {
+			return b.WithUpsert([]string{"id", "email"}, "balance")
+		}},
+		{"do_nothing", func(b *utils.InsertBuilder[account]) *utils.InsertBuilder[account] {
+			return b.WithOnConflictDoNothing("email")
+		}},
+	}
+	for _, dialect := range []utils.SQLDialect{utils.DialectPostgres, utils.DialectSQLite, utils.DialectMSSQL} {
+		for _, tc := range cases {
+			t.Run(dialect.String()+"/"+tc.name, func(t *testing.T) {
+				builder := tc.build(utils.NewInsertBuilder(dialect, "billing.accounts", accountColumns))
+				query, params, err := builder.Build(accounts)
+				require.NoError(t, err)
+				require.Equal(t, []any{10, "a@example.com", 1, 20, "b@example.com", 2}, params)
+				requireGolden(t, dialect.String()+"_"+tc.name, query)
+
+				// the same entities produce the same query
+				again, _, err := builder.Build(accounts)
+				require.NoError(t, err)
+				require.Equal(t, query, again)
+			})
+		}
+	}
+}
+
+func TestInsertBuilderErrors(t *testing.T) {
+	_, _, err := utils.NewInsertBuilder(utils.DialectPostgres, "accounts", accountColumns).Build(nil)
+	require.ErrorIs(t, err, utils.ErrEmptyInsert)
+
+	_, _, err = utils.NewInsertBuilder(utils.DialectPostgres, "accounts", accountColumns).
+		WithUpsert(nil).Build([]account{{}})
+	require.ErrorIs(t, err, utils.ErrNoConflictTarget)
+
+	_, _, err = utils.NewInsertBuilder(utils.DialectMSSQL, "accounts", accountColumns).
+		WithOnConflictDoNothing().Build([]account{{}})
+	require.ErrorIs(t, err, utils.ErrNoConflictTarget)
+
+	_, _, err = utils.NewInsertBuilder(utils.DialectSQLite, "accounts", accountColumns).
+		WithUpsert([]string{"uuid"}).Build([]account{{}})
+	require.ErrorIs(t, err, utils.ErrUnknownColumn)
+
+	// conflict target may be omitted to skip any conflict
+	query, _, err := utils.NewInsertBuilder(utils.DialectPostgres, "accounts", accountColumns).
+		WithOnConflictDoNothing().Build([]account{{}})
+	require.NoError(t, err)
+	require.Equal(t, `INSERT INTO "accounts" ("balance", "email", "id") VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`, query)
+}
+
+func TestSQLDialect_QuoteIdentifier(t *testing.T) {
+	require.Equal(t, `"public"."my ""table"""`, utils.DialectPostgres.QuoteIdentifier(`public.my "table"`))
+	require.Equal(t, `[dbo].[my [table]]]`, utils.DialectMSSQL.QuoteIdentifier(`dbo.my [table]`))
+}
@@ -2,8 +2,11 @@ package utils
 
 import (
 	"context"
+	"fmt"
 	"hash/maphash"
+	"iter"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,19 +16,42 @@ const (
 	shardMask = numShards - 1
 )
 
+// evictionSamples is number of entries compared to pick a victim when shard is full.
+const evictionSamples = 5
+
+// EvictionPolicy chooses which entry is evicted when map reaches its max entries.
+type EvictionPolicy int
+
+const (
+	EvictLRU          EvictionPolicy = iota // the least recently accessed entry
+	EvictOldestExpiry                       // entry which expires first
+)
+
 // item stores the payload alongside its expiry as a monotonic int64 nanosecond
 // timestamp. Using int64 instead of time.Time halves the field size (8 vs 24 bytes)
 // and reduces comparison cost to a single integer compare.
+// accessNano is updated only if map is bounded with EvictLRU policy.
 type item[V any] struct {
 	value      V
 	expiryNano int64
+	accessNano int64
 }
 
 type cacheShard[K comparable, V any] struct {
-	mu sync.RWMutex
-	m  map[K]item[V]
-	// this extra field will guarantee that object will be 64 bytes size, tweak for efficient cache usage
-	_ [32]byte // pads to 64 B (1 cache line) so adjacent shards don't cause false sharing
+	mu     sync.RWMutex
+	m      map[K]item[V]
+	wheel  *timingWheel[K] // nil unless WithTimingWheel is used
+	limit  int             // max entries, 0 means unbounded
+	hits   atomic.Uint64   // counted per shard, so lookups of different shards don't contend
+	misses atomic.Uint64
+	// fields take exactly 64 B (1 cache line) so adjacent shards don't cause false sharing
+}
+
+// removal is an entry removed under shard lock, OnEvict callback is called for it after unlock.
+type removal[K comparable, V any] struct {
+	key    K
+	value  V
+	reason string
 }
 
 // TTLMap stores values with a time-to-live.
@@ -37,9 +63,18 @@ type cacheShard[K comparable, V any] struct {
 //   - int64 nanosecond expiry → cheaper comparison, 8 bytes instead of 24
 //   - Get uses RLock on the hot (non-expired) path → concurrent reads scale with cores
 type TTLMap[K comparable, V any] struct {
-	shards [numShards]cacheShard[K, V]
-	seed   maphash.Seed
-	maxTTL int64 // nanoseconds
+	shards          [numShards]cacheShard[K, V]
+	seed            maphash.Seed
+	maxTTL          int64 // nanoseconds
+	cleanupInterval time.Duration
+
+	// written under all shard locks, so they can be read under any of them
+	policy  EvictionPolicy
+	onEvict func(key K, value V, reason string)
+
+	snapshot *SnapshotConf[K, V]
+	counters CacheCounters
+	loader   *Loader[K, V]
 
 	wg        sync.WaitGroup
 	stopCh    chan struct{}
@@ -55,13 +90,15 @@ func NewTTLMap[K comparable, V any](maxTTL, cleanupInterval time.Duration) *TTLM
 // NewTTLMapWithContext ties the cleanup goroutine to ctx and also supports Close().
 func NewTTLMapWithContext[K comparable, V any](ctx context.Context, maxTTL, cleanupInterval time.Duration) *TTLMap[K, V] {
 	m := &TTLMap[K, V]{
-		seed:   maphash.MakeSeed(),
-		maxTTL: maxTTL.Nanoseconds(),
-		stopCh: make(chan struct{}),
+		seed:            maphash.MakeSeed(),
+		maxTTL:          maxTTL.Nanoseconds(),
+		cleanupInterval: cleanupInterval,
+		stopCh:          make(chan struct{}),
 	}
 	for i := range m.shards {
 		m.shards[i].m = make(map[K]item[V], 64)
 	}
+	m.loader = NewLoader(LoaderConf{}, m.Put, m.counters.ObserveLoad)
 
 	ticker := time.NewTicker(cleanupInterval)
 	m.wg.Add(1)
@@ -71,17 +108,7 @@ func NewTTLMapWithContext[K comparable, V any](ctx context.Context, maxTTL, clea
 		for {
 			select {
 			case <-ticker.C:
-				now := time.Now().UnixNano()
-				for i := range m.shards {
-					s := &m.shards[i]
-					s.mu.Lock()
-					for k, v := range s.m {
-						if now > v.expiryNano {
-							delete(s.m, k)
-						}
-					}
-					s.mu.Unlock()
-				}
+				m.cleanup(time.Now().UnixNano())
 			case <-m.stopCh:
 				return
 			case <-ctx.Done():
@@ -92,6 +119,42 @@ func NewTTLMapWithContext[K comparable, V any](ctx context.Context, maxTTL, clea
 	return m
 }
 
+// cleanup removes expired entries, in timing wheel mode only entries scheduled to expire are visited.
+func (m *TTLMap[K, V]) cleanup(now int64) {
+	var removed []removal[K, V]
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.Lock()
+		onEvict := m.onEvict
+		expired := 0
+		expire := func(k K, it item[V]) {
+			delete(s.m, k)
+			expired++
+			if onEvict != nil {
+				removed = append(removed, removal[K, V]{key: k, value: it.value, reason: EvictReasonExpired})
+			}
+		}
+		if s.wheel != nil {
+			for _, k := range s.wheel.due(now) {
+				// key could be deleted or put again with later expiry since it was scheduled
+				if it, ok := s.m[k]; ok && now > it.expiryNano {
+					expire(k, it)
+				}
+			}
+		} else {
+			for k, it := range s.m {
+				if now > it.expiryNano {
+					expire(k, it)
+				}
+			}
+		}
+		s.mu.Unlock()
+		m.counters.Evicted(EvictReasonExpired, expired)
+		m.notify(onEvict, removed...)
+		removed = removed[:0]
+	}
+}
+
 // Close stops the background cleanup goroutine and waits for it to exit, safe to call multiple times.
 func (m *TTLMap[K, V]) Close() {
 	m.closeOnce.Do(func() {
@@ -100,6 +163,187 @@ func (m *TTLMap[K, V]) Close() {
 	})
 }
 
+// WithLoader configures GetOrLoad, call it before the map is used.
+func (m *TTLMap[K, V]) WithLoader(conf LoaderConf) *TTLMap[K, V] {
+	m.loader = NewLoader(conf, m.Put, m.counters.ObserveLoad)
+	return m
+}
+
+// WithOnEvict sets callback called for entries which expired or were evicted or deleted,
+// reason is one of EvictReasonExpired, EvictReasonSize and EvictReasonManual.
+// Replaced entries are not reported. Callback is called without locks held, so it may use the map.
+func (m *TTLMap[K, V]) WithOnEvict(onEvict func(key K, value V, reason string)) *TTLMap[K, V] {
+	m.lockAll()
+	m.onEvict = onEvict
+	m.unlockAll()
+	return m
+}
+
+// WithMaxEntries bounds number of entries, put of a new key into full map evicts an entry chosen by policy.
+// Bound is split between shards and victim is picked from a few sampled entries of the shard,
+// so eviction is approximate. EvictLRU makes Get take shard write lock to record access.
+// Bound lower than number of shards is raised to it.
+func (m *TTLMap[K, V]) WithMaxEntries(maxEntries int, policy EvictionPolicy) *TTLMap[K, V] {
+	m.lockAll()
+	defer m.unlockAll()
+	m.policy = policy
+	for i := range m.shards {
+		m.shards[i].limit = max(1, maxEntries/numShards)
+		if i < maxEntries%numShards {
+			m.shards[i].limit++
+		}
+	}
+	return m
+}
+
+// WithTimingWheel switches cleanup from scanning all entries to timing wheel: keys are bucketed
+// by expiry with cleanup interval precision and every cleanup visits only buckets which are due.
+// Every put adds key to a bucket, keys put again or deleted leave stale bucket records until they are due.
+func (m *TTLMap[K, V]) WithTimingWheel() *TTLMap[K, V] {
+	now := time.Now().UnixNano()
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.Lock()
+		if s.wheel == nil {
+			s.wheel = newTimingWheel[K](m.cleanupInterval, now)
+			for k, it := range s.m {
+				s.wheel.schedule(k, it.expiryNano)
+			}
+		}
+		s.mu.Unlock()
+	}
+	return m
+}
+
+func (m *TTLMap[K, V]) lockAll() {
+	for i := range m.shards {
+		m.shards[i].mu.Lock()
+	}
+}
+
+func (m *TTLMap[K, V]) unlockAll() {
+	for i := range m.shards {
+		m.shards[i].mu.Unlock()
+	}
+}
+
+// notify calls onEvict for removed entries, it must be called without shard locks.
+func (m *TTLMap[K, V]) notify(onEvict func(key K, value V, reason string), removed ...removal[K, V]) {
+	if onEvict == nil {
+		return
+	}
+	for _, r := range removed {
+		onEvict(r.key, r.value, r.reason)
+	}
+}
+
+// setLocked writes entry to the shard, evicting another entry if the shard is full.
+// Returns evicted entry, it must be passed to notify after unlock.
+func (m *TTLMap[K, V]) setLocked(s *cacheShard[K, V], k K, it item[V], now int64) (removal[K, V], bool) {
+	var victim removal[K, V]
+	evicted := false
+	if _, exists := s.m[k]; !exists && s.limit > 0 && len(s.m) >= s.limit {
+		victim, evicted = m.evictLocked(s, now), true
+	}
+	it.accessNano = now
+	s.m[k] = it
+	if s.wheel != nil {
+		s.wheel.schedule(k, it.expiryNano)
+	}
+	return victim, evicted
+}
+
+// evictLocked removes the worst of sampled entries by policy, map iteration order is random.
+func (m *TTLMap[K, V]) evictLocked(s *cacheShard[K, V], now int64) removal[K, V] {
+	var victimKey K
+	var victim item[V]
+	sampled := 0
+	for k, it := range s.m {
+		if sampled == 0 || m.worse(it, victim, now) {
+			victimKey, victim = k, it
+		}
+		sampled++
+		if sampled == evictionSamples {
+			break
+		}
+	}
+	delete(s.m, victimKey)
+	reason := EvictReasonSize
+	if now > victim.expiryNano {
+		reason = EvictReasonExpired
+	}
+	m.counters.Evicted(reason, 1)
+	return removal[K, V]{key: victimKey, value: victim.value, reason: reason}
+}
+
+// worse reports if a should be evicted before b, expired entries go first.
+func (m *TTLMap[K, V]) worse(a, b item[V], now int64) bool {
+	if aExpired, bExpired := now > a.expiryNano, now > b.expiryNano; aExpired != bExpired {
+		return aExpired
+	}
+	if m.policy == EvictLRU {
+		return a.accessNano < b.accessNano
+	}
+	return a.expiryNano < b.expiryNano
+}
+
+// WithSnapshot configures file used by SaveSnapshot and LoadSnapshot.
+func (m *TTLMap[K, V]) WithSnapshot(conf SnapshotConf[K, V]) *TTLMap[K, V] {
+	m.snapshot = &conf
+	return m
+}
+
+// SaveSnapshot writes live entries with their expiry to configured file.
+func (m *TTLMap[K, V]) SaveSnapshot() error {
+	if m.snapshot == nil {
+		return ErrSnapshotNotConfigured
+	}
+	now := time.Now().UnixNano()
+	entries := make([]SnapshotEntry[K, V], 0, m.Len())
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.RLock()
+		for k, v := range s.m {
+			if now <= v.expiryNano {
+				entries = append(entries, SnapshotEntry[K, V]{Key: k, Value: v.value, ExpiresAt: time.Unix(0, v.expiryNano)})
+			}
+		}
+		s.mu.RUnlock()
+	}
+	if err := WriteSnapshot(*m.snapshot, entries); err != nil {
+		return fmt.Errorf("save ttl map snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot puts entries from configured file keeping their remaining TTL, but not longer than maxTTL.
+// Expired entries are skipped, missing file loads nothing. Returns number of loaded entries.
+func (m *TTLMap[K, V]) LoadSnapshot() (int, error) {
+	if m.snapshot == nil {
+		return 0, ErrSnapshotNotConfigured
+	}
+	entries, err := ReadSnapshot(*m.snapshot)
+	if err != nil {
+		return 0, fmt.Errorf("load ttl map snapshot: %w", err)
+	}
+	maxExpiry := time.Now().UnixNano() + m.maxTTL
+	for _, entry := range entries {
+		expiryNano := maxExpiry
+		if !entry.ExpiresAt.IsZero() {
+			expiryNano = min(entry.ExpiresAt.UnixNano(), maxExpiry)
+		}
+		s := m.shardOf(entry.Key)
+		s.mu.Lock()
+		onEvict := m.onEvict
+		victim, evicted := m.setLocked(s, entry.Key, item[V]{value: entry.Value, expiryNano: expiryNano}, time.Now().UnixNano())
+		s.mu.Unlock()
+		if evicted {
+			m.notify(onEvict, victim)
+		}
+	}
+	return len(entries), nil
+}
+
 // shardOf returns the shard responsible for key k.
 func (m *TTLMap[K, V]) shardOf(k K) *cacheShard[K, V] {
 	return &m.shards[maphash.Comparable(m.seed, k)&shardMask]
@@ -120,88 +364,170 @@ func (m *TTLMap[K, V]) Len() int {
 
 // Put stores value under the specified key and refreshes its expiry time.
 func (m *TTLMap[K, V]) Put(k K, v V) {
+	m.PutWithTTL(k, v, 0)
+}
+
+// PutWithTTL stores value which expires after ttl, ttl which is not positive or longer than maxTTL means maxTTL.
+func (m *TTLMap[K, V]) PutWithTTL(k K, v V, ttl time.Duration) {
+	if ttl <= 0 || ttl.Nanoseconds() > m.maxTTL {
+		ttl = time.Duration(m.maxTTL)
+	}
+	now := time.Now().UnixNano()
 	s := m.shardOf(k)
 	s.mu.Lock()
-	s.m[k] = item[V]{value: v, expiryNano: time.Now().UnixNano() + m.maxTTL}
+	onEvict := m.onEvict
+	victim, evicted := m.setLocked(s, k, item[V]{value: v, expiryNano: now + ttl.Nanoseconds()}, now)
 	s.mu.Unlock()
+	if evicted {
+		m.notify(onEvict, victim)
+	}
 }
 
 // Delete removes the value associated with the given key.
 func (m *TTLMap[K, V]) Delete(k K) {
 	s := m.shardOf(k)
 	s.mu.Lock()
+	onEvict := m.onEvict
+	it, ok := s.m[k]
 	delete(s.m, k)
 	s.mu.Unlock()
+	if ok {
+		m.counters.Evicted(EvictReasonManual, 1)
+		m.notify(onEvict, removal[K, V]{key: k, value: it.value, reason: EvictReasonManual})
+	}
 }
 
 // Get returns the value associated with key.
 // Uses RLock on the hot (live) path for concurrent read scalability;
 // upgrades to a write lock only when an expired entry must be deleted.
 func (m *TTLMap[K, V]) Get(k K) (val V, ok bool) {
+	val, _, ok = m.get(k)
+	return val, ok
+}
+
+// get returns value with its remaining TTL.
+func (m *TTLMap[K, V]) get(k K) (val V, ttl time.Duration, ok bool) {
 	s := m.shardOf(k)
 
 	s.mu.RLock()
+	if s.limit > 0 && m.policy == EvictLRU {
+		s.mu.RUnlock()
+		return m.getAndTouch(s, k)
+	}
 	it, ok := s.m[k]
 	if !ok {
 		s.mu.RUnlock()
-		return val, false
+		s.misses.Add(1)
+		return val, 0, false
 	}
-	if time.Now().UnixNano() <= it.expiryNano {
+	if now := time.Now().UnixNano(); now <= it.expiryNano {
 		val = it.value
 		s.mu.RUnlock()
-		return val, true
+		s.hits.Add(1)
+		return val, time.Duration(it.expiryNano - now), true
 	}
 	s.mu.RUnlock()
 
 	// Expired: upgrade to write lock to evict.
+	s.misses.Add(1)
+	m.deleteExpired(s, k)
+	return val, 0, false
+}
+
+// getAndTouch is get which records access for EvictLRU policy.
+func (m *TTLMap[K, V]) getAndTouch(s *cacheShard[K, V], k K) (val V, ttl time.Duration, ok bool) {
+	now := time.Now().UnixNano()
+	s.mu.Lock()
+	it, ok := s.m[k]
+	if ok && now <= it.expiryNano {
+		it.accessNano = now
+		s.m[k] = it
+		s.mu.Unlock()
+		s.hits.Add(1)
+		return it.value, time.Duration(it.expiryNano - now), true
+	}
+	s.mu.Unlock()
+	s.misses.Add(1)
+	if ok {
+		m.deleteExpired(s, k)
+	}
+	return val, 0, false
+}
+
+// GetOrLoad returns value of k, missing value is loaded with loader and put to the map.
+// Concurrent calls for the same key share one loader call, its error is returned as is
+// and cached for LoaderConf.ErrorTTL. Value in the last
+// LoaderConf.StaleWhileRevalidate of its TTL is returned and reloaded in background.
+func (m *TTLMap[K, V]) GetOrLoad(ctx context.Context, k K, loader LoadFunc[K, V]) (V, error) {
+	if val, ttl, ok := m.get(k); ok {
+		m.loader.Revalidate(ctx, k, ttl, loader)
+		return val, nil
+	}
+	return m.loader.Load(ctx, k, loader)
+}
+
+// deleteExpired removes k if it is still expired once write lock is taken.
+func (m *TTLMap[K, V]) deleteExpired(s *cacheShard[K, V], k K) {
 	s.mu.Lock()
-	if it2, ok2 := s.m[k]; ok2 && time.Now().UnixNano() > it2.expiryNano {
+	onEvict := m.onEvict
+	it, ok := s.m[k]
+	ok = ok && time.Now().UnixNano() > it.expiryNano
+	if ok {
 		delete(s.m, k)
 	}
 	s.mu.Unlock()
-	return val, false
+	if ok {
+		m.counters.Evicted(EvictReasonExpired, 1)
+		m.notify(onEvict, removal[K, V]{key: k, value: it.value, reason: EvictReasonExpired})
+	}
 }
 
-// GetAndRefresh returns the value associated with key and resets its TTL.
+// lookupLocked returns live entry of k, expired entry is deleted and returned as removal.
+// Must be called under shard write lock.
+func (m *TTLMap[K, V]) lookupLocked(s *cacheShard[K, V], k K, now int64) (it item[V], ok bool, expired []removal[K, V]) {
+	it, ok = s.m[k]
+	if !ok {
+		s.misses.Add(1)
+		return it, false, nil
+	}
+	if now > it.expiryNano {
+		delete(s.m, k)
+		s.misses.Add(1)
+		m.counters.Evicted(EvictReasonExpired, 1)
+		return it, false, []removal[K, V]{{key: k, value: it.value, reason: EvictReasonExpired}}
+	}
+	s.hits.Add(1)
+	it.accessNano = now
+	return it, true, nil
+}
+
+// GetAndRefresh returns the value associated with key and resets its TTL to maxTTL.
 // If the key is missing or expired, the zero value and false are returned.
 func (m *TTLMap[K, V]) GetAndRefresh(k K) (val V, ok bool) {
+	now := time.Now().UnixNano()
 	s := m.shardOf(k)
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	it, ok := s.m[k]
-	if !ok || time.Now().UnixNano() > it.expiryNano {
-		if ok {
-			delete(s.m, k)
-		}
-		return val, false
+	onEvict := m.onEvict
+	it, ok, expired := m.lookupLocked(s, k, now)
+	if ok {
+		it.expiryNano = now + m.maxTTL
+		m.setLocked(s, k, it, now)
+		val = it.value
 	}
-	it.expiryNano = time.Now().UnixNano() + m.maxTTL
-	s.m[k] = it
-	return it.value, true
+	s.mu.Unlock()
+	m.notify(onEvict, expired...)
+	return val, ok
 }
 
 // Do executes fn with the value associated with key.
 // The map lock is released before fn is called, so fn may safely access the map.
 // If the key is missing or expired, fn is not called and false is returned.
 func (m *TTLMap[K, V]) Do(k K, fn func(v V)) bool {
-	s := m.shardOf(k)
-
-	s.mu.RLock()
-	it, ok := s.m[k]
-	s.mu.RUnlock()
-	if !ok || time.Now().UnixNano() > it.expiryNano {
-		if ok {
-			s.mu.Lock()
-			if it2, ok2 := s.m[k]; ok2 && time.Now().UnixNano() > it2.expiryNano {
-				delete(s.m, k)
-			}
-			s.mu.Unlock()
-		}
-		return false
+	val, _, ok := m.get(k)
+	if ok {
+		fn(val)
 	}
-	fn(it.value)
-	return true
+	return ok
 }
 
 // DoAndApply modifies the value associated with key using fn.
@@ -209,34 +535,96 @@ func (m *TTLMap[K, V]) Do(k K, fn func(v V)) bool {
 func (m *TTLMap[K, V]) DoAndApply(k K, fn func(v V) V) bool {
 	s := m.shardOf(k)
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	it, ok := s.m[k]
-	if !ok {
-		return false
+	onEvict := m.onEvict
+	it, ok, expired := m.lookupLocked(s, k, time.Now().UnixNano())
+	if ok {
+		it.value = fn(it.value)
+		s.m[k] = it
 	}
-	if time.Now().UnixNano() > it.expiryNano {
-		delete(s.m, k)
-		return false
-	}
-	it.value = fn(it.value)
-	s.m[k] = it
-	return true
+	s.mu.Unlock()
+	m.notify(onEvict, expired...)
+	return ok
 }
 
 // Upsert inserts zeroValue if the key does not exist, or updates the existing value
 // using fn. Either way the TTL is refreshed.
 func (m *TTLMap[K, V]) Upsert(key K, fn func(value V) V, zeroValue V) {
+	now := time.Now().UnixNano()
 	s := m.shardOf(key)
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
+	onEvict := m.onEvict
 	it, ok := s.m[key]
-	if !ok {
-		s.m[key] = item[V]{value: zeroValue, expiryNano: time.Now().UnixNano() + m.maxTTL}
-		return
+	value := zeroValue
+	if ok {
+		value = fn(it.value)
+	}
+	victim, evicted := m.setLocked(s, key, item[V]{value: value, expiryNano: now + m.maxTTL}, now)
+	s.mu.Unlock()
+	if evicted {
+		m.notify(onEvict, victim)
+	}
+}
+
+// Stats returns lookup and eviction counters, TTLMap doesn't weight items so Bytes is always zero.
+func (m *TTLMap[K, V]) Stats() CacheStats {
+	stats := m.counters.Stats(int64(m.Len()), 0)
+	for i := range m.shards {
+		stats.Hits += m.shards[i].hits.Load()
+		stats.Misses += m.shards[i].misses.Load()
+	}
+	return stats
+}
+
+// ObserveLoad records loading of a missing value, it is meant for code filling the map on miss.
+func (m *TTLMap[K, V]) ObserveLoad(duration time.Duration, err error) {
+	m.counters.ObserveLoad(duration, err)
+}
+
+// All returns iterator over live entries. Entries of every shard are copied under its lock,
+// so loop body may use the map, changes of shards not visited yet are seen.
+func (m *TTLMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		var entries []SnapshotEntry[K, V]
+		for i := range m.shards {
+			s := &m.shards[i]
+			now := time.Now().UnixNano()
+			s.mu.RLock()
+			entries = entries[:0]
+			for k, v := range s.m {
+				if now <= v.expiryNano {
+					entries = append(entries, SnapshotEntry[K, V]{Key: k, Value: v.value})
+				}
+			}
+			s.mu.RUnlock()
+			for _, entry := range entries {
+				if !yield(entry.Key, entry.Value) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Keys returns iterator over keys of live entries, see All.
+func (m *TTLMap[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for k := range m.All() {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns iterator over values of live entries, see All.
+func (m *TTLMap[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, v := range m.All() {
+			if !yield(v) {
+				return
+			}
+		}
 	}
-	s.m[key] = item[V]{value: fn(it.value), expiryNano: time.Now().UnixNano() + m.maxTTL}
 }
 
 // LoadAll returns a snapshot of all live (non-expired) entries.
@@ -3,6 +3,7 @@ package utils_test
 import (
 	"context"
 	"go_project_template/internal/utils"
+	"slices"
 	"strconv"
 	"sync"
 	"testing"
@@ -157,6 +158,19 @@ func TestGetAndRefresh_ExtendsTTL(t *testing.T) {
 	require.Equal(t, 0, len(m.LoadAll()))
 }
 
+func TestGetAndRefresh_Expired(t *testing.T) {
+	m := utils.NewTTLMap[string, int](20*time.Millisecond, time.Hour)
+	defer m.Close()
+
+	m.Put("x", 7)
+	time.Sleep(30 * time.Millisecond)
+	v, ok := m.GetAndRefresh("x")
+	require.False(t, ok)
+	require.Zero(t, v, "expired value should not be returned")
+	_, ok = m.Get("x")
+	require.False(t, ok)
+}
+
 func TestDelete_RemovesKey(t *testing.T) {
 	m := utils.NewTTLMap[string, int](time.Minute, time.Minute)
 	defer m.Close()
@@ -254,3 +268,141 @@ func TestDo_DoesNotHoldLock_DuringUserFunction(t *testing.T) {
 	require.True(t, ok)
 	require.Equal(t, 2, val)
 }
+
+func TestPutWithTTL_ExpiresPerKey(t *testing.T) {
+	m := utils.NewTTLMap[string, int](time.Minute, 10*time.Millisecond)
+	defer m.Close()
+
+	m.PutWithTTL("short", 1, 50*time.Millisecond)
+	m.PutWithTTL("long", 2, time.Hour) // capped by maxTTL
+	m.Put("default", 3)
+
+	require.Eventually(t, func() bool {
+		return m.Len() == 2
+	}, time.Second, 10*time.Millisecond)
+	_, ok := m.Get("short")
+	require.False(t, ok)
+	_, ok = m.Get("long")
+	require.True(t, ok)
+}
+
+func TestOnEvict_ReportsReasons(t *testing.T) {
+	var mu sync.Mutex
+	reasons := make(map[string]string)
+	m := utils.NewTTLMap[string, int](50*time.Millisecond, 10*time.Millisecond).
+		WithOnEvict(func(key string, value int, reason string) {
+			mu.Lock()
+			reasons[key+"="+strconv.Itoa(value)] = reason
+			mu.Unlock()
+		})
+	defer m.Close()
+
+	m.Put("expired", 1)
+	m.Put("deleted", 2)
+	m.Put("replaced", 3)
+	m.Put("replaced", 4)
+	m.Delete("deleted")
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(reasons) == 3
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, map[string]string{
+		"expired=1":  utils.EvictReasonExpired,
+		"deleted=2":  utils.EvictReasonManual,
+		"replaced=4": utils.EvictReasonExpired,
+	}, reasons)
+}
+
+func TestWithMaxEntries(t *testing.T) {
+	const maxEntries = 64 // 2 entries per shard, so every victim is picked from all entries of its shard
+
+	t.Run("should bound number of entries", func(t *testing.T) {
+		evicted := 0
+		m := utils.NewTTLMap[string, int](time.Minute, time.Minute).
+			WithMaxEntries(maxEntries, utils.EvictLRU).
+			WithOnEvict(func(string, int, string) { evicted++ })
+		defer m.Close()
+		for i := 0; i < 1000; i++ {
+			m.Put("key"+strconv.Itoa(i), i)
+		}
+		require.LessOrEqual(t, m.Len(), maxEntries)
+		require.Equal(t, 1000-m.Len(), evicted)
+		require.Equal(t, uint64(evicted), m.Stats().Evictions[utils.EvictReasonSize])
+	})
+	t.Run("should keep recently used entry", func(t *testing.T) {
+		m := utils.NewTTLMap[string, int](time.Minute, time.Minute).WithMaxEntries(maxEntries, utils.EvictLRU)
+		defer m.Close()
+		m.Put("hot", 0)
+		for i := 0; i < 1000; i++ {
+			m.Put("key"+strconv.Itoa(i), i)
+			_, ok := m.Get("hot")
+			require.True(t, ok)
+		}
+	})
+	t.Run("should evict entry expiring first", func(t *testing.T) {
+		var reason string
+		m := utils.NewTTLMap[string, int](time.Minute, time.Minute).
+			WithMaxEntries(maxEntries, utils.EvictOldestExpiry).
+			WithOnEvict(func(key string, _ int, r string) {
+				if key == "short" {
+					reason = r
+				}
+			})
+		defer m.Close()
+		m.PutWithTTL("short", 0, time.Second)
+		for i := 0; i < 1000; i++ {
+			m.Put("key"+strconv.Itoa(i), i)
+		}
+		_, ok := m.Get("short")
+		require.False(t, ok)
+		require.Equal(t, utils.EvictReasonSize, reason)
+	})
+}
+
+func TestWithTimingWheel_RemovesExpired(t *testing.T) {
+	maxTTL := 100 * time.Millisecond
+	cleanup := 10 * time.Millisecond
+	m := utils.NewTTLMap[string, int](maxTTL, cleanup)
+	defer m.Close()
+	m.Put("before", 0) // put before switching mode is scheduled too
+	m.WithTimingWheel()
+
+	for i := 0; i < 10; i++ {
+		m.PutWithTTL("k"+strconv.Itoa(i), i, maxTTL/2)
+	}
+	m.Put("refreshed", 0)
+	time.Sleep(maxTTL / 2)
+	m.Put("refreshed", 1) // the first schedule of the key is stale now
+
+	require.Eventually(t, func() bool {
+		return m.Len() == 1
+	}, time.Second, cleanup)
+	val, ok := m.Get("refreshed")
+	require.True(t, ok)
+	require.Equal(t, 1, val)
+	require.Eventually(t, func() bool {
+		return m.Len() == 0
+	}, time.Second, cleanup)
+}
+
+func TestTTLMap_Iterators(t *testing.T) {
+	m := utils.NewTTLMap[string, int](time.Minute, time.Minute)
+	defer m.Close()
+	m.Put("a", 1)
+	m.Put("b", 2)
+	m.PutWithTTL("expired", 3, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	seen := make(map[string]int)
+	for k, v := range m.All() {
+		seen[k] = v
+		m.Delete(k) // shard lock is not held by loop body
+	}
+	require.Equal(t, map[string]int{"a": 1, "b": 2}, seen)
+
+	m.Put("c", 3)
+	require.Equal(t, []string{"c"}, slices.Collect(m.Keys()))
+	require.Equal(t, []int{3}, slices.Collect(m.Values()))
+}
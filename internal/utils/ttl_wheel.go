@@ -0,0 +1,48 @@
+package utils
+
+import "time"
+
+// timingWheel buckets keys by expiry tick, so cleanup visits only keys which are due instead of the whole map.
+// Buckets are kept in a map by absolute tick number, so TTL of any length fits without wrapping around.
+// Key scheduled again stays in its previous bucket too, owner checks expiry of due keys.
+type timingWheel[K comparable] struct {
+	tick  int64 // nanoseconds
+	slots map[int64][]K
+	next  int64 // the first tick not processed yet
+}
+
+func newTimingWheel[K comparable](tick time.Duration, now int64) *timingWheel[K] {
+	w := &timingWheel[K]{
+		tick:  max(1, tick.Nanoseconds()),
+		slots: make(map[int64][]K),
+	}
+	w.next = now / w.tick
+	return w
+}
+
+func (w *timingWheel[K]) schedule(k K, expiryNano int64) {
+	slot := max(expiryNano/w.tick, w.next)
+	w.slots[slot] = append(w.slots[slot], k)
+}
+
+// due removes and returns keys of ticks which ended before now.
+func (w *timingWheel[K]) due(now int64) []K {
+	end := now / w.tick
+	var keys []K
+	if end-w.next > int64(len(w.slots)) {
+		// long pause, e.g. after suspend, visiting existing buckets is cheaper than every tick
+		for slot, slotKeys := range w.slots {
+			if slot < end {
+				keys = append(keys, slotKeys...)
+				delete(w.slots, slot)
+			}
+		}
+	} else {
+		for slot := w.next; slot < end; slot++ {
+			keys = append(keys, w.slots[slot]...)
+			delete(w.slots, slot)
+		}
+	}
+	w.next = max(w.next, end)
+	return keys
+}
@@ -86,3 +86,26 @@ func BenchmarkMixed80R20W(b *testing.B) {
 		}
 	})
 }
+
+// BenchmarkPutTimingWheel measures write throughput with expiry scheduled in timing wheel.
+func BenchmarkPutTimingWheel(b *testing.B) {
+	m := utils.NewTTLMap[string, int](time.Hour, time.Hour).WithTimingWheel()
+
+	b.ResetTimer()
+	for i := range b.N {
+		m.Put("key"+strconv.Itoa(i%10_000), i)
+	}
+}
+
+// BenchmarkGetLRUBound measures read throughput when Get records access for EvictLRU.
+func BenchmarkGetLRUBound(b *testing.B) {
+	const n = 10_000
+	m := utils.NewTTLMap[string, int](time.Hour, time.Hour).WithMaxEntries(n, utils.EvictLRU)
+	for i := range n {
+		m.Put("key"+strconv.Itoa(i), i)
+	}
+	b.ResetTimer()
+	for i := range b.N {
+		m.Get("key" + strconv.Itoa(i%n))
+	}
+}
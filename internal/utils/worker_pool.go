@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+var ErrTaskPanicked = errors.New("task panicked")
+
+// Task is a unit of work of WorkerPool, ctx is canceled on task timeout or forced stop.
+type Task func(ctx context.Context) error
+
+// WorkerPool runs tasks from a bounded queue with fixed number of workers.
+// Task errors and recovered panics are passed to WithOnError handler, panic doesn't stop the worker.
+type WorkerPool struct {
+	queue       *Queue[Task]
+	workers     int
+	taskTimeout time.Duration
+	onError     func(err error)
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	startOnce sync.Once
+}
+
+// NewWorkerPool creates pool of workers goroutines taking tasks from queue of queueSize.
+func NewWorkerPool(workers, queueSize int) *WorkerPool {
+	return &WorkerPool{
+		queue:   NewQueue[Task](queueSize),
+		workers: max(1, workers),
+		onError: func(error) {},
+	}
+}
+
+// WithTaskTimeout limits run time of every task, zero means no limit.
+func (p *WorkerPool) WithTaskTimeout(timeout time.Duration) *WorkerPool {
+	p.taskTimeout = timeout
+	return p
+}
+
+// WithOnError sets handler of task errors and panics wrapped in ErrTaskPanicked, e.g. to log them.
+// It is called from worker goroutines.
+func (p *WorkerPool) WithOnError(onError func(err error)) *WorkerPool {
+	p.onError = onError
+	return p
+}
+
+// Start starts workers, ctx is parent of task contexts. Workers run until Stop.
+func (p *WorkerPool) Start(ctx context.Context) *WorkerPool {
+	p.startOnce.Do(func() {
+		p.ctx, p.cancel = context.WithCancel(ctx)
+		p.wg.Add(p.workers)
+		for range p.workers {
+			go p.work()
+		}
+	})
+	return p
+}
+
+func (p *WorkerPool) work() {
+	defer p.wg.Done()
+	for {
+		task, err := p.queue.Pop(context.Background())
+		if err != nil {
+			// queue is closed and drained
+			return
+		}
+		if err := p.run(task); err != nil {
+			p.onError(err)
+		}
+	}
+}
+
+func (p *WorkerPool) run(task Task) (err error) {
+	ctx, cancel := p.ctx, context.CancelFunc(func() {})
+	if p.taskTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, p.taskTimeout)
+	}
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v\n%s", ErrTaskPanicked, r, debug.Stack())
+		}
+	}()
+	return task(ctx)
+}
+
+// Submit queues task waiting for free space until ctx is done, returns ErrQueueClosed after Stop.
+func (p *WorkerPool) Submit(ctx context.Context, task Task) error {
+	return p.queue.Push(ctx, task)
+}
+
+// TrySubmit queues task without waiting, returns ErrQueueFull if queue has no free space.
+func (p *WorkerPool) TrySubmit(task Task) error {
+	return p.queue.TryPush(task)
+}
+
+// Pending returns number of queued tasks which are not started yet.
+func (p *WorkerPool) Pending() int {
+	return p.queue.Len()
+}
+
+// Stop rejects new tasks and waits until queued tasks are done. If ctx is done first,
+// contexts of running and queued tasks are canceled and ctx error is returned once workers exit.
+// Pool which was never started is drained by Stop itself.
+func (p *WorkerPool) Stop(ctx context.Context) error {
+	p.Start(context.Background())
+	p.queue.Close()
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		<-done
+		return fmt.Errorf("stop worker pool: %w", ctx.Err())
+	}
+}
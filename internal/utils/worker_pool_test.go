@@ -0,0 +1,93 @@
+package utils_test
+
+import (
+	"context"
+	"errors"
+	"go_project_template/internal/utils"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerPool(t *testing.T) {
+	t.Run("should run all submitted tasks before stop returns", func(t *testing.T) {
+		pool := utils.NewWorkerPool(4, 10).Start(context.Background())
+		var done atomic.Int32
+		for i := 0; i < 100; i++ {
+			require.NoError(t, pool.Submit(context.Background(), func(context.Context) error {
+				time.Sleep(time.Millisecond)
+				done.Add(1)
+				return nil
+			}))
+		}
+		require.NoError(t, pool.Stop(context.Background()))
+		require.Equal(t, int32(100), done.Load())
+		require.ErrorIs(t, pool.Submit(context.Background(), func(context.Context) error { return nil }), utils.ErrQueueClosed)
+	})
+	t.Run("should report errors and recover panics", func(t *testing.T) {
+		var mu sync.Mutex
+		var errs []error
+		pool := utils.NewWorkerPool(1, 10).
+			WithOnError(func(err error) {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}).
+			Start(context.Background())
+		errTask := errors.New("task failed")
+		require.NoError(t, pool.TrySubmit(func(context.Context) error { return errTask }))
+		require.NoError(t, pool.TrySubmit(func(context.Context) error { panic("boom") }))
+		require.NoError(t, pool.TrySubmit(func(context.Context) error { return nil }))
+		require.NoError(t, pool.Stop(context.Background()))
+
+		require.Len(t, errs, 2)
+		require.ErrorIs(t, errs[0], errTask)
+		require.ErrorIs(t, errs[1], utils.ErrTaskPanicked)
+		require.Contains(t, errs[1].Error(), "boom")
+	})
+	t.Run("should cancel task on timeout", func(t *testing.T) {
+		var taskErr error
+		pool := utils.NewWorkerPool(1, 1).
+			WithTaskTimeout(10 * time.Millisecond).
+			WithOnError(func(err error) { taskErr = err }).
+			Start(context.Background())
+		require.NoError(t, pool.Submit(context.Background(), func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}))
+		require.NoError(t, pool.Stop(context.Background()))
+		require.ErrorIs(t, taskErr, context.DeadlineExceeded)
+	})
+	t.Run("should reject tasks when queue is full", func(t *testing.T) {
+		release := make(chan struct{})
+		started := make(chan struct{})
+		pool := utils.NewWorkerPool(1, 1).Start(context.Background())
+		require.NoError(t, pool.TrySubmit(func(context.Context) error {
+			close(started)
+			<-release
+			return nil
+		}))
+		<-started
+		require.NoError(t, pool.TrySubmit(func(context.Context) error { return nil }))
+		require.Equal(t, 1, pool.Pending())
+		require.ErrorIs(t, pool.TrySubmit(func(context.Context) error { return nil }), utils.ErrQueueFull)
+		close(release)
+		require.NoError(t, pool.Stop(context.Background()))
+	})
+	t.Run("should cancel tasks when stop times out", func(t *testing.T) {
+		pool := utils.NewWorkerPool(1, 1).Start(context.Background())
+		started := make(chan struct{})
+		require.NoError(t, pool.TrySubmit(func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return nil
+		}))
+		<-started
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, pool.Stop(ctx), context.DeadlineExceeded)
+	})
+}
@@ -0,0 +1,16 @@
+create table outbox
+(
+    id              bigserial
+        constraint outbox_pk
+            primary key,
+    topic           varchar   not null,
+    event_key       varchar   not null default '',
+    payload         jsonb     not null,
+    created_at      timestamp not null default now(),
+    attempts        int       not null default 0,
+    next_attempt_at timestamp not null default now(),
+    delivered_at    timestamp,
+    last_error      varchar
+);
+
+create index outbox_pending_idx on outbox (next_attempt_at) where delivered_at is null;
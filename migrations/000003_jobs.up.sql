@@ -0,0 +1,18 @@
+create table jobs
+(
+    id           bigserial
+        constraint jobs_pk
+            primary key,
+    job_type     varchar   not null,
+    payload      jsonb     not null,
+    status       varchar   not null default 'pending',
+    attempts     int       not null default 0,
+    max_attempts int       not null default 5,
+    run_at       timestamp not null default now(),
+    locked_until timestamp,
+    last_error   varchar,
+    created_at   timestamp not null default now(),
+    updated_at   timestamp not null default now()
+);
+
+create index jobs_due_idx on jobs (job_type, run_at) where status in ('pending', 'running');
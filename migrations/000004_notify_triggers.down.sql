@@ -0,0 +1,2 @@
+drop trigger if exists users_notify_change on users;
+drop function if exists notify_row_change();
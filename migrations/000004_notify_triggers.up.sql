@@ -0,0 +1,25 @@
+-- notify_row_change sends RowChange JSON to channel TG_ARGV[0] with id taken from column TG_ARGV[1]
+create or replace function notify_row_change() returns trigger as
+$$
+declare
+    row_data jsonb;
+begin
+    if TG_OP = 'DELETE' then
+        row_data = to_jsonb(OLD);
+    else
+        row_data = to_jsonb(NEW);
+    end if;
+    perform pg_notify(TG_ARGV[0], json_build_object(
+            'table', TG_TABLE_NAME,
+            'op', lower(TG_OP),
+            'id', row_data ->> TG_ARGV[1]
+        )::text);
+    return null;
+end;
+$$ language plpgsql;
+
+create trigger "users_notify_change"
+    after insert or update or delete
+    on "users"
+    for each row
+execute function notify_row_change('users_changes', 'u_id');